@@ -0,0 +1,373 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fieldBinding describes how one struct field is filled from extension fields.
+type fieldBinding struct {
+	index   []int
+	names   []string // key followed by its aliases
+	json    bool
+	list    bool
+	sep     string // list separator, "," when empty
+	header  int    // index of the bound header field, or -1
+	nested  bool   // untagged struct field filled from the same fields
+	display string
+}
+
+// bindingCache caches the bindings for each struct type.
+var bindingCache sync.Map // map[reflect.Type][]fieldBinding
+
+// textUnmarshalerType is the reflect.Type of encoding.TextUnmarshaler.
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// BindFields fills the exported fields of the struct pointed to by v from the
+// extension fields, using `cef` struct tags of the form
+//
+//	`cef:"fileId,alias=fileid,json"`
+//
+// The first tag element is the extension key; it defaults to the Go field name
+// and "-" skips the field. Options are:
+//   - alias=name, an additional key to read the value from (may be repeated)
+//   - json, decode the value as JSON into the field; an interface{} field keeps
+//     the raw string if the value is not valid JSON
+//   - list, split the value on commas into a slice, trimming spaces
+//   - sep=x, split the value on x instead of commas; implies list
+//   - header, read the header field with the given name, such as Severity,
+//     instead of an extension key (see Unmarshal)
+//
+// Struct fields without a key in their tag, and pointers to such structs, are
+// filled recursively from the same fields unless they are time.Time, implement
+// encoding.TextUnmarshaler or have the json option. A nil pointer is only
+// allocated when one of its fields is set, and a pointer to a struct type that
+// is already being filled, as in a recursive type, is skipped.
+//
+// Keys are matched exactly first and then case-insensitively. Values are
+// converted to strings, booleans, integers, floats, slices, pointers, time.Time
+// (see ParseTimestamp) and types implementing encoding.TextUnmarshaler. Fields
+// without a value are left unchanged. The first conversion error is returned
+// after all fields are bound.
+func BindFields(v interface{}, fields map[string]string) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("BindFields requires a non-nil pointer to a struct, got %T", v)
+	}
+	b := binder{fields: fields}
+	_, err := b.bind(rv.Elem())
+	return err
+}
+
+// binder fills struct fields from extension fields and, optionally, header
+// fields.
+type binder struct {
+	fields map[string]string
+	folded map[string]string        // fields keyed by lower-case key, built on demand
+	header *[cefHeaderFields]string // nil when only extension fields are bound
+	loc    *time.Location           // zone for timestamps without one, UTC if nil
+	// active holds the struct types being bound, so that a pointer back to one
+	// of them is skipped instead of recursing forever.
+	active map[reflect.Type]bool
+}
+
+// bind fills the struct rv and reports whether any field was set, along with
+// the first conversion error.
+func (b *binder) bind(rv reflect.Value) (bool, error) {
+	if b.active == nil {
+		b.active = make(map[reflect.Type]bool)
+	}
+	b.active[rv.Type()] = true
+	defer delete(b.active, rv.Type())
+
+	set := false
+	var firstErr error
+	for _, binding := range structBindings(rv.Type()) {
+		field := rv.FieldByIndex(binding.index)
+		if binding.nested {
+			nestedSet, err := b.bindNested(field)
+			set = set || nestedSet
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		value, ok := b.lookup(binding)
+		if !ok {
+			continue
+		}
+		set = true
+		if err := b.setValue(field, value, binding); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("field %s: %w", binding.display, err)
+		}
+	}
+	return set, firstErr
+}
+
+// bindNested fills a nested struct or pointer to struct. A pointer to a struct
+// type that is already being bound, such as the next node of a linked list, is
+// left unchanged.
+func (b *binder) bindNested(field reflect.Value) (bool, error) {
+	if field.Kind() != reflect.Ptr {
+		return b.bind(field)
+	}
+	if b.active[field.Type().Elem()] {
+		return false, nil
+	}
+	if !field.IsNil() {
+		return b.bind(field.Elem())
+	}
+	ptr := reflect.New(field.Type().Elem())
+	set, err := b.bind(ptr.Elem())
+	if set {
+		field.Set(ptr)
+	}
+	return set, err
+}
+
+// lookup returns the value bound to binding.
+func (b *binder) lookup(binding fieldBinding) (string, bool) {
+	if binding.header >= 0 {
+		if b.header == nil || b.header[binding.header] == "" {
+			return "", false
+		}
+		return b.header[binding.header], true
+	}
+	if value, ok := lookupField(b.fields, binding.names); ok {
+		return value, true
+	}
+	if b.folded == nil {
+		b.folded = foldFields(b.fields)
+	}
+	return lookupField(b.folded, lowerAll(binding.names))
+}
+
+// setValue converts value and stores it in field. Timestamps are parsed with
+// ParseTimestamp in the binder's location.
+func (b *binder) setValue(field reflect.Value, value string, binding fieldBinding) error {
+	if !binding.json {
+		switch field.Type() {
+		case timeType, reflect.PointerTo(timeType):
+			ts, err := ParseTimestamp(value, b.loc)
+			if err != nil {
+				return err
+			}
+			if field.Kind() == reflect.Ptr {
+				field.Set(reflect.ValueOf(&ts))
+			} else {
+				field.Set(reflect.ValueOf(ts))
+			}
+			return nil
+		}
+	}
+	return setFieldValue(field, value, binding)
+}
+
+// structBindings returns the cached bindings for a struct type.
+func structBindings(t reflect.Type) []fieldBinding {
+	if cached, ok := bindingCache.Load(t); ok {
+		return cached.([]fieldBinding)
+	}
+
+	var bindings []fieldBinding
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("cef")
+		if tag == "-" {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		binding := fieldBinding{index: field.Index, header: -1, display: field.Name}
+		name := parts[0]
+		if name == "" {
+			name = field.Name
+		}
+		binding.names = append(binding.names, name)
+		header := false
+		for _, opt := range parts[1:] {
+			switch {
+			case opt == "json":
+				binding.json = true
+			case opt == "list":
+				binding.list = true
+			case opt == "header":
+				header = true
+			case strings.HasPrefix(opt, "sep="):
+				binding.list = true
+				binding.sep = strings.TrimPrefix(opt, "sep=")
+			case strings.HasPrefix(opt, "alias="):
+				binding.names = append(binding.names, strings.TrimPrefix(opt, "alias="))
+			}
+		}
+		if header {
+			for i, fieldName := range headerFieldNames {
+				if strings.EqualFold(fieldName, name) {
+					binding.header = i
+				}
+			}
+		}
+		binding.nested = parts[0] == "" && !binding.json && isNestedStruct(field.Type)
+		bindings = append(bindings, binding)
+	}
+
+	cached, _ := bindingCache.LoadOrStore(t, bindings)
+	return cached.([]fieldBinding)
+}
+
+// isNestedStruct reports whether t is a struct, or pointer to a struct, that
+// is bound field by field rather than converted from a single value.
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != timeType && !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// lookupField returns the value of the first of names present in fields.
+func lookupField(fields map[string]string, names []string) (string, bool) {
+	for _, name := range names {
+		if value, ok := fields[name]; ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// foldFields returns a copy of fields keyed by lower-case key. When two keys
+// differ only in case, the lexically smallest original key wins so that the
+// result does not depend on map iteration order.
+func foldFields(fields map[string]string) map[string]string {
+	folded := make(map[string]string, len(fields))
+	origin := make(map[string]string, len(fields))
+	for key, value := range fields {
+		lower := strings.ToLower(key)
+		if prev, ok := origin[lower]; ok && prev < key {
+			continue
+		}
+		folded[lower] = value
+		origin[lower] = key
+	}
+	return folded
+}
+
+// lowerAll returns the lower-case form of each name.
+func lowerAll(names []string) []string {
+	lowered := make([]string, len(names))
+	for i, name := range names {
+		lowered[i] = strings.ToLower(name)
+	}
+	return lowered
+}
+
+// setFieldValue converts value and stores it in field.
+func setFieldValue(field reflect.Value, value string, binding fieldBinding) error {
+	if binding.json {
+		if field.Kind() == reflect.Interface && field.NumMethod() == 0 {
+			var decoded interface{}
+			if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+				field.Set(reflect.ValueOf(value))
+				return nil
+			}
+			if decoded != nil {
+				field.Set(reflect.ValueOf(decoded))
+			}
+			return nil
+		}
+		return json.Unmarshal([]byte(value), field.Addr().Interface())
+	}
+
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 {
+		var parts []string
+		if binding.list {
+			sep := binding.sep
+			if sep == "" {
+				sep = ","
+			}
+			for _, part := range strings.Split(value, sep) {
+				parts = append(parts, strings.TrimSpace(part))
+			}
+		} else {
+			parts = []string{value}
+		}
+		slice := reflect.MakeSlice(field.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := convertString(slice.Index(i), part); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+
+	return convertString(field, value)
+}
+
+// convertString converts a string into the type of dst and stores it.
+func convertString(dst reflect.Value, value string) error {
+	if dst.Kind() == reflect.Ptr {
+		ptr := reflect.New(dst.Type().Elem())
+		if err := convertString(ptr.Elem(), value); err != nil {
+			return err
+		}
+		dst.Set(ptr)
+		return nil
+	}
+
+	if dst.CanAddr() && dst.Addr().Type().Implements(textUnmarshalerType) {
+		return dst.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+
+	switch dst.Kind() {
+	case reflect.String:
+		dst.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		dst.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, dst.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dst.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, dst.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dst.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, dst.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dst.SetFloat(f)
+	case reflect.Interface:
+		if dst.NumMethod() != 0 {
+			return fmt.Errorf("cannot assign string to %s", dst.Type())
+		}
+		dst.Set(reflect.ValueOf(value))
+	case reflect.Slice:
+		if dst.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("cannot convert string to %s", dst.Type())
+		}
+		dst.SetBytes([]byte(value))
+	default:
+		return fmt.Errorf("cannot convert string to %s", dst.Type())
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+// Tests for the struct-tag driven field binder.
+package parser
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+// TestBindFields tests tag names, aliases, case folding and type conversion.
+func TestBindFields(t *testing.T) {
+	type target struct {
+		FileID   string            `cef:"fileId,alias=fid"`
+		Method   string            `cef:"requestMethod"`
+		Count    int               `cef:"cnt"`
+		Ratio    float64           `cef:"cfp1"`
+		Allowed  bool              `cef:"allowed"`
+		Port     *uint16           `cef:"spt"`
+		Src      netip.Addr        `cef:"src"`
+		XFF      []string          `cef:"xff,list"`
+		Tags     []string          `cef:"tags"`
+		Headers  interface{}       `cef:"headers,json"`
+		Labels   map[string]string `cef:"labels,json"`
+		Untagged string
+		Skipped  string `cef:"-"`
+	}
+
+	fields := map[string]string{
+		"fid":           "123",
+		"requestmethod": "GET",
+		"cnt":           "42",
+		"cfp1":          "0.5",
+		"allowed":       "true",
+		"spt":           "443",
+		"src":           "10.0.0.1",
+		"xff":           "10.1.1.1, 10.2.2.2",
+		"tags":          "a,b",
+		"headers":       `[{"Host":"example.com"}]`,
+		"labels":        `{"k":"v"}`,
+		"untagged":      "yes",
+		"Skipped":       "no",
+	}
+
+	var v target
+	if err := BindFields(&v, fields); err != nil {
+		t.Fatalf("BindFields() error = %v", err)
+	}
+
+	port := uint16(443)
+	expected := target{
+		FileID:   "123",
+		Method:   "GET",
+		Count:    42,
+		Ratio:    0.5,
+		Allowed:  true,
+		Port:     &port,
+		Src:      netip.MustParseAddr("10.0.0.1"),
+		XFF:      []string{"10.1.1.1", "10.2.2.2"},
+		Tags:     []string{"a,b"},
+		Headers:  []interface{}{map[string]interface{}{"Host": "example.com"}},
+		Labels:   map[string]string{"k": "v"},
+		Untagged: "yes",
+	}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("BindFields() = %+v, want %+v", v, expected)
+	}
+}
+
+// TestBindFieldsPrefersExactKey tests that an exact key wins over a case-insensitive match.
+func TestBindFieldsPrefersExactKey(t *testing.T) {
+	var v struct {
+		Method string `cef:"requestMethod"`
+	}
+	if err := BindFields(&v, map[string]string{"requestmethod": "POST", "requestMethod": "GET"}); err != nil {
+		t.Fatalf("BindFields() error = %v", err)
+	}
+	if v.Method != "GET" {
+		t.Errorf("expected exact key value 'GET', got '%s'", v.Method)
+	}
+}
+
+// TestBindFieldsErrors tests conversion errors and invalid targets.
+func TestBindFieldsErrors(t *testing.T) {
+	var v struct {
+		Count   int         `cef:"cnt"`
+		Name    string      `cef:"name"`
+		Headers interface{} `cef:"headers,json"`
+	}
+	err := BindFields(&v, map[string]string{"cnt": "many", "name": "ok", "headers": "not json"})
+	if err == nil {
+		t.Errorf("expected conversion error, got nil")
+	}
+	if v.Name != "ok" {
+		t.Errorf("expected remaining fields to be bound, got '%s'", v.Name)
+	}
+	if v.Headers != "not json" {
+		t.Errorf("expected raw string for invalid JSON, got '%v'", v.Headers)
+	}
+
+	if err := BindFields(v, nil); err == nil {
+		t.Errorf("expected error for non-pointer target, got nil")
+	}
+}
+
+// TestImpervaExtensionsKeyDrift tests that lower-case vendor keys populate Imperva fields.
+func TestImpervaExtensionsKeyDrift(t *testing.T) {
+	cefEvent, err := ParseCEF(ImpervaCEFCombined)
+	if err != nil {
+		t.Fatalf("ParseCEF() error = %v", err)
+	}
+
+	ie := cefEvent.Extensions.(*ImpervaExtensions)
+	if ie.FileID != "3412341160002518171" {
+		t.Errorf("expected FileID to be '3412341160002518171', got '%s'", ie.FileID)
+	}
+	if ie.RequestMethod != "GET" {
+		t.Errorf("expected RequestMethod to be 'GET', got '%s'", ie.RequestMethod)
+	}
+	if ie.DeviceExternalID != "33411452762204224" {
+		t.Errorf("expected DeviceExternalID to be '33411452762204224', got '%s'", ie.DeviceExternalID)
+	}
+
+	expectedResHeaders := []interface{}{
+		map[string]interface{}{"Content-Type": "text/html; charset=UTF-8"},
+	}
+	if !reflect.DeepEqual(ie.AdditionalResHeaders, expectedResHeaders) {
+		t.Errorf("expected AdditionalResHeaders to be '%v', got '%v'", expectedResHeaders, ie.AdditionalResHeaders)
+	}
+}
+
+// TestImpervaExtensionsAbsentFields tests the zero values of absent Imperva fields.
+func TestImpervaExtensionsAbsentFields(t *testing.T) {
+	cefEvent, err := ParseCEF("CEF:0|Incapsula|SIEMintegration|1|1|Normal|0|src=10.0.0.1")
+	if err != nil {
+		t.Fatalf("ParseCEF() error = %v", err)
+	}
+
+	ie := cefEvent.Extensions.(*ImpervaExtensions)
+	if ie.CS10 != "" || ie.CS11 != "" || !reflect.DeepEqual(ie.XFF, []string{""}) {
+		t.Errorf("unexpected zero values: CS10 = %#v, CS11 = %#v, XFF = %#v", ie.CS10, ie.CS11, ie.XFF)
+	}
+	if line, _ := Format(cefEvent); line != "CEF:0|Incapsula|SIEMintegration|1|1|Normal|0|src=10.0.0.1" {
+		t.Errorf("Format() = %s", line)
+	}
+}
@@ -0,0 +1,147 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unsafe"
+)
+
+// internedKeys maps the extension keys of the dictionary, the labels of its
+// custom fields and the keys of the built-in vendor types to a shared copy so
+// that ParseBytes does not allocate them.
+var internedKeys = func() map[string]string {
+	keys := make(map[string]string, 2*len(dictionary))
+	for _, def := range dictionary {
+		keys[def.Key] = def.Key
+		if _, _, ok := customFieldKey(def.Key); ok {
+			keys[def.Key+"Label"] = def.Key + "Label"
+		}
+	}
+	for _, ext := range []Extensions{&ImpervaExtensions{}, &CentrifyExtensions{}} {
+		for _, binding := range structBindings(reflect.TypeOf(ext).Elem()) {
+			for _, name := range binding.names {
+				keys[name] = name
+			}
+		}
+	}
+	return keys
+}()
+
+// internKey returns a copy of key that does not share memory with its input,
+// reusing the interned copy of common keys.
+func internKey(key string) string {
+	if interned, ok := internedKeys[key]; ok {
+		return interned
+	}
+	return strings.Clone(key)
+}
+
+// ParseBytes parses a CEF record into cef using the default parser. See
+// Parser.ParseBytes.
+func ParseBytes(data []byte, cef *CEF) error {
+	return defaultParser.ParseBytes(data, cef)
+}
+
+// ParseBytes parses a CEF record into cef, reusing the event and its
+// extensions to avoid allocating. It is intended for high-throughput callers
+// that parse many records in a loop.
+//
+// The header fields and extension values of cef are views into data: they
+// remain valid only as long as data is not modified, so callers that reuse the
+// buffer must copy what they keep. Values containing escape sequences are
+// copied, as are the strings of a returned *ParseError. Extensions are always stored in a *DefaultExtensions, whose map is
+// cleared and reused when cef already holds one; vendor-specific extension
+// types and label folding are only available through Parse.
+func (p *Parser) ParseBytes(data []byte, cef *CEF) error {
+	if cef == nil {
+		return fmt.Errorf("cannot parse into nil CEF event")
+	}
+	line := bytesView(data)
+
+	header, extension, envelope, err := p.parseHeader(line)
+	if err != nil {
+		return detachError(err)
+	}
+
+	de, ok := cef.Extensions.(*DefaultExtensions)
+	if !ok || de == nil {
+		de = &DefaultExtensions{}
+	}
+	if de.Fields == nil {
+		de.Fields = make(map[string]string)
+	} else {
+		clear(de.Fields)
+	}
+
+	*cef = CEF{
+		Version:       header[0],
+		DeviceVendor:  header[1],
+		DeviceProduct: header[2],
+		DeviceVersion: header[3],
+		SignatureID:   header[4],
+		Name:          header[5],
+		Severity:      header[6],
+		Extensions:    de,
+		Syslog:        envelope,
+		times:         p.timeSettings(),
+	}
+
+	count := 0
+	var limitErr error
+	scanErr := scanExtensions(extension, p.quoted, func(field extensionField, _ *ParseError) {
+		if limitErr != nil {
+			return
+		}
+		if count++; p.maxExtensions > 0 && count > p.maxExtensions {
+			limitErr = p.tooManyExtensions(field)
+			return
+		}
+		if limitErr = p.checkExtensionField(field); limitErr != nil {
+			return
+		}
+		prev, seen := de.Fields[field.Key]
+		switch {
+		case !seen:
+			de.Fields[internKey(field.Key)] = field.Value
+		case p.duplicates == DuplicateFirst:
+		case p.duplicates == DuplicateCollect:
+			// Assigning to an existing key also replaces the stored key, so
+			// every assignment uses a copy.
+			de.Fields[internKey(field.Key)] = prev + ", " + field.Value
+		case p.duplicates == DuplicateError:
+			limitErr = duplicateKeyError(field)
+		default:
+			de.Fields[internKey(field.Key)] = field.Value
+		}
+	})
+	if scanErr != nil {
+		return detachError(extensionError(scanErr, line, extension))
+	}
+	if limitErr != nil {
+		return detachError(extensionError(limitErr, line, extension))
+	}
+	return nil
+}
+
+// detachError copies the strings of a *ParseError that may be views into the
+// buffer passed to ParseBytes, so that the error outlives the buffer.
+func detachError(err error) error {
+	var perr *ParseError
+	if errors.As(err, &perr) {
+		perr.Key = strings.Clone(perr.Key)
+		perr.Snippet = strings.Clone(perr.Snippet)
+		perr.Detail = strings.Clone(perr.Detail)
+	}
+	return err
+}
+
+// bytesView returns a string sharing memory with b.
+func bytesView(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	return unsafe.String(&b[0], len(b)) // #nosec G103 -- read-only view documented on ParseBytes
+}
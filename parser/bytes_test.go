@@ -0,0 +1,195 @@
+// Tests and benchmarks for the byte-slice parsing path.
+package parser
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// benchCEF is a typical CEF record using dictionary keys only.
+const benchCEF = `CEF:0|Security|threatmanager|1.0|100|worm successfully stopped|10|src=10.0.0.1 dst=2.1.2.2 spt=1232 dpt=80 proto=TCP act=blocked request=http://example.com/path cs1=rule-7 cs1Label=Rule name msg=Detected a threat. No action needed`
+
+// TestParseBytes tests that ParseBytes matches ParseCEF.
+func TestParseBytes(t *testing.T) {
+	for _, line := range []string{benchCEF, ImpervaCEF1, CentrifyCEF, `CEF:0|Ven\|dor|P|1|2|N|3|msg=a\=b c=d`} {
+		expected, err := NewParser(WithExtensionsFactory(func(string, string, string) Extensions {
+			return &DefaultExtensions{}
+		})).Parse(line)
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+
+		var cefEvent CEF
+		if err := ParseBytes([]byte(line), &cefEvent); err != nil {
+			t.Fatalf("ParseBytes() error = %v", err)
+		}
+		if !reflect.DeepEqual(&cefEvent, expected) {
+			t.Errorf("ParseBytes() = %v, want %v", cefEvent, expected)
+		}
+	}
+}
+
+// TestParseBytesReuse tests that a reused event does not keep stale fields.
+func TestParseBytesReuse(t *testing.T) {
+	var cefEvent CEF
+	if err := ParseBytes([]byte(benchCEF), &cefEvent); err != nil {
+		t.Fatalf("ParseBytes() error = %v", err)
+	}
+	fields := cefEvent.Extensions.(*DefaultExtensions).Fields
+	if err := ParseBytes([]byte("CEF:0|V|P|1|2|N|3|dhost=host1"), &cefEvent); err != nil {
+		t.Fatalf("ParseBytes() error = %v", err)
+	}
+	de := cefEvent.Extensions.(*DefaultExtensions)
+	if !reflect.DeepEqual(de.Fields, map[string]string{"dhost": "host1"}) {
+		t.Errorf("Fields = %v", de.Fields)
+	}
+	if reflect.ValueOf(de.Fields).Pointer() != reflect.ValueOf(fields).Pointer() {
+		t.Errorf("expected the extension map to be reused")
+	}
+}
+
+// TestParseBytesErrors tests the error cases of ParseBytes.
+func TestParseBytesErrors(t *testing.T) {
+	var cefEvent CEF
+	tests := []struct {
+		name string
+		p    *Parser
+		data string
+		cef  *CEF
+	}{
+		{"Nil event", defaultParser, benchCEF, nil},
+		{"Empty", defaultParser, "", &cefEvent},
+		{"Invalid format", defaultParser, "CEF:0|V|P", &cefEvent},
+		{"Malformed escape", defaultParser, `CEF:0|V|P|1|2|N|3|msg=a\qb`, &cefEvent},
+		{"Too many extensions", NewParser(WithMaxExtensions(2)), benchCEF, &cefEvent},
+		{"Value too long", NewParser(WithMaxValueLength(5)), benchCEF, &cefEvent},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.p.ParseBytes([]byte(test.data), test.cef); err == nil {
+				t.Errorf("expected error, got nil")
+			}
+		})
+	}
+}
+
+// TestParseBytesErrorDetached tests that a parse error does not share memory
+// with the parsed buffer.
+func TestParseBytesErrorDetached(t *testing.T) {
+	tests := []struct {
+		name string
+		p    *Parser
+		data string
+	}{
+		{"Header", defaultParser, "CEF:0|V|P"},
+		{"Malformed escape", defaultParser, `CEF:0|V|P|1|2|N|3|msg=a\qb`},
+		{"Duplicate key", NewParser(WithDuplicateKeys(DuplicateError)), "CEF:0|V|P|1|2|N|3|dhost=a dhost=b"},
+		{"Key too long", NewParser(WithMaxKeyLength(3)), "CEF:0|V|P|1|2|N|3|dhost=a"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := []byte(test.data)
+			var cefEvent CEF
+			err := test.p.ParseBytes(data, &cefEvent)
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("ParseBytes() error = %v, want a *ParseError", err)
+			}
+			expected := *perr
+			expected.Key = string([]byte(perr.Key))
+			expected.Snippet = string([]byte(perr.Snippet))
+			expected.Detail = string([]byte(perr.Detail))
+
+			for i := range data {
+				data[i] = 'X'
+			}
+			if !reflect.DeepEqual(*perr, expected) {
+				t.Errorf("ParseError after reusing the buffer = %+v, want %+v", *perr, expected)
+			}
+		})
+	}
+}
+
+// TestParseBytesDuplicateKeys tests that repeated keys stay valid after the
+// buffer is reused.
+func TestParseBytesDuplicateKeys(t *testing.T) {
+	for _, policy := range []DuplicateKeyPolicy{DuplicateLast, DuplicateCollect} {
+		data := []byte("CEF:0|V|P|1|2|N|3|userKey=a userKey=b")
+		var cefEvent CEF
+		if err := NewParser(WithDuplicateKeys(policy)).ParseBytes(data, &cefEvent); err != nil {
+			t.Fatalf("ParseBytes() error = %v", err)
+		}
+		fields := cefEvent.Extensions.(*DefaultExtensions).Fields
+
+		// Values are views into data; only the keys must survive.
+		copy(data, strings.Repeat("X", len(data)))
+		if _, ok := fields["userKey"]; !ok || len(fields) != 1 {
+			t.Errorf("policy %v: keys of %q after reusing the buffer, want userKey", policy, fields)
+		}
+	}
+}
+
+// TestParseBytesAllocs tests that parsing into a reused event does not allocate.
+func TestParseBytesAllocs(t *testing.T) {
+	data := []byte(benchCEF)
+	var cefEvent CEF
+	if err := ParseBytes(data, &cefEvent); err != nil {
+		t.Fatalf("ParseBytes() error = %v", err)
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = ParseBytes(data, &cefEvent)
+	})
+	if allocs != 0 {
+		t.Errorf("ParseBytes() allocated %v times per run, want 0", allocs)
+	}
+}
+
+// BenchmarkParseCEF benchmarks ParseCEF on a typical record.
+func BenchmarkParseCEF(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseCEF(benchCEF); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkParseBytes benchmarks ParseBytes on a typical record.
+func BenchmarkParseBytes(b *testing.B) {
+	data := []byte(benchCEF)
+	var cefEvent CEF
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		if err := ParseBytes(data, &cefEvent); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkParseCEFImperva benchmarks ParseCEF on the Imperva sample.
+func BenchmarkParseCEFImperva(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseCEF(ImpervaCEF1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkParseBytesImperva benchmarks ParseBytes on the Imperva sample.
+func BenchmarkParseBytesImperva(b *testing.B) {
+	data := []byte(ImpervaCEF1)
+	var cefEvent CEF
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		if err := ParseBytes(data, &cefEvent); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -13,6 +13,15 @@ type CEF struct {
 	Name          string
 	Severity      string
 	Extensions    Extensions
+
+	// CustomFields holds the custom fields keyed by label when the Parser is
+	// configured with WithLabelFolding.
+	CustomFields map[string]string `json:",omitempty"`
+
+	// Syslog holds the syslog envelope the event was received in, if any.
+	Syslog *Syslog `json:",omitempty"`
+
+	times *timeSettings // set when the Parser overrides the default location or clock
 }
 
 // Extensions defines methods for parsing, converting to JSON/map, and getting field names.
@@ -24,7 +33,7 @@ type Extensions interface {
 	GetField(fieldName string) (interface{}, error)
 }
 
-// AsJSON returns the CEF event as a pretty JSON string.
+// AsJSON returns the CEF event as a pretty JSON string. See MarshalJSON.
 func (cef *CEF) AsJSON() string {
 	data, _ := json.MarshalIndent(cef, "", "  ")
 	return string(data)
@@ -4,76 +4,69 @@ package parser
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
 	"reflect"
 )
 
 // CentrifyExtensions represents the specific extension fields for Centrify.
 type CentrifyExtensions struct {
-	DHost              string
-	DUser              string
-	Msg                string
-	SHost              string
-	Src                string
-	RT                 string
-	DeviceProcessName  string
-	DvcHost            string
-	DTZ                string
-	RequestContext     string
-	ExternalID         string
-	DPriv              string
-	DestinationService string
-	SUID               string
-	CS1                string
-	CS1Label           string
-	CS2                string
-	CS2Label           string
-	CS3                string
-	CS3Label           string
-	CS4                string
-	CS4Label           string
-	CS5                string
-	CS5Label           string
-	CS6                string
-	CS6Label           string
+	DHost              string `cef:"dhost"`
+	DUser              string `cef:"duser"`
+	Msg                string `cef:"msg"`
+	SHost              string `cef:"shost"`
+	Src                string `cef:"src"`
+	RT                 string `cef:"rt"`
+	DeviceProcessName  string `cef:"deviceProcessName"`
+	DvcHost            string `cef:"dvchost"`
+	DTZ                string `cef:"dtz"`
+	RequestContext     string `cef:"requestContext"`
+	ExternalID         string `cef:"externalId"`
+	DPriv              string `cef:"dpriv"`
+	DestinationService string `cef:"destinationServiceName"`
+	SUID               string `cef:"suid"`
+	CS1                string `cef:"cs1"`
+	CS1Label           string `cef:"cs1Label"`
+	CS2                string `cef:"cs2"`
+	CS2Label           string `cef:"cs2Label"`
+	CS3                string `cef:"cs3"`
+	CS3Label           string `cef:"cs3Label"`
+	CS4                string `cef:"cs4"`
+	CS4Label           string `cef:"cs4Label"`
+	CS5                string `cef:"cs5"`
+	CS5Label           string `cef:"cs5Label"`
+	CS6                string `cef:"cs6"`
+	CS6Label           string `cef:"cs6Label"`
+
+	extra map[string]string // fields without a struct field, keyed as received
 }
 
 // ParseExtensions parses the extension string into the CentrifyExtensions struct.
+// Quoted values are read whole, since Centrify sends them unescaped; see
+// WithQuotedValues.
 func (ce *CentrifyExtensions) ParseExtensions(extension string) map[string]string {
-	fields := parseExtensions(extension)
-	ce.DHost = fields["dhost"]
-	ce.DUser = fields["duser"]
-	ce.Msg = fields["msg"]
-	ce.SHost = fields["shost"]
-	ce.Src = fields["src"]
-	ce.RT = fields["rt"]
-	ce.DeviceProcessName = fields["deviceProcessName"]
-	ce.DvcHost = fields["dvchost"]
-	ce.DTZ = fields["dtz"]
-	ce.RequestContext = fields["requestContext"]
-	ce.ExternalID = fields["externalId"]
-	ce.DPriv = fields["dpriv"]
-	ce.DestinationService = fields["destinationServiceName"]
-	ce.SUID = fields["suid"]
-	ce.CS1 = fields["cs1"]
-	ce.CS1Label = fields["cs1Label"]
-	ce.CS2 = fields["cs2"]
-	ce.CS2Label = fields["cs2Label"]
-	ce.CS3 = fields["cs3"]
-	ce.CS3Label = fields["cs3Label"]
-	ce.CS4 = fields["cs4"]
-	ce.CS4Label = fields["cs4Label"]
-	ce.CS5 = fields["cs5"]
-	ce.CS5Label = fields["cs5Label"]
-	ce.CS6 = fields["cs6"]
-	ce.CS6Label = fields["cs6Label"]
+	tokens, _ := tokenizeExtensions(extension, ce.quotedValues())
+	fields := fieldsToMap(tokens)
+	ce.loadFields(fields)
 	return fields
 }
 
+// quotedValues reports that Centrify extension values may be quoted.
+func (ce *CentrifyExtensions) quotedValues() bool {
+	return true
+}
+
+// loadFields populates the CentrifyExtensions struct from the tokenized extension fields.
+func (ce *CentrifyExtensions) loadFields(fields map[string]string) {
+	*ce = CentrifyExtensions{}
+	_ = BindFields(ce, fields)
+	ce.extra = collectUnbound(reflect.TypeOf(ce).Elem(), fields)
+}
+
 // GetField dynamically retrieves a field value by name using reflection.
 func (ce *CentrifyExtensions) GetField(fieldName string) (interface{}, error) {
 	r := reflect.ValueOf(ce)
 	f := reflect.Indirect(r).FieldByName(fieldName)
-	if f.IsValid() {
+	if f.IsValid() && f.CanInterface() {
 		return f.Interface(), nil
 	}
 	return nil, fmt.Errorf("field %s not found", fieldName)
@@ -94,3 +87,39 @@ func (ce *CentrifyExtensions) AsMap() map[string]string {
 func (ce *CentrifyExtensions) GetFieldNames() []string {
 	return getFieldNames(ce)
 }
+
+// CustomFields returns the custom fields keyed by their labels. Fields sharing a
+// label are resolved with LabelCollisionSuffix.
+func (ce *CentrifyExtensions) CustomFields() map[string]string {
+	folded, _ := FoldCustomFields(ce, LabelCollisionSuffix)
+	return folded
+}
+
+// SetField converts value and stores it in the field bound to key, or as an
+// unbound field if no field is bound to it.
+func (ce *CentrifyExtensions) SetField(key, value string) error {
+	return setStructField(ce, key, value)
+}
+
+// DeleteField clears the field or unbound field of key and reports whether it
+// was set.
+func (ce *CentrifyExtensions) DeleteField(key string) bool {
+	return deleteStructField(ce, key)
+}
+
+// RenameField moves the value bound to oldKey to the field bound to newKey.
+func (ce *CentrifyExtensions) RenameField(oldKey, newKey string) error {
+	return renameStructField(ce, oldKey, newKey)
+}
+
+// Clone returns a deep copy of the extensions.
+func (ce *CentrifyExtensions) Clone() Extensions {
+	clone := deepCopy(reflect.ValueOf(ce)).Interface().(*CentrifyExtensions)
+	clone.extra = maps.Clone(ce.extra)
+	return clone
+}
+
+// unboundFields returns the extension fields that have no struct field.
+func (ce *CentrifyExtensions) unboundFields() *map[string]string {
+	return &ce.extra
+}
@@ -0,0 +1,292 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// CIMDataModel names a Splunk Common Information Model data model.
+type CIMDataModel string
+
+// Data models produced by ToCIM.
+const (
+	CIMWeb                CIMDataModel = "Web"
+	CIMAuthentication     CIMDataModel = "Authentication"
+	CIMIntrusionDetection CIMDataModel = "Intrusion_Detection"
+)
+
+// cimTags holds the tags that place events in each data model.
+var cimTags = map[CIMDataModel][]string{
+	CIMWeb:                {"web"},
+	CIMAuthentication:     {"authentication"},
+	CIMIntrusionDetection: {"ids", "attack"},
+}
+
+// cimSeverities holds the CIM severity names by OCSF severity_id; see
+// ocsfSeverityID.
+var cimSeverities = []string{"unknown", "informational", "low", "medium", "high", "critical"}
+
+// cimField is the CIM field a CEF extension key is mapped to.
+type cimField struct {
+	name    string
+	numeric bool
+}
+
+// cimFields maps lowercase standard CEF extension keys to CIM fields.
+var cimFields = map[string]cimField{
+	"act":                      {"action", false},
+	"app":                      {"app", false},
+	"cat":                      {"category", false},
+	"dmac":                     {"dest_mac", false},
+	"dpt":                      {"dest_port", true},
+	"dst":                      {"dest_ip", false},
+	"duser":                    {"user", false},
+	"duid":                     {"user_id", false},
+	"fname":                    {"file_name", false},
+	"filepath":                 {"file_path", false},
+	"filehash":                 {"file_hash", false},
+	"in":                       {"bytes_in", true},
+	"out":                      {"bytes_out", true},
+	"proto":                    {"transport", false},
+	"reason":                   {"reason", false},
+	"request":                  {"url", false},
+	"requestclientapplication": {"http_user_agent", false},
+	"requestcontext":           {"http_referrer", false},
+	"requestmethod":            {"http_method", false},
+	"smac":                     {"src_mac", false},
+	"spt":                      {"src_port", true},
+	"src":                      {"src_ip", false},
+	"suid":                     {"src_user_id", false},
+	"suser":                    {"src_user", false},
+}
+
+// impervaCIMFields maps Imperva Cloud WAF keys, where src and cpt describe the
+// client and sip and spt the protected server.
+var impervaCIMFields = map[string]cimField{
+	"cpt":               {"src_port", true},
+	"sip":               {"dest_ip", false},
+	"spt":               {"dest_port", true},
+	"sourceservicename": {"site", false},
+	"qstr":              {"uri_query", false},
+	"ref":               {"http_referrer", false},
+	"cn1":               {"status", true},
+	"in":                {"bytes_out", true},
+}
+
+// centrifyCIMFields maps Centrify keys, where duser is the authenticating user.
+var centrifyCIMFields = map[string]cimField{
+	"suid":       {"user_id", false},
+	"authmethod": {"authentication_method", false},
+}
+
+// ToCIM maps the CEF event to the fields of a Splunk CIM data model:
+//   - Web for Imperva Cloud WAF events and events with a request URL or method
+//   - Authentication for Centrify login, logout and application launch events
+//     and for events whose name or category mentions logins or authentication
+//   - Intrusion_Detection for events whose name or category mentions an
+//     attack, an intrusion, an exploit or malware
+//
+// Other events get no data model, returned as "", and no tags. Every event
+// gets the vendor, product, vendor_product, signature, signature_id and
+// severity fields, src and dest from the host names or
+// addresses, and dvc from dvchost or dvc. Extension fields are mapped to their
+// CIM names, with ports, byte counts and status codes as numbers, and action
+// is normalized to the values of the data model.
+func ToCIM(cef *CEF) (CIMDataModel, map[string]interface{}) {
+	fields := extensionFields(cef.Extensions)
+	out := map[string]interface{}{
+		"vendor":         cef.DeviceVendor,
+		"product":        cef.DeviceProduct,
+		"vendor_product": strings.TrimSpace(cef.DeviceVendor + " " + cef.DeviceProduct),
+		"signature":      cef.Name,
+		"signature_id":   cef.SignatureID,
+		"severity":       cimSeverities[ocsfSeverityID(cef.Severity)],
+	}
+	if n, err := strconv.Atoi(cef.Severity); err == nil {
+		out["severity_id"] = n
+	}
+
+	var vendorFields map[string]cimField
+	var model CIMDataModel
+	switch {
+	case cef.DeviceVendor == "Incapsula" && cef.DeviceProduct == "SIEMintegration":
+		model, vendorFields = CIMWeb, impervaCIMFields
+	case cef.DeviceVendor == "Centrify" && centrifyAuthActivity(cef.Name) != 0:
+		model, vendorFields = CIMAuthentication, centrifyCIMFields
+	}
+
+	// When several keys map to the same CIM field, a vendor mapping wins over
+	// a standard one and the first key in Format order wins otherwise.
+	mapped := make(map[string]bool) // CIM field name to whether a vendor mapping set it
+	for _, ext := range formatFields(cef.Extensions) {
+		if strings.TrimSpace(ext.Value) == "" {
+			continue
+		}
+		lower := strings.ToLower(ext.Key)
+		field, vendor := vendorFields[lower]
+		if !vendor {
+			var ok bool
+			if field, ok = cimFields[lower]; !ok {
+				continue
+			}
+		}
+		if byVendor, ok := mapped[field.name]; ok && (byVendor || !vendor) {
+			continue
+		}
+		if !field.numeric {
+			out[field.name] = ext.Value
+		} else if n, err := strconv.ParseInt(strings.TrimSpace(ext.Value), 10, 64); err == nil {
+			out[field.name] = n
+		} else {
+			continue
+		}
+		mapped[field.name] = vendor
+	}
+
+	if vendorFields == nil {
+		_, hasURL := out["url"]
+		_, hasMethod := out["http_method"]
+		if hasURL || hasMethod {
+			model = CIMWeb
+		} else if isAuthenticationEvent(cef, out) {
+			model = CIMAuthentication
+		} else if isIntrusionEvent(cef, out) {
+			model = CIMIntrusionDetection
+		}
+	}
+
+	if outcome, ok := lookupKey(fields, "outcome"); ok && outcome != "" {
+		if _, ok := out["action"]; !ok || model == CIMAuthentication {
+			out["action"] = outcome
+		}
+	}
+	cimEndpoint(out, "src", fields, "shost")
+	cimEndpoint(out, "dest", fields, "dhost")
+	if host, ok := lookupKey(fields, "dvchost"); ok && host != "" {
+		out["dvc"] = host
+	} else if addr, ok := lookupKey(fields, "dvc"); ok && addr != "" {
+		out["dvc"] = addr
+	}
+	if _, ok := out["user"]; !ok {
+		if user, ok := out["src_user"]; ok {
+			out["user"] = user
+		}
+	}
+	if transport, ok := out["transport"].(string); ok {
+		out["transport"] = strings.ToLower(transport)
+	}
+
+	switch model {
+	case CIMWeb:
+		if site, ok := out["site"].(string); ok {
+			out["dest"] = site
+		}
+		if url, ok := out["url"].(string); ok {
+			out["uri_path"] = cimURIPath(url)
+		}
+		cimAction(out, "blocked", "allowed")
+	case CIMAuthentication:
+		if cef.DeviceVendor == "Centrify" {
+			if strings.Contains(strings.ToLower(cef.Name), "fail") {
+				out["action"] = "failure"
+			} else {
+				out["action"] = "success"
+			}
+			labels, _ := FoldCustomFields(cef.Extensions, LabelCollisionFirst)
+			if app := labels["applicationName"]; app != "" {
+				out["app"] = app
+			} else if service, ok := lookupKey(fields, "destinationServiceName"); ok {
+				out["app"] = service
+			}
+		}
+		cimAction(out, "failure", "success")
+	case CIMIntrusionDetection:
+		out["ids_type"] = "network"
+		cimAction(out, "blocked", "allowed")
+	}
+	if tags := cimTags[model]; tags != nil {
+		out["tag"] = append([]string(nil), tags...)
+	}
+	return model, out
+}
+
+// isAuthenticationEvent reports whether the name or category of the CEF event
+// describes a login or an authentication.
+func isAuthenticationEvent(cef *CEF, out map[string]interface{}) bool {
+	return mentionsAny(cef, out, "login", "logon", "logout", "logoff", "authentic")
+}
+
+// isIntrusionEvent reports whether the name or category of the CEF event
+// describes an attack, an intrusion, an exploit or malware.
+func isIntrusionEvent(cef *CEF, out map[string]interface{}) bool {
+	return mentionsAny(cef, out, "attack", "intrusion", "exploit", "malware", "worm", "virus", "trojan")
+}
+
+// mentionsAny reports whether the name or the mapped category of the CEF
+// event contains any of words, ignoring case.
+func mentionsAny(cef *CEF, out map[string]interface{}, words ...string) bool {
+	category, _ := out["category"].(string)
+	text := strings.ToLower(cef.Name + " " + category)
+	for _, word := range words {
+		if strings.Contains(text, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// cimEndpoint sets the CIM field name ("src" or "dest") from the host name
+// extension key, or else from the name_ip field, which is dropped unless it is
+// a valid address.
+func cimEndpoint(out map[string]interface{}, name string, fields map[string]string, hostKey string) {
+	if ip, ok := out[name+"_ip"].(string); ok {
+		if addr, err := netip.ParseAddr(strings.TrimSpace(ip)); err == nil {
+			out[name+"_ip"] = addr.String()
+			out[name] = addr.String()
+		} else {
+			delete(out, name+"_ip")
+		}
+	}
+	if host, ok := lookupKey(fields, hostKey); ok && host != "" {
+		out[name] = host
+	}
+}
+
+// cimAction normalizes the action field to negative or positive when it names
+// a well-known outcome, and lowercases it otherwise.
+func cimAction(out map[string]interface{}, negative, positive string) {
+	action, ok := out["action"].(string)
+	if !ok {
+		return
+	}
+	lower := strings.ToLower(action)
+	for _, word := range []string{"block", "deny", "denied", "drop", "reject", "fail"} {
+		if strings.Contains(lower, word) {
+			out["action"] = negative
+			return
+		}
+	}
+	for _, word := range []string{"allow", "accept", "pass", "permit", "success", "cached"} {
+		if strings.Contains(lower, word) {
+			out["action"] = positive
+			return
+		}
+	}
+	out["action"] = lower
+}
+
+// cimURIPath returns the path of a URL that may lack a scheme, such as
+// "example.com/path?q=1".
+func cimURIPath(url string) string {
+	if _, rest, found := strings.Cut(url, "://"); found {
+		url = rest
+	}
+	i := strings.IndexAny(url, "/?")
+	if i < 0 || url[i] != '/' {
+		return "/"
+	}
+	path, _, _ := strings.Cut(url[i:], "?")
+	return path
+}
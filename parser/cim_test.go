@@ -0,0 +1,151 @@
+// Tests for the Splunk CIM mapping.
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestToCIM tests the data model selection and field mapping.
+func TestToCIM(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		model    CIMDataModel
+		expected map[string]interface{}
+		absent   []string
+	}{
+		{
+			name:  "Imperva",
+			line:  ImpervaCEF1,
+			model: CIMWeb,
+			expected: map[string]interface{}{
+				"vendor_product": "Incapsula SIEMintegration",
+				"src":            "123.123.123.123",
+				"src_ip":         "123.123.123.123",
+				"src_port":       int64(10401),
+				"dest":           "example.com",
+				"dest_port":      int64(443),
+				"http_method":    "GET",
+				"url":            "example.com/path/to/resource",
+				"uri_path":       "/path/to/resource",
+				"status":         int64(200),
+				"bytes_out":      int64(451),
+				"http_referrer":  "https://example.com/path/to/referrer",
+				"action":         "allowed",
+				"severity":       "informational",
+				"severity_id":    0,
+				"tag":            []string{"web"},
+			},
+		},
+		{
+			name:  "Centrify",
+			line:  CentrifyCEF,
+			model: CIMAuthentication,
+			expected: map[string]interface{}{
+				"user":      "cloudadmin@persistent.com01",
+				"user_id":   "c2c7bcc6-9560-44e0-8dff-5be221cd37ee",
+				"src":       "103.6.32.100",
+				"dest":      "AAA0056",
+				"app":       "Instagram",
+				"action":    "success",
+				"dvc":       "dinesh-VirtualBox",
+				"signature": "Cloud.Saas.Application.SelfServiceAppLaunch",
+				"tag":       []string{"authentication"},
+			},
+		},
+		{
+			name:  "Generic authentication",
+			line:  "CEF:0|Acme|SSO|1|4625|Logon failure|5|suser=bob src=10.0.0.5 outcome=Failure act=deny",
+			model: CIMAuthentication,
+			expected: map[string]interface{}{
+				"user":     "bob",
+				"src_user": "bob",
+				"action":   "failure",
+				"severity": "medium",
+			},
+		},
+		{
+			name:  "Intrusion detection",
+			line:  "CEF:0|Security|threatmanager|1.0|100|worm successfully stopped|10|src=10.0.0.1 dst=2.1.2.2 spt=1232 dpt=80 proto=TCP act=Blocked shost=attacker dvc=10.0.0.9",
+			model: CIMIntrusionDetection,
+			expected: map[string]interface{}{
+				"src":          "attacker",
+				"src_ip":       "10.0.0.1",
+				"dest":         "2.1.2.2",
+				"dest_port":    int64(80),
+				"transport":    "tcp",
+				"action":       "blocked",
+				"dvc":          "10.0.0.9",
+				"ids_type":     "network",
+				"signature_id": "100",
+				"severity":     "critical",
+				"tag":          []string{"ids", "attack"},
+			},
+		},
+		{
+			name:  "Unclassified",
+			line:  "CEF:0|Acme|Firewall|1|200|Connection closed|3|src=10.0.0.1 dst=2.1.2.2 act=Closed",
+			model: "",
+			expected: map[string]interface{}{
+				"src":      "10.0.0.1",
+				"dest":     "2.1.2.2",
+				"action":   "Closed",
+				"severity": "low",
+			},
+			absent: []string{"tag", "ids_type"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cefEvent, err := ParseCEF(test.line)
+			if err != nil {
+				t.Fatalf("ParseCEF() error = %v", err)
+			}
+			model, fields := ToCIM(cefEvent)
+			if model != test.model {
+				t.Errorf("model = %s, want %s", model, test.model)
+			}
+			for name, want := range test.expected {
+				if got := fields[name]; !reflect.DeepEqual(got, want) {
+					t.Errorf("%s = %#v, want %#v", name, got, want)
+				}
+			}
+			for _, name := range test.absent {
+				if got, ok := fields[name]; ok {
+					t.Errorf("unexpected %s = %#v", name, got)
+				}
+			}
+		})
+	}
+}
+
+// TestToCIMFieldPrecedence tests that keys mapped to the same CIM field are
+// resolved the same way every time.
+func TestToCIMFieldPrecedence(t *testing.T) {
+	imperva, _ := ParseCEF("CEF:0|Incapsula|SIEMintegration|0|1|N|5|requestContext=https://a.example ref=https://b.example")
+	generic, _ := ParseCEF("CEF:0|V|P|1|2|N|3|SRC=10.0.0.2 src=10.0.0.1")
+	for i := 0; i < 20; i++ {
+		if _, fields := ToCIM(imperva); fields["http_referrer"] != "https://b.example" {
+			t.Fatalf("http_referrer = %v, want the vendor mapping of ref", fields["http_referrer"])
+		}
+		if _, fields := ToCIM(generic); fields["src_ip"] != "10.0.0.2" {
+			t.Fatalf("src_ip = %v, want the first key in Format order", fields["src_ip"])
+		}
+	}
+}
+
+// TestCIMURIPath tests path extraction from URLs with and without a scheme.
+func TestCIMURIPath(t *testing.T) {
+	for url, expected := range map[string]string{
+		"example.com/a/b?q=1":  "/a/b",
+		"https://example.com":  "/",
+		"https://example.com/": "/",
+		"example.com?x=/y":     "/",
+	} {
+		if got := cimURIPath(url); got != expected {
+			t.Errorf("cimURIPath(%q) = %q, want %q", url, got, expected)
+		}
+	}
+}
@@ -4,6 +4,7 @@ package parser
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
 )
 
 // DefaultExtensions provides a generic implementation of the Extensions interface.
@@ -13,10 +14,15 @@ type DefaultExtensions struct {
 
 // ParseExtensions parses the extension string into a map.
 func (de *DefaultExtensions) ParseExtensions(extension string) map[string]string {
-	de.Fields = parseExtensions(extension)
+	de.loadFields(parseExtensions(extension))
 	return de.Fields
 }
 
+// loadFields stores the tokenized extension fields.
+func (de *DefaultExtensions) loadFields(fields map[string]string) {
+	de.Fields = fields
+}
+
 // GetField dynamically retrieves a field value by name.
 func (de *DefaultExtensions) GetField(fieldName string) (interface{}, error) {
 	if value, ok := de.Fields[fieldName]; ok {
@@ -44,3 +50,49 @@ func (de *DefaultExtensions) GetFieldNames() []string {
 	}
 	return fieldNames
 }
+
+// CustomFields returns the custom fields keyed by their labels. Fields sharing a
+// label are resolved with LabelCollisionSuffix.
+func (de *DefaultExtensions) CustomFields() map[string]string {
+	folded, _ := FoldCustomFields(de, LabelCollisionSuffix)
+	return folded
+}
+
+// SetField sets the value of key, adding the field if it is absent.
+func (de *DefaultExtensions) SetField(key, value string) error {
+	if err := checkSetKey(key); err != nil {
+		return err
+	}
+	if de.Fields == nil {
+		de.Fields = make(map[string]string)
+	}
+	de.Fields[key] = value
+	return nil
+}
+
+// DeleteField removes key and reports whether it was present.
+func (de *DefaultExtensions) DeleteField(key string) bool {
+	_, ok := de.Fields[key]
+	delete(de.Fields, key)
+	return ok
+}
+
+// RenameField moves the value of oldKey to newKey, replacing any value newKey
+// already has.
+func (de *DefaultExtensions) RenameField(oldKey, newKey string) error {
+	value, ok := de.Fields[oldKey]
+	if !ok {
+		return fmt.Errorf("field %s not found", oldKey)
+	}
+	if err := checkSetKey(newKey); err != nil {
+		return err
+	}
+	delete(de.Fields, oldKey)
+	de.Fields[newKey] = value
+	return nil
+}
+
+// Clone returns a deep copy of the extensions.
+func (de *DefaultExtensions) Clone() Extensions {
+	return &DefaultExtensions{Fields: maps.Clone(de.Fields)}
+}
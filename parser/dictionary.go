@@ -0,0 +1,228 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DataType is the data type of a CEF extension field in the ArcSight dictionary.
+type DataType int
+
+const (
+	// TypeString is a free-form string.
+	TypeString DataType = iota
+	// TypeInteger is a 32-bit signed integer.
+	TypeInteger
+	// TypeLong is a 64-bit signed integer.
+	TypeLong
+	// TypeFloat is a single-precision floating point number.
+	TypeFloat
+	// TypeDouble is a double-precision floating point number.
+	TypeDouble
+	// TypeIPv4 is an IPv4 address.
+	TypeIPv4
+	// TypeIPv6 is an IPv6 address.
+	TypeIPv6
+	// TypeMAC is a MAC address.
+	TypeMAC
+	// TypeTimestamp is a point in time.
+	TypeTimestamp
+)
+
+// dataTypeNames holds the display name of each DataType.
+var dataTypeNames = map[DataType]string{
+	TypeString:    "String",
+	TypeInteger:   "Integer",
+	TypeLong:      "Long",
+	TypeFloat:     "Floating Point",
+	TypeDouble:    "Double",
+	TypeIPv4:      "IPv4 Address",
+	TypeIPv6:      "IPv6 Address",
+	TypeMAC:       "MAC Address",
+	TypeTimestamp: "Time Stamp",
+}
+
+// String returns the dictionary name of the data type.
+func (dt DataType) String() string {
+	if name, ok := dataTypeNames[dt]; ok {
+		return name
+	}
+	return fmt.Sprintf("DataType(%d)", int(dt))
+}
+
+// FieldDefinition describes a standard CEF extension key.
+type FieldDefinition struct {
+	Key       string   // CEF key, such as "src"
+	FullName  string   // ArcSight full name, such as "sourceAddress"
+	Type      DataType // data type of the value
+	MaxLength int      // maximum length for String values, zero if not applicable
+}
+
+// dictionary lists the standard CEF extension keys from the ArcSight dictionary.
+var dictionary = []FieldDefinition{
+	{"act", "deviceAction", TypeString, 63},
+	{"agt", "agentAddress", TypeIPv4, 0},
+	{"ahost", "agentHostName", TypeString, 1023},
+	{"aid", "agentId", TypeString, 40},
+	{"amac", "agentMacAddress", TypeMAC, 0},
+	{"app", "applicationProtocol", TypeString, 31},
+	{"art", "agentReceiptTime", TypeTimestamp, 0},
+	{"at", "agentType", TypeString, 63},
+	{"atz", "agentTimeZone", TypeString, 255},
+	{"av", "agentVersion", TypeString, 31},
+	{"c6a1", "deviceCustomIPv6Address1", TypeIPv6, 0},
+	{"c6a1Label", "deviceCustomIPv6Address1Label", TypeString, 1023},
+	{"c6a3", "deviceCustomIPv6Address3", TypeIPv6, 0},
+	{"c6a3Label", "deviceCustomIPv6Address3Label", TypeString, 1023},
+	{"c6a4", "deviceCustomIPv6Address4", TypeIPv6, 0},
+	{"c6a4Label", "deviceCustomIPv6Address4Label", TypeString, 1023},
+	{"cat", "deviceEventCategory", TypeString, 1023},
+	{"catdt", "categoryDeviceType", TypeString, 1023},
+	{"cfp1", "deviceCustomFloatingPoint1", TypeFloat, 0},
+	{"cfp1Label", "deviceCustomFloatingPoint1Label", TypeString, 1023},
+	{"cfp2", "deviceCustomFloatingPoint2", TypeFloat, 0},
+	{"cfp2Label", "deviceCustomFloatingPoint2Label", TypeString, 1023},
+	{"cfp3", "deviceCustomFloatingPoint3", TypeFloat, 0},
+	{"cfp3Label", "deviceCustomFloatingPoint3Label", TypeString, 1023},
+	{"cfp4", "deviceCustomFloatingPoint4", TypeFloat, 0},
+	{"cfp4Label", "deviceCustomFloatingPoint4Label", TypeString, 1023},
+	{"cn1", "deviceCustomNumber1", TypeLong, 0},
+	{"cn1Label", "deviceCustomNumber1Label", TypeString, 1023},
+	{"cn2", "deviceCustomNumber2", TypeLong, 0},
+	{"cn2Label", "deviceCustomNumber2Label", TypeString, 1023},
+	{"cn3", "deviceCustomNumber3", TypeLong, 0},
+	{"cn3Label", "deviceCustomNumber3Label", TypeString, 1023},
+	{"cnt", "baseEventCount", TypeInteger, 0},
+	{"cs1", "deviceCustomString1", TypeString, 4000},
+	{"cs1Label", "deviceCustomString1Label", TypeString, 1023},
+	{"cs2", "deviceCustomString2", TypeString, 4000},
+	{"cs2Label", "deviceCustomString2Label", TypeString, 1023},
+	{"cs3", "deviceCustomString3", TypeString, 4000},
+	{"cs3Label", "deviceCustomString3Label", TypeString, 1023},
+	{"cs4", "deviceCustomString4", TypeString, 4000},
+	{"cs4Label", "deviceCustomString4Label", TypeString, 1023},
+	{"cs5", "deviceCustomString5", TypeString, 4000},
+	{"cs5Label", "deviceCustomString5Label", TypeString, 1023},
+	{"cs6", "deviceCustomString6", TypeString, 4000},
+	{"cs6Label", "deviceCustomString6Label", TypeString, 1023},
+	{"destinationDnsDomain", "destinationDnsDomain", TypeString, 255},
+	{"destinationServiceName", "destinationServiceName", TypeString, 1023},
+	{"destinationTranslatedAddress", "destinationTranslatedAddress", TypeIPv4, 0},
+	{"destinationTranslatedPort", "destinationTranslatedPort", TypeInteger, 0},
+	{"deviceCustomDate1", "deviceCustomDate1", TypeTimestamp, 0},
+	{"deviceCustomDate1Label", "deviceCustomDate1Label", TypeString, 1023},
+	{"deviceCustomDate2", "deviceCustomDate2", TypeTimestamp, 0},
+	{"deviceCustomDate2Label", "deviceCustomDate2Label", TypeString, 1023},
+	{"deviceDirection", "deviceDirection", TypeInteger, 0},
+	{"deviceDnsDomain", "deviceDnsDomain", TypeString, 255},
+	{"deviceExternalId", "deviceExternalId", TypeString, 255},
+	{"deviceFacility", "deviceFacility", TypeString, 1023},
+	{"deviceInboundInterface", "deviceInboundInterface", TypeString, 128},
+	{"deviceNtDomain", "deviceNtDomain", TypeString, 255},
+	{"deviceOutboundInterface", "deviceOutboundInterface", TypeString, 128},
+	{"devicePayloadId", "devicePayloadId", TypeString, 128},
+	{"deviceProcessName", "deviceProcessName", TypeString, 1023},
+	{"deviceTranslatedAddress", "deviceTranslatedAddress", TypeIPv4, 0},
+	{"dhost", "destinationHostName", TypeString, 1023},
+	{"dlat", "destinationGeoLatitude", TypeDouble, 0},
+	{"dlong", "destinationGeoLongitude", TypeDouble, 0},
+	{"dmac", "destinationMacAddress", TypeMAC, 0},
+	{"dntdom", "destinationNtDomain", TypeString, 255},
+	{"dpid", "destinationProcessId", TypeInteger, 0},
+	{"dpriv", "destinationUserPrivileges", TypeString, 1023},
+	{"dproc", "destinationProcessName", TypeString, 1023},
+	{"dpt", "destinationPort", TypeInteger, 0},
+	{"dst", "destinationAddress", TypeIPv4, 0},
+	{"dtz", "deviceTimeZone", TypeString, 255},
+	{"duid", "destinationUserId", TypeString, 1023},
+	{"duser", "destinationUserName", TypeString, 1023},
+	{"dvc", "deviceAddress", TypeIPv4, 0},
+	{"dvchost", "deviceHostName", TypeString, 100},
+	{"dvcmac", "deviceMacAddress", TypeMAC, 0},
+	{"dvcpid", "deviceProcessId", TypeInteger, 0},
+	{"end", "endTime", TypeTimestamp, 0},
+	{"externalId", "externalId", TypeString, 40},
+	{"fileCreateTime", "fileCreateTime", TypeTimestamp, 0},
+	{"fileHash", "fileHash", TypeString, 255},
+	{"fileId", "fileId", TypeString, 1023},
+	{"fileModificationTime", "fileModificationTime", TypeTimestamp, 0},
+	{"filePath", "filePath", TypeString, 1023},
+	{"filePermission", "filePermission", TypeString, 1023},
+	{"fileType", "fileType", TypeString, 1023},
+	{"flexDate1", "flexDate1", TypeTimestamp, 0},
+	{"flexDate1Label", "flexDate1Label", TypeString, 128},
+	{"flexNumber1", "flexNumber1", TypeLong, 0},
+	{"flexNumber1Label", "flexNumber1Label", TypeString, 128},
+	{"flexNumber2", "flexNumber2", TypeLong, 0},
+	{"flexNumber2Label", "flexNumber2Label", TypeString, 128},
+	{"flexString1", "flexString1", TypeString, 1023},
+	{"flexString1Label", "flexString1Label", TypeString, 128},
+	{"flexString2", "flexString2", TypeString, 1023},
+	{"flexString2Label", "flexString2Label", TypeString, 128},
+	{"fname", "fileName", TypeString, 1023},
+	{"fsize", "fileSize", TypeInteger, 0},
+	{"in", "bytesIn", TypeInteger, 0},
+	{"msg", "message", TypeString, 1023},
+	{"oldFileCreateTime", "oldFileCreateTime", TypeTimestamp, 0},
+	{"oldFileHash", "oldFileHash", TypeString, 255},
+	{"oldFileId", "oldFileId", TypeString, 1023},
+	{"oldFileModificationTime", "oldFileModificationTime", TypeTimestamp, 0},
+	{"oldFileName", "oldFileName", TypeString, 1023},
+	{"oldFilePath", "oldFilePath", TypeString, 1023},
+	{"oldFilePermission", "oldFilePermission", TypeString, 1023},
+	{"oldFileSize", "oldFileSize", TypeInteger, 0},
+	{"oldFileType", "oldFileType", TypeString, 1023},
+	{"out", "bytesOut", TypeInteger, 0},
+	{"outcome", "eventOutcome", TypeString, 63},
+	{"proto", "transportProtocol", TypeString, 31},
+	{"reason", "Reason", TypeString, 1023},
+	{"request", "requestUrl", TypeString, 1023},
+	{"requestClientApplication", "requestClientApplication", TypeString, 1023},
+	{"requestContext", "requestContext", TypeString, 2048},
+	{"requestCookies", "requestCookies", TypeString, 1023},
+	{"requestMethod", "requestMethod", TypeString, 1023},
+	{"rt", "deviceReceiptTime", TypeTimestamp, 0},
+	{"shost", "sourceHostName", TypeString, 1023},
+	{"slat", "sourceGeoLatitude", TypeDouble, 0},
+	{"slong", "sourceGeoLongitude", TypeDouble, 0},
+	{"smac", "sourceMacAddress", TypeMAC, 0},
+	{"sntdom", "sourceNtDomain", TypeString, 255},
+	{"sourceDnsDomain", "sourceDnsDomain", TypeString, 255},
+	{"sourceServiceName", "sourceServiceName", TypeString, 1023},
+	{"sourceTranslatedAddress", "sourceTranslatedAddress", TypeIPv4, 0},
+	{"sourceTranslatedPort", "sourceTranslatedPort", TypeInteger, 0},
+	{"spid", "sourceProcessId", TypeInteger, 0},
+	{"spriv", "sourceUserPrivileges", TypeString, 1023},
+	{"sproc", "sourceProcessName", TypeString, 1023},
+	{"spt", "sourcePort", TypeInteger, 0},
+	{"src", "sourceAddress", TypeIPv4, 0},
+	{"start", "startTime", TypeTimestamp, 0},
+	{"suid", "sourceUserId", TypeString, 1023},
+	{"suser", "sourceUserName", TypeString, 1023},
+	{"type", "type", TypeInteger, 0},
+}
+
+// dictionaryIndex maps lower-case keys and full names to dictionary entries.
+var dictionaryIndex = func() map[string]FieldDefinition {
+	index := make(map[string]FieldDefinition, 2*len(dictionary))
+	for _, def := range dictionary {
+		index[strings.ToLower(def.FullName)] = def
+	}
+	for _, def := range dictionary {
+		index[strings.ToLower(def.Key)] = def
+	}
+	return index
+}()
+
+// LookupField returns the dictionary definition for a CEF key or its full
+// name, such as "src" or "sourceAddress". The lookup is case-insensitive.
+func LookupField(name string) (FieldDefinition, bool) {
+	def, ok := dictionaryIndex[strings.ToLower(name)]
+	return def, ok
+}
+
+// FieldDefinitions returns a copy of the standard CEF extension dictionary.
+func FieldDefinitions() []FieldDefinition {
+	return append([]FieldDefinition(nil), dictionary...)
+}
@@ -0,0 +1,409 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ECSVersion is the Elastic Common Schema version of the documents produced by
+// ToECS.
+const ECSVersion = "8.11.0"
+
+// ECSDocument is an Elastic Common Schema document as nested maps, ready to be
+// encoded as JSON.
+type ECSDocument map[string]interface{}
+
+// Set stores value at the dotted ECS field path, such as "source.ip", creating
+// intermediate objects as needed. A non-object value on the way is replaced.
+func (d ECSDocument) Set(path string, value interface{}) {
+	parent, name := d.parent(path, true)
+	parent[name] = value
+}
+
+// Get returns the value at the dotted ECS field path.
+func (d ECSDocument) Get(path string) (interface{}, bool) {
+	parent, name := d.parent(path, false)
+	if parent == nil {
+		return nil, false
+	}
+	value, ok := parent[name]
+	return value, ok
+}
+
+// Delete removes the value at the dotted ECS field path. Objects left empty are
+// kept.
+func (d ECSDocument) Delete(path string) {
+	if parent, name := d.parent(path, false); parent != nil {
+		delete(parent, name)
+	}
+}
+
+// parent returns the object holding the last element of path and the name of
+// that element, creating missing objects when create is set. It returns a nil
+// object when path cannot be resolved.
+func (d ECSDocument) parent(path string, create bool) (ECSDocument, string) {
+	obj := d
+	for {
+		name, rest, found := strings.Cut(path, ".")
+		if !found {
+			return obj, name
+		}
+		next, ok := obj[name].(ECSDocument)
+		if !ok {
+			if !create {
+				return nil, ""
+			}
+			next = ECSDocument{}
+			obj[name] = next
+		}
+		obj, path = next, rest
+	}
+}
+
+// ECSField is the ECS field a CEF extension key is mapped to.
+type ECSField struct {
+	Path string   // dotted ECS field name, such as "source.ip"
+	Type DataType // type the value is converted to
+}
+
+// ECSOverride customizes the ECS mapping for the events of a vendor.
+type ECSOverride struct {
+	// Fields maps CEF extension keys, matched case-insensitively, to ECS
+	// fields. It takes precedence over the standard mapping; an empty Path
+	// leaves the key unmapped.
+	Fields map[string]ECSField
+	// Transform, if set, is called with the finished document to add or
+	// rewrite fields that need more than a one-to-one mapping.
+	Transform func(cef *CEF, doc ECSDocument)
+}
+
+// ecsFields maps lowercase standard CEF extension keys to ECS fields.
+var ecsFields = map[string]ECSField{
+	"act":                          {"event.action", TypeString},
+	"app":                          {"network.protocol", TypeString},
+	"destinationtranslatedaddress": {"destination.nat.ip", TypeIPv6},
+	"destinationtranslatedport":    {"destination.nat.port", TypeInteger},
+	"deviceexternalid":             {"observer.name", TypeString},
+	"deviceinboundinterface":       {"observer.ingress.interface.name", TypeString},
+	"deviceoutboundinterface":      {"observer.egress.interface.name", TypeString},
+	"deviceprocessname":            {"process.name", TypeString},
+	"dhost":                        {"destination.domain", TypeString},
+	"dmac":                         {"destination.mac", TypeMAC},
+	"dntdom":                       {"destination.user.domain", TypeString},
+	"dpid":                         {"process.pid", TypeInteger},
+	"dproc":                        {"process.name", TypeString},
+	"dpt":                          {"destination.port", TypeInteger},
+	"dst":                          {"destination.ip", TypeIPv6},
+	"dtz":                          {"event.timezone", TypeString},
+	"duid":                         {"destination.user.id", TypeString},
+	"duser":                        {"destination.user.name", TypeString},
+	"dvc":                          {"observer.ip", TypeIPv6},
+	"dvchost":                      {"observer.hostname", TypeString},
+	"dvcmac":                       {"observer.mac", TypeMAC},
+	"end":                          {"event.end", TypeTimestamp},
+	"externalid":                   {"event.id", TypeString},
+	"filepath":                     {"file.path", TypeString},
+	"fname":                        {"file.name", TypeString},
+	"fsize":                        {"file.size", TypeLong},
+	"in":                           {"source.bytes", TypeLong},
+	"msg":                          {"message", TypeString},
+	"out":                          {"destination.bytes", TypeLong},
+	"outcome":                      {"event.outcome", TypeString},
+	"proto":                        {"network.transport", TypeString},
+	"reason":                       {"event.reason", TypeString},
+	"request":                      {"url.original", TypeString},
+	"requestclientapplication":     {"user_agent.original", TypeString},
+	"requestcontext":               {"http.request.referrer", TypeString},
+	"requestmethod":                {"http.request.method", TypeString},
+	"rt":                           {"@timestamp", TypeTimestamp},
+	"shost":                        {"source.domain", TypeString},
+	"smac":                         {"source.mac", TypeMAC},
+	"sntdom":                       {"source.user.domain", TypeString},
+	"sourcetranslatedaddress":      {"source.nat.ip", TypeIPv6},
+	"sourcetranslatedport":         {"source.nat.port", TypeInteger},
+	"spid":                         {"process.parent.pid", TypeInteger},
+	"sproc":                        {"process.parent.name", TypeString},
+	"spt":                          {"source.port", TypeInteger},
+	"src":                          {"source.ip", TypeIPv6},
+	"start":                        {"event.start", TypeTimestamp},
+	"suid":                         {"user.id", TypeString},
+	"suser":                        {"user.name", TypeString},
+}
+
+// ecsSeverities maps the textual CEF severities to numbers.
+var ecsSeverities = map[string]int{"unknown": 0, "low": 3, "medium": 6, "high": 8, "very-high": 10}
+
+// ecsOverride is a registered ECSOverride with its keys in lowercase.
+type ecsOverride struct {
+	fields    map[string]ECSField
+	transform func(cef *CEF, doc ECSDocument)
+}
+
+// ECSConverter converts CEF events to ECS documents, applying the overrides
+// registered for their vendor and product. An ECSConverter is safe for
+// concurrent use.
+type ECSConverter struct {
+	mu        sync.RWMutex
+	overrides map[[2]string]ecsOverride
+}
+
+// defaultECSConverter is the ECSConverter used by ToECS and RegisterECSOverride.
+var defaultECSConverter = NewDefaultECSConverter()
+
+// NewECSConverter returns an ECSConverter with the standard mapping only.
+func NewECSConverter() *ECSConverter {
+	return &ECSConverter{overrides: make(map[[2]string]ecsOverride)}
+}
+
+// NewDefaultECSConverter returns an ECSConverter with the built-in overrides for
+// Imperva and Centrify events.
+func NewDefaultECSConverter() *ECSConverter {
+	c := NewECSConverter()
+	_ = c.Register("Incapsula", "SIEMintegration", impervaECSOverride)
+	_ = c.Register("Centrify", "Centrify_Cloud", centrifyECSOverride)
+	return c
+}
+
+// Register sets the override for events from the given vendor and product,
+// replacing any previous one.
+func (c *ECSConverter) Register(vendor, product string, override ECSOverride) error {
+	if vendor == "" || product == "" {
+		return fmt.Errorf("vendor and product must not be empty")
+	}
+	fields := make(map[string]ECSField, len(override.Fields))
+	for key, field := range override.Fields {
+		fields[strings.ToLower(key)] = field
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.overrides[[2]string{vendor, product}] = ecsOverride{fields: fields, transform: override.Transform}
+	return nil
+}
+
+// Unregister removes the override for the vendor and product. It reports
+// whether an override was removed.
+func (c *ECSConverter) Unregister(vendor, product string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := [2]string{vendor, product}
+	if _, ok := c.overrides[key]; !ok {
+		return false
+	}
+	delete(c.overrides, key)
+	return true
+}
+
+// Convert returns the ECS document for the CEF event. The header is mapped to
+// the observer and event fields, the syslog envelope to log.syslog and the
+// extension fields to their ECS fields, converted to numbers, addresses and
+// times as the fields require. Folded custom fields are stored as labels.
+// Extension fields without an ECS field, or whose value cannot be converted,
+// are kept as strings under cef.extensions.
+func (c *ECSConverter) Convert(cef *CEF) ECSDocument {
+	c.mu.RLock()
+	override := c.overrides[[2]string{cef.DeviceVendor, cef.DeviceProduct}]
+	c.mu.RUnlock()
+
+	doc := ECSDocument{}
+	doc.Set("ecs.version", ECSVersion)
+	doc.Set("event.kind", "event")
+	doc.Set("event.code", cef.SignatureID)
+	doc.Set("observer.vendor", cef.DeviceVendor)
+	doc.Set("observer.product", cef.DeviceProduct)
+	doc.Set("observer.version", cef.DeviceVersion)
+	doc.Set("cef.version", cef.Version)
+	doc.Set("cef.name", cef.Name)
+	if severity, ok := ecsSeverity(cef.Severity); ok {
+		doc.Set("event.severity", severity)
+	}
+
+	if sl := cef.Syslog; sl != nil {
+		if sl.Priority >= 0 {
+			doc.Set("log.syslog.priority", sl.Priority)
+			doc.Set("log.syslog.facility.code", sl.Facility)
+			doc.Set("log.syslog.severity.code", sl.Severity)
+		}
+		if sl.Version > 0 {
+			doc.Set("log.syslog.version", strconv.Itoa(sl.Version))
+		}
+		for path, value := range map[string]string{
+			"log.syslog.hostname": sl.Hostname,
+			"log.syslog.appname":  sl.AppName,
+			"log.syslog.procid":   sl.ProcID,
+			"log.syslog.msgid":    sl.MsgID,
+		} {
+			if value != "" && value != "-" {
+				doc.Set(path, value)
+			}
+		}
+		if len(sl.StructuredData) > 0 {
+			doc.Set("log.syslog.structured_data", sl.StructuredData)
+		}
+		if !sl.Timestamp.IsZero() {
+			doc.Set("@timestamp", sl.Timestamp)
+		}
+	}
+
+	extensions := ECSDocument{}
+	for _, field := range formatFields(cef.Extensions) {
+		key := strings.ToLower(field.Key)
+		mapping, ok := override.fields[key]
+		if !ok {
+			mapping, ok = ecsFields[key]
+		}
+		if ok && mapping.Path != "" {
+			if value, err := cef.ecsValue(field.Key, field.Value, mapping.Type); err == nil {
+				doc.Set(mapping.Path, value)
+				continue
+			}
+		}
+		extensions[field.Key] = field.Value
+	}
+	if len(extensions) > 0 {
+		doc.Set("cef.extensions", extensions)
+	}
+	if _, ok := doc.Get("message"); !ok && cef.Name != "" {
+		doc.Set("message", cef.Name)
+	}
+	if len(cef.CustomFields) > 0 {
+		labels := make(map[string]string, len(cef.CustomFields))
+		for label, value := range cef.CustomFields {
+			labels[label] = value
+		}
+		doc.Set("labels", labels)
+	}
+
+	if override.transform != nil {
+		override.transform(cef, doc)
+	}
+	return doc
+}
+
+// ecsSeverity converts a numeric or textual CEF severity to a number.
+func ecsSeverity(severity string) (int, bool) {
+	if n, err := strconv.Atoi(severity); err == nil {
+		return n, true
+	}
+	n, ok := ecsSeverities[strings.ToLower(severity)]
+	return n, ok
+}
+
+// ecsValue converts the value of the extension key of the CEF event to dt for
+// an ECS document. Addresses are normalized to their string form and MAC
+// addresses to the uppercase, hyphen-separated form ECS recommends.
+func (cef *CEF) ecsValue(key, value string, dt DataType) (interface{}, error) {
+	trimmed := strings.TrimSpace(value)
+	switch dt {
+	case TypeInteger, TypeLong:
+		return strconv.ParseInt(trimmed, 10, 64)
+	case TypeFloat, TypeDouble:
+		return strconv.ParseFloat(trimmed, 64)
+	case TypeIPv4, TypeIPv6:
+		addr, err := netip.ParseAddr(trimmed)
+		if err != nil {
+			return nil, err
+		}
+		return addr.String(), nil
+	case TypeMAC:
+		mac, err := net.ParseMAC(trimmed)
+		if err != nil {
+			return nil, err
+		}
+		return strings.ToUpper(strings.ReplaceAll(mac.String(), ":", "-")), nil
+	case TypeTimestamp:
+		return cef.parseTime(key, value)
+	default:
+		return value, nil
+	}
+}
+
+// RegisterECSOverride sets the override used by ToECS for events from the
+// given vendor and product.
+func RegisterECSOverride(vendor, product string, override ECSOverride) error {
+	return defaultECSConverter.Register(vendor, product, override)
+}
+
+// UnregisterECSOverride removes an override used by ToECS.
+func UnregisterECSOverride(vendor, product string) bool {
+	return defaultECSConverter.Unregister(vendor, product)
+}
+
+// ToECS converts the CEF event to an ECS document with the package-level
+// overrides. See ECSConverter.Convert.
+func ToECS(cef *CEF) ECSDocument {
+	return defaultECSConverter.Convert(cef)
+}
+
+// ToECS converts the CEF event to an ECS document. See ECSConverter.Convert.
+func (cef *CEF) ToECS() ECSDocument {
+	return ToECS(cef)
+}
+
+// impervaECSOverride maps Imperva Cloud WAF events, where src and cpt describe
+// the client, sip and spt the protected server, and cs7 and cs8 the client's
+// latitude and longitude.
+var impervaECSOverride = ECSOverride{
+	Fields: map[string]ECSField{
+		"sip":               {"destination.ip", TypeIPv6},
+		"spt":               {"destination.port", TypeInteger},
+		"cpt":               {"source.port", TypeInteger},
+		"ccode":             {"source.geo.country_iso_code", TypeString},
+		"cicode":            {"source.geo.city_name", TypeString},
+		"cs7":               {"source.geo.location.lat", TypeDouble},
+		"cs8":               {"source.geo.location.lon", TypeDouble},
+		"cn1":               {"http.response.status_code", TypeInteger},
+		"in":                {"http.response.body.bytes", TypeLong},
+		"sourceServiceName": {"url.domain", TypeString},
+		"qstr":              {"url.query", TypeString},
+		"ref":               {"http.request.referrer", TypeString},
+		"deviceExternalId":  {"event.id", TypeString},
+		"Customer":          {"organization.name", TypeString},
+		"suid":              {"organization.id", TypeString},
+	},
+	Transform: func(cef *CEF, doc ECSDocument) {
+		if xff, ok := extensionValue(cef.Extensions, "xff"); ok {
+			first, _, _ := strings.Cut(xff, ",")
+			if addr, err := netip.ParseAddr(strings.TrimSpace(first)); err == nil {
+				doc.Set("network.forwarded_ip", addr.String())
+			}
+		}
+		// ver holds the protocol and cipher, e.g. "TLSv1.3 TLS_AES_128_GCM_SHA256".
+		if ver, ok := extensionValue(cef.Extensions, "ver"); ok {
+			protocol, cipher, _ := strings.Cut(strings.TrimSpace(ver), " ")
+			if version, ok := strings.CutPrefix(protocol, "TLSv"); ok {
+				doc.Set("tls.version_protocol", "tls")
+				doc.Set("tls.version", version)
+			}
+			if cipher = strings.TrimSpace(cipher); cipher != "" {
+				doc.Set("tls.cipher", cipher)
+			}
+		}
+	},
+}
+
+// centrifyECSOverride maps Centrify events, where duser is the acting user,
+// requestContext the user agent and the clientIPAddress custom field the
+// address of the client.
+var centrifyECSOverride = ECSOverride{
+	Fields: map[string]ECSField{
+		"duser":          {"user.name", TypeString},
+		"dpriv":          {"", TypeString},
+		"requestContext": {"user_agent.original", TypeString},
+		"level":          {"log.level", TypeString},
+	},
+	Transform: func(cef *CEF, doc ECSDocument) {
+		if role, ok := extensionValue(cef.Extensions, "dpriv"); ok && role != "" {
+			doc.Set("user.roles", []string{role})
+		}
+		labels, _ := FoldCustomFields(cef.Extensions, LabelCollisionFirst)
+		if addr, err := netip.ParseAddr(strings.TrimSpace(labels["clientIPAddress"])); err == nil {
+			doc.Set("client.ip", addr.String())
+		}
+	},
+}
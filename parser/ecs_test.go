@@ -0,0 +1,176 @@
+// Tests for the Elastic Common Schema converter.
+package parser
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestToECS tests the standard mapping of header, syslog and extension fields.
+func TestToECS(t *testing.T) {
+	line := `<134>1 2024-10-16T12:00:00Z host app - - - CEF:0|Security|threatmanager|1.0|100|worm stopped|High|src=10.0.0.1 spt=1232 dst=2001:db8::1 dpt=http suser=alice requestMethod=POST request=https://example.com/a smac=00:11:22:aa:bb:cc rt=1729080000000 cs1=blocked cs1Label=verdict act=deny`
+	cefEvent, err := NewParser(WithLabelFolding(LabelCollisionSuffix)).Parse(line)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	doc := cefEvent.ToECS()
+
+	expected := map[string]interface{}{
+		"ecs.version":              ECSVersion,
+		"event.code":               "100",
+		"event.severity":           8,
+		"event.action":             "deny",
+		"observer.vendor":          "Security",
+		"observer.product":         "threatmanager",
+		"observer.version":         "1.0",
+		"message":                  "worm stopped",
+		"source.ip":                "10.0.0.1",
+		"source.port":              int64(1232),
+		"source.mac":               "00-11-22-AA-BB-CC",
+		"destination.ip":           "2001:db8::1",
+		"user.name":                "alice",
+		"http.request.method":      "POST",
+		"url.original":             "https://example.com/a",
+		"@timestamp":               time.UnixMilli(1729080000000).UTC(),
+		"log.syslog.priority":      134,
+		"log.syslog.facility.code": 16,
+		"log.syslog.hostname":      "host",
+		"log.syslog.appname":       "app",
+		"labels":                   map[string]string{"verdict": "blocked"},
+	}
+	for path, want := range expected {
+		if got, ok := doc.Get(path); !ok || !reflect.DeepEqual(got, want) {
+			t.Errorf("%s = %#v, want %#v", path, got, want)
+		}
+	}
+	if _, ok := doc.Get("destination.port"); ok {
+		t.Errorf("expected invalid dpt to be left unmapped")
+	}
+	if got, _ := doc.Get("cef.extensions"); !reflect.DeepEqual(got, ECSDocument{"dpt": "http", "cs1": "blocked", "cs1Label": "verdict"}) {
+		t.Errorf("cef.extensions = %#v", got)
+	}
+	if _, err := json.Marshal(doc); err != nil {
+		t.Errorf("json.Marshal() error = %v", err)
+	}
+}
+
+// TestToECSVendors tests the built-in Imperva and Centrify overrides.
+func TestToECSVendors(t *testing.T) {
+	imperva, _ := ParseCEF(ImpervaCEF1)
+	doc := ToECS(imperva)
+	for path, want := range map[string]interface{}{
+		"source.ip":                   "123.123.123.123",
+		"source.port":                 int64(10401),
+		"destination.ip":              "123.123.123.123",
+		"destination.port":            int64(443),
+		"source.geo.country_iso_code": "US",
+		"source.geo.location.lat":     37.751,
+		"source.geo.location.lon":     -97.822,
+		"http.response.status_code":   int64(200),
+		"http.request.method":         "GET",
+		"url.domain":                  "example.com",
+		"event.id":                    "12345678901234567",
+		"network.forwarded_ip":        "123.123.123.123",
+		"tls.version":                 "1.3",
+		"tls.cipher":                  "TLS_AES_128_GCM_SHA256",
+		"event.start":                 time.UnixMilli(1720396716929).UTC(),
+	} {
+		if got, ok := doc.Get(path); !ok || !reflect.DeepEqual(got, want) {
+			t.Errorf("Imperva %s = %#v, want %#v", path, got, want)
+		}
+	}
+
+	centrify, _ := ParseCEF(CentrifyCEF)
+	doc = ToECS(centrify)
+	for path, want := range map[string]interface{}{
+		"user.name":           "cloudadmin@persistent.com01",
+		"user.id":             "c2c7bcc6-9560-44e0-8dff-5be221cd37ee",
+		"user.roles":          []string{"WebRole"},
+		"client.ip":           "103.6.32.100",
+		"destination.domain":  "AAA0056",
+		"observer.hostname":   "dinesh-VirtualBox",
+		"event.id":            "772a4a904e82da87.W00.0315.1aa20afe647f09c",
+		"message":             "User cloudadmin@persistent.com01 launched Instagram from 103.6.32.100",
+		"user_agent.original": "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/52.0.2743.116 Safari/537.36 Edge/15.15063",
+	} {
+		if got, ok := doc.Get(path); !ok || !reflect.DeepEqual(got, want) {
+			t.Errorf("Centrify %s = %#v, want %#v", path, got, want)
+		}
+	}
+	if _, ok := doc.Get("destination.user.name"); ok {
+		t.Errorf("expected duser to be overridden for Centrify")
+	}
+}
+
+// TestECSConverterRegister tests overrides for new vendors.
+func TestECSConverterRegister(t *testing.T) {
+	c := NewECSConverter()
+	if err := c.Register("", "P", ECSOverride{}); err == nil {
+		t.Errorf("expected error for an empty vendor")
+	}
+	err := c.Register("Acme", "Firewall", ECSOverride{
+		Fields: map[string]ECSField{
+			"SRC":    {"client.ip", TypeIPv4},
+			"act":    {"", TypeString},
+			"bytes":  {"network.bytes", TypeLong},
+			"policy": {"rule.name", TypeString},
+		},
+		Transform: func(cef *CEF, doc ECSDocument) {
+			doc.Delete("cef.name")
+			doc.Set("observer.type", "firewall")
+		},
+	})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	cefEvent, _ := ParseCEF("CEF:0|Acme|Firewall|1|2|N|3|src=10.0.0.1 act=drop bytes=42 policy=default")
+	doc := c.Convert(cefEvent)
+	for path, want := range map[string]interface{}{
+		"client.ip":          "10.0.0.1",
+		"network.bytes":      int64(42),
+		"rule.name":          "default",
+		"observer.type":      "firewall",
+		"cef.extensions.act": "drop",
+	} {
+		if got, ok := doc.Get(path); !ok || !reflect.DeepEqual(got, want) {
+			t.Errorf("%s = %#v, want %#v", path, got, want)
+		}
+	}
+	for _, path := range []string{"source.ip", "event.action", "cef.name"} {
+		if got, ok := doc.Get(path); ok {
+			t.Errorf("expected %s to be absent, got %#v", path, got)
+		}
+	}
+
+	if !c.Unregister("Acme", "Firewall") || c.Unregister("Acme", "Firewall") {
+		t.Errorf("Unregister() should succeed once")
+	}
+	if got, _ := c.Convert(cefEvent).Get("source.ip"); got != "10.0.0.1" {
+		t.Errorf("source.ip = %#v after Unregister", got)
+	}
+}
+
+// TestECSDocument tests the dotted path accessors.
+func TestECSDocument(t *testing.T) {
+	doc := ECSDocument{}
+	doc.Set("a.b.c", 1)
+	doc.Set("a.d", "x")
+	if got, ok := doc.Get("a.b.c"); !ok || got != 1 {
+		t.Errorf("Get(a.b.c) = %v, %v", got, ok)
+	}
+	if _, ok := doc.Get("a.d.e"); ok {
+		t.Errorf("expected Get through a non-object to fail")
+	}
+	doc.Set("a.d.e", 2)
+	if got, _ := doc.Get("a.d.e"); got != 2 {
+		t.Errorf("Get(a.d.e) = %v", got)
+	}
+	doc.Delete("a.b.c")
+	doc.Delete("x.y")
+	if _, ok := doc.Get("a.b.c"); ok {
+		t.Errorf("expected a.b.c to be deleted")
+	}
+}
@@ -0,0 +1,116 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// headerEscaper escapes the characters that are special in CEF header fields.
+var headerEscaper = strings.NewReplacer(`\`, `\\`, `|`, `\|`)
+
+// extensionEscaper escapes the characters that are special in CEF extension values.
+var extensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+
+// Format encodes the CEF event as a single CEF line. Header fields have `|` and
+// `\` escaped and extension values have `=`, `\` and line breaks escaped, so
+// that parsing the result yields the same event.
+//
+// Extensions are written in struct field order for the vendor types, followed
+// by their unbound fields in key order, in their original order for
+// OrderedExtensions and in key order for DefaultExtensions. Zero-valued struct
+// fields are omitted.
+func Format(cef *CEF) (string, error) {
+	if cef == nil {
+		return "", fmt.Errorf("cannot format nil CEF event")
+	}
+
+	header := [cefHeaderFields]string{
+		cef.Version, cef.DeviceVendor, cef.DeviceProduct, cef.DeviceVersion,
+		cef.SignatureID, cef.Name, cef.Severity,
+	}
+
+	var b strings.Builder
+	b.WriteString(cefPrefix)
+	for i, value := range header {
+		if strings.ContainsAny(value, "\r\n") {
+			return "", fmt.Errorf("cannot format %s: contains a line break", headerFieldNames[i])
+		}
+		b.WriteString(headerEscaper.Replace(value))
+		b.WriteByte('|')
+	}
+
+	for i, field := range formatFields(cef.Extensions) {
+		if !isValidCEFKey(field.Key, 0) {
+			return "", fmt.Errorf("cannot format extension key %q", field.Key)
+		}
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(field.Key)
+		b.WriteByte('=')
+		b.WriteString(extensionEscaper.Replace(field.Value))
+	}
+
+	return b.String(), nil
+}
+
+// MarshalCEF encodes the CEF event as a single CEF line. See Format.
+func (cef *CEF) MarshalCEF() ([]byte, error) {
+	line, err := Format(cef)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(line), nil
+}
+
+// formatFields returns the extension fields of ext in the order they are
+// written by Format.
+func formatFields(ext Extensions) []extensionField {
+	if ext == nil {
+		return nil
+	}
+
+	if oe, ok := ext.(*OrderedExtensions); ok {
+		fields := make([]extensionField, len(oe.Fields))
+		for i, field := range oe.Fields {
+			fields[i] = extensionField{Key: field.Key, Value: field.Value}
+		}
+		return fields
+	}
+
+	val := reflect.ValueOf(ext)
+	if _, ok := ext.(*DefaultExtensions); !ok && val.Kind() == reflect.Ptr && !val.IsNil() &&
+		val.Elem().Kind() == reflect.Struct && hasCEFTags(val.Elem().Type()) {
+		val = val.Elem()
+		var fields []extensionField
+		for _, binding := range structBindings(val.Type()) {
+			if value, ok := stringifyField(val.FieldByIndex(binding.index)); ok {
+				fields = append(fields, extensionField{Key: binding.names[0], Value: value})
+			}
+		}
+		if extra := unboundMap(ext); extra != nil {
+			fields = append(fields, sortedFields(*extra)...)
+		}
+		return fields
+	}
+
+	return sortedFields(extensionFields(ext))
+}
+
+// sortedFields returns the fields of m in key order.
+func sortedFields(m map[string]string) []extensionField {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fields := make([]extensionField, 0, len(keys))
+	for _, k := range keys {
+		fields = append(fields, extensionField{Key: k, Value: m[k]})
+	}
+	return fields
+}
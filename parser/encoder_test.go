@@ -0,0 +1,209 @@
+// Tests for the CEF encoder.
+package parser
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+// TestFormat tests escaping in formatted CEF lines.
+func TestFormat(t *testing.T) {
+	cefEvent := &CEF{
+		Version:       "0",
+		DeviceVendor:  `Ven|dor`,
+		DeviceProduct: `Pro\duct`,
+		DeviceVersion: "1.0",
+		SignatureID:   "100",
+		Name:          "Name",
+		Severity:      "5",
+		Extensions: &DefaultExtensions{Fields: map[string]string{
+			"msg":   "a=b\\c\nd\re",
+			"act":   `"quoted"`,
+			"dhost": "host1",
+		}},
+	}
+
+	line, err := Format(cefEvent)
+	if err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+	expected := `CEF:0|Ven\|dor|Pro\\duct|1.0|100|Name|5|act="quoted" dhost=host1 msg=a\=b\\c\nd\re`
+	if line != expected {
+		t.Errorf("Format() = %s, want %s", line, expected)
+	}
+
+	data, err := cefEvent.MarshalCEF()
+	if err != nil || string(data) != expected {
+		t.Errorf("MarshalCEF() = %s, %v", data, err)
+	}
+}
+
+// TestFormatErrors tests events that cannot be formatted.
+func TestFormatErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cef  *CEF
+	}{
+		{"Nil event", nil},
+		{"Line break in header", &CEF{Version: "0", Name: "a\nb", Extensions: &DefaultExtensions{}}},
+		{"Invalid key", &CEF{Version: "0", Extensions: &DefaultExtensions{Fields: map[string]string{"bad key": "x"}}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Format(test.cef); err == nil {
+				t.Errorf("expected error, got nil")
+			}
+		})
+	}
+}
+
+// TestFormatRoundTrip tests that the bundled vendor samples survive a round trip.
+func TestFormatRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		cef  string
+	}{
+		{"Imperva", ImpervaCEF1},
+		{"Centrify", CentrifyCEF},
+		{"Default", "CEF:0|Vendor|Product|1.0|100|Name|5|src=10.0.0.1 msg=hello world cs1=[1, 2] cs2={\"a\": \"b c=d\"}"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expected, err := ParseCEF(test.cef)
+			if err != nil {
+				t.Fatalf("ParseCEF() error = %v", err)
+			}
+			line, err := Format(expected)
+			if err != nil {
+				t.Fatalf("Format() error = %v", err)
+			}
+			cefEvent, err := ParseCEF(line)
+			if err != nil {
+				t.Fatalf("ParseCEF(%s) error = %v", line, err)
+			}
+			if !reflect.DeepEqual(cefEvent, expected) {
+				t.Errorf("round trip of %s = %v, want %v", line, cefEvent, expected)
+			}
+		})
+	}
+}
+
+// TestFormatProperty tests that ParseCEF(Format(x)) reproduces x for random events.
+func TestFormatProperty(t *testing.T) {
+	keyChars := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if r < 128 && isCEFKeyChar(byte(r)) {
+				return r
+			}
+			return -1
+		}, s)
+	}
+	noBreaks := strings.NewReplacer("\r", "", "\n", "").Replace
+
+	roundTrip := func(vendor, product, version, name string, fields map[string]string) bool {
+		ext := &DefaultExtensions{Fields: make(map[string]string, len(fields))}
+		for k, v := range fields {
+			if k = keyChars(k); k != "" {
+				ext.Fields[k] = v
+			}
+		}
+		expected := &CEF{
+			Version:       "0",
+			DeviceVendor:  noBreaks(vendor),
+			DeviceProduct: noBreaks(product),
+			DeviceVersion: noBreaks(version),
+			SignatureID:   "100",
+			Name:          noBreaks(name),
+			Severity:      "5",
+			Extensions:    ext,
+		}
+
+		line, err := Format(expected)
+		if err != nil {
+			t.Logf("Format() error = %v", err)
+			return false
+		}
+		cefEvent, err := ParseCEF(line)
+		if err != nil {
+			t.Logf("ParseCEF(%q) error = %v", line, err)
+			return false
+		}
+		if !reflect.DeepEqual(cefEvent, expected) {
+			t.Logf("round trip of %q = %#v", line, cefEvent.Extensions)
+			return false
+		}
+		return true
+	}
+
+	// The default generator almost never produces the characters that are
+	// special to the tokenizer, so strings are drawn mostly from them.
+	special := []rune("\"[]{}\\= |\n\rab1")
+	randomString := func(r *rand.Rand) string {
+		runes := make([]rune, r.Intn(12))
+		for i := range runes {
+			if r.Intn(8) == 0 {
+				runes[i] = rune(r.Intn(0x2000))
+			} else {
+				runes[i] = special[r.Intn(len(special))]
+			}
+		}
+		return string(runes)
+	}
+	values := func(args []reflect.Value, r *rand.Rand) {
+		for i := 0; i < 4; i++ {
+			args[i] = reflect.ValueOf(randomString(r))
+		}
+		fields := make(map[string]string)
+		for n := r.Intn(6); n > 0; n-- {
+			fields[fmt.Sprintf("k%d", r.Intn(10))] = randomString(r)
+		}
+		args[4] = reflect.ValueOf(fields)
+	}
+
+	if err := quick.Check(roundTrip, &quick.Config{MaxCount: 5000, Values: values}); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestFormatRoundTripValues tests values that used to be altered by a round trip.
+func TestFormatRoundTripValues(t *testing.T) {
+	for _, value := range []string{
+		`"`,
+		`""`,
+		`"quoted"`,
+		`"a b" `,
+		`[1, 2]  `,
+		`{x}  `,
+		`{]`,
+		`"}`,
+		`[{"k":"1; mode=block"}]`,
+		`{"a": "b c=d"} `,
+		` leading`,
+		`trailing  `,
+		`a\`,
+		"line\nbreak\r",
+	} {
+		expected := &CEF{
+			Version: "0", DeviceVendor: "V", DeviceProduct: "P", DeviceVersion: "1",
+			SignatureID: "100", Name: "N", Severity: "5",
+			Extensions: &DefaultExtensions{Fields: map[string]string{"a": value, "b": value, "c": "1"}},
+		}
+		line, err := Format(expected)
+		if err != nil {
+			t.Fatalf("Format() error = %v", err)
+		}
+		cefEvent, err := ParseCEF(line)
+		if err != nil {
+			t.Fatalf("ParseCEF(%q) error = %v", line, err)
+		}
+		if !reflect.DeepEqual(cefEvent, expected) {
+			t.Errorf("round trip of %q = %q", line, extensionFields(cefEvent.Extensions))
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"errors"
+	"unicode/utf8"
+)
+
+// Sentinel errors reported by the parser, usually wrapped in a *ParseError.
+var (
+	// ErrInvalidLength reports an empty record or one longer than the maximum
+	// line length.
+	ErrInvalidLength = errors.New("invalid CEF string length")
+	// ErrInvalidFormat reports a record that is not a CEF record, or is wrapped
+	// in an invalid syslog envelope.
+	ErrInvalidFormat = errors.New("invalid CEF format")
+	// ErrInvalidHeader reports a header component that fails validation.
+	ErrInvalidHeader = errors.New("one or more CEF components are invalid")
+	// ErrInvalidExtension reports an invalid extension field.
+	ErrInvalidExtension = errors.New("invalid CEF extension")
+)
+
+// Reason identifies the specific cause of a ParseError.
+type Reason int
+
+const (
+	ReasonUnknown           Reason = iota // unspecified
+	ReasonEmpty                           // the record is empty
+	ReasonTooLong                         // the record exceeds the maximum line length
+	ReasonSyslog                          // the syslog envelope is invalid
+	ReasonMissingPrefix                   // the record does not start with "CEF:" or "LEEF:"
+	ReasonMissingFields                   // the header has fewer than seven fields
+	ReasonInvalidUTF8                     // a header field is not valid UTF-8
+	ReasonControlCharacter                // a header field contains control characters
+	ReasonFieldTooLong                    // a header field exceeds its maximum length
+	ReasonInvalidVersion                  // the Version is not numeric
+	ReasonEmptyField                      // a required header field is empty
+	ReasonInvalidSeverity                 // the Severity is not 0-10 or a textual severity
+	ReasonMalformedEscape                 // an extension value has an unknown escape sequence
+	ReasonTooManyExtensions               // the extension has more fields than allowed
+	ReasonKeyTooLong                      // an extension key exceeds the maximum length
+	ReasonValueTooLong                    // an extension value exceeds the maximum length
+	ReasonLabelCollision                  // two custom fields share a label
+	ReasonDuplicateKey                    // an extension key appears more than once
+	ReasonTruncated                       // the record ends prematurely
+	ReasonInvalidDelimiter                // the LEEF 2.0 delimiter is not a character or hex code
+	ReasonInvalidAttribute                // a LEEF attribute is not a key=value pair
+)
+
+// reasonNames describes each Reason.
+var reasonNames = [...]string{
+	ReasonUnknown:           "unknown",
+	ReasonEmpty:             "empty record",
+	ReasonTooLong:           "record too long",
+	ReasonSyslog:            "invalid syslog envelope",
+	ReasonMissingPrefix:     "missing CEF prefix",
+	ReasonMissingFields:     "missing header fields",
+	ReasonInvalidUTF8:       "invalid UTF-8",
+	ReasonControlCharacter:  "control character",
+	ReasonFieldTooLong:      "field too long",
+	ReasonInvalidVersion:    "invalid version",
+	ReasonEmptyField:        "empty field",
+	ReasonInvalidSeverity:   "invalid severity",
+	ReasonMalformedEscape:   "malformed escape sequence",
+	ReasonTooManyExtensions: "too many extensions",
+	ReasonKeyTooLong:        "key too long",
+	ReasonValueTooLong:      "value too long",
+	ReasonLabelCollision:    "label collision",
+	ReasonDuplicateKey:      "duplicate key",
+	ReasonTruncated:         "truncated input",
+	ReasonInvalidDelimiter:  "invalid delimiter",
+	ReasonInvalidAttribute:  "invalid attribute",
+}
+
+// String returns a short description of the reason.
+func (r Reason) String() string {
+	if r >= 0 && int(r) < len(reasonNames) {
+		return reasonNames[r]
+	}
+	return reasonNames[ReasonUnknown]
+}
+
+// snippetLength is the maximum length of ParseError.Snippet in bytes.
+const snippetLength = 40
+
+// ParseError describes why a CEF record failed to parse.
+type ParseError struct {
+	Err    error  // one of the sentinel errors, such as ErrInvalidHeader
+	Reason Reason // specific cause
+	// Field is the index of the offending header field (0 for Version to 6 for
+	// Severity), or -1 if the error is not about a header field.
+	Field int
+	// Key is the offending extension key, if any.
+	Key string
+	// Offset is the byte offset of the problem within the parsed input.
+	Offset int
+	// Snippet is the input around Offset, truncated to a few dozen bytes.
+	Snippet string
+	// Detail describes the problem further. It is appended to the message of
+	// Err when set.
+	Detail string
+}
+
+// Error implements the error interface.
+func (e *ParseError) Error() string {
+	if e.Detail == "" {
+		return e.Err.Error()
+	}
+	return e.Err.Error() + ": " + e.Detail
+}
+
+// Unwrap returns the sentinel error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Component returns the name of the offending header field or extension key,
+// or an empty string.
+func (e *ParseError) Component() string {
+	if e.Field >= 0 && e.Field < cefHeaderFields {
+		return headerFieldNames[e.Field]
+	}
+	return e.Key
+}
+
+// newParseError returns a ParseError for the problem at offset in input.
+func newParseError(err error, reason Reason, input string, offset int) *ParseError {
+	return &ParseError{
+		Err:     err,
+		Reason:  reason,
+		Field:   -1,
+		Offset:  offset,
+		Snippet: snippet(input, offset),
+	}
+}
+
+// snippet returns up to snippetLength bytes of s starting shortly before
+// offset, trimmed to whole UTF-8 sequences.
+func snippet(s string, offset int) string {
+	start := offset - snippetLength/4
+	if start < 0 {
+		start = 0
+	}
+	if start > len(s) {
+		start = len(s)
+	}
+	end := start + snippetLength
+	if end > len(s) {
+		end = len(s)
+	}
+	for start < end && !utf8.RuneStart(s[start]) {
+		start++
+	}
+	for end < len(s) && end > start && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[start:end]
+}
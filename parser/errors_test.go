@@ -0,0 +1,85 @@
+// Tests for structured parse errors.
+package parser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestParseErrors tests the sentinel, reason, component and offset of parse errors.
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		p         *Parser
+		cef       string
+		sentinel  error
+		reason    Reason
+		component string
+		offset    int
+	}{
+		{"Empty", defaultParser, "", ErrInvalidLength, ReasonEmpty, "", 0},
+		{"Too long", NewParser(WithMaxLineLength(10)), "CEF:0|V|P|1|2|N|3|", ErrInvalidLength, ReasonTooLong, "", 10},
+		{"Missing prefix", defaultParser, "InvalidCEFString", ErrInvalidFormat, ReasonMissingPrefix, "", 0},
+		{"Invalid syslog", defaultParser, "<999>Oct 16 12:00:00 host CEF:0|V|P|1|2|N|3|", ErrInvalidFormat, ReasonSyslog, "", 0},
+		{"Missing fields", defaultParser, "CEF:0|V|P|1", ErrInvalidFormat, ReasonMissingFields, "", 11},
+		{"Invalid severity", defaultParser, "CEF:0|V|P|1|2|N|11|", ErrInvalidHeader, ReasonInvalidSeverity, "Severity", 16},
+		{"Invalid version", defaultParser, "CEF:x|V|P|1|2|N|3|", ErrInvalidHeader, ReasonInvalidVersion, "Version", 4},
+		{"Empty signature behind escape", defaultParser, `CEF:0|V\|x|P|1||N|3|`, ErrInvalidHeader, ReasonEmptyField, "SignatureID", 15},
+		{"Header in syslog", defaultParser, "<134>host CEF:0|V|P|1|2|N|11|", ErrInvalidHeader, ReasonInvalidSeverity, "Severity", 26},
+		{"Malformed escape", defaultParser, `CEF:0|V|P|1|2|N|3|src=1 msg=a\qb`, ErrInvalidExtension, ReasonMalformedEscape, "msg", 29},
+		{"Too many extensions", NewParser(WithMaxExtensions(1)), "CEF:0|V|P|1|2|N|3|src=1 dst=2", ErrInvalidExtension, ReasonTooManyExtensions, "dst", 24},
+		{"Key too long", NewParser(WithMaxKeyLength(2)), "CEF:0|V|P|1|2|N|3|src=1", ErrInvalidExtension, ReasonKeyTooLong, "src", 18},
+		{"Value too long", NewParser(WithMaxValueLength(2)), "CEF:0|V|P|1|2|N|3|src=123", ErrInvalidExtension, ReasonValueTooLong, "src", 22},
+		{"Label collision", NewParser(WithLabelFolding(LabelCollisionError)), "CEF:0|V|P|1|2|N|3|cs1=a cs1Label=x cs2=b cs2Label=x", ErrInvalidExtension, ReasonLabelCollision, "", 18},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.p.Parse(test.cef)
+			if !errors.Is(err, test.sentinel) {
+				t.Fatalf("expected %v, got %v", test.sentinel, err)
+			}
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("expected *ParseError, got %T", err)
+			}
+			if perr.Reason != test.reason || perr.Component() != test.component || perr.Offset != test.offset {
+				t.Errorf("got reason %v, component %q, offset %d; want %v, %q, %d",
+					perr.Reason, perr.Component(), perr.Offset, test.reason, test.component, test.offset)
+			}
+			if test.cef != "" && !strings.HasPrefix(test.cef[perr.Offset-min(perr.Offset, snippetLength/4):], perr.Snippet) {
+				t.Errorf("snippet %q does not match the input at offset %d", perr.Snippet, perr.Offset)
+			}
+
+			var cefEvent CEF
+			if test.p.foldLabels {
+				return
+			}
+			if err := test.p.ParseBytes([]byte(test.cef), &cefEvent); !errors.Is(err, test.sentinel) {
+				t.Errorf("ParseBytes() error = %v, want %v", err, test.sentinel)
+			}
+		})
+	}
+}
+
+// TestParseErrorMessage tests the messages of ParseError.
+func TestParseErrorMessage(t *testing.T) {
+	_, err := ParseCEF(`CEF:0|V|P|1|2|N|3|msg=a\qb`)
+	if err == nil || err.Error() != `invalid CEF extension: malformed escape sequence "\\q"` {
+		t.Errorf("unexpected error message: %v", err)
+	}
+
+	err = (&CEF{Version: "0", SignatureID: "1", Severity: "High!"}).Validate(ValidationDefault)
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Component() != "Severity" || !strings.Contains(err.Error(), `invalid Severity "High!"`) {
+		t.Errorf("unexpected Validate() error: %v", err)
+	}
+
+	if ReasonInvalidSeverity.String() != "invalid severity" || Reason(-1).String() != "unknown" {
+		t.Errorf("unexpected Reason strings")
+	}
+	if snippet(strings.Repeat("é", 40), 21) == "" {
+		t.Errorf("expected a non-empty snippet")
+	}
+}
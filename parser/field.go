@@ -0,0 +1,144 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	timeType         = reflect.TypeOf(time.Time{})
+	hardwareAddrType = reflect.TypeOf(net.HardwareAddr{})
+)
+
+// Field returns the extension field name of ext as a T. The name is a CEF
+// extension key or, for struct-based types such as ImpervaExtensions, the Go
+// name of a struct field.
+//
+// Struct fields whose value is already a T, such as ImpervaExtensions.XFF as a
+// []string or the decoded JSON of ImpervaExtensions.CS10 as a []interface{},
+// are returned as they are. Other values are converted from their string form:
+//   - time.Time uses ParseTimestamp, in the zone given by the dtz field
+//   - net.HardwareAddr uses net.ParseMAC
+//   - types implementing encoding.TextUnmarshaler, such as netip.Addr and
+//     net.IP, use UnmarshalText
+//   - maps, structs and slices are decoded as JSON when the value is a JSON
+//     object or array; other slices are split on commas
+//   - strings, booleans, integers, floats and pointers to them are converted
+//     as by BindFields
+//
+// A missing field is reported as an error, and a failed conversion as a
+// *ConversionError.
+func Field[T any](ext Extensions, name string) (T, error) {
+	var zero T
+	target := reflect.TypeOf((*T)(nil)).Elem()
+
+	value, ok, direct := structFieldValue(ext, name, target)
+	if direct.IsValid() {
+		return direct.Interface().(T), nil
+	}
+	if !ok {
+		if value, ok = extensionValue(ext, name); !ok {
+			return zero, fmt.Errorf("field %s not found", name)
+		}
+	}
+
+	out := reflect.New(target).Elem()
+	if err := convertFieldValue(ext, out, value); err != nil {
+		return zero, &ConversionError{Key: name, Value: value, Err: err, GoType: target}
+	}
+	return out.Interface().(T), nil
+}
+
+// MustField is like Field but panics if the field is missing or cannot be
+// converted.
+func MustField[T any](ext Extensions, name string) T {
+	value, err := Field[T](ext, name)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+// structFieldValue looks name up among the fields of a struct-based extension
+// type, by Go name first and then by CEF key. It returns the field directly
+// when its value is assignable to target, and its string form otherwise. A
+// zero field is reported as absent.
+func structFieldValue(ext Extensions, name string, target reflect.Type) (string, bool, reflect.Value) {
+	val := reflect.ValueOf(ext)
+	if !val.IsValid() || val.Kind() != reflect.Ptr || val.IsNil() || val.Elem().Kind() != reflect.Struct ||
+		!hasCEFTags(val.Elem().Type()) {
+		return "", false, reflect.Value{}
+	}
+	val = val.Elem()
+
+	var field reflect.Value
+	if sf, ok := val.Type().FieldByName(name); ok && sf.IsExported() {
+		field = val.FieldByIndex(sf.Index)
+	} else if binding, ok := findBinding(val.Type(), name); ok {
+		field = val.FieldByIndex(binding.index)
+	} else {
+		return "", false, reflect.Value{}
+	}
+	if field.IsZero() {
+		return "", false, reflect.Value{}
+	}
+
+	for v := field; ; v = v.Elem() {
+		if v.Type().AssignableTo(target) {
+			return "", true, v
+		}
+		if v.Kind() != reflect.Interface {
+			break
+		}
+	}
+	value, _ := stringifyField(field)
+	return value, true, reflect.Value{}
+}
+
+// convertFieldValue converts the string value of a field of ext into out.
+func convertFieldValue(ext Extensions, out reflect.Value, value string) error {
+	trimmed := strings.TrimSpace(value)
+
+	switch out.Type() {
+	case timeType:
+		var loc *time.Location
+		if dtz, ok := extensionValue(ext, "dtz"); ok {
+			loc, _ = resolveZone(strings.TrimSpace(dtz))
+		}
+		ts, err := ParseTimestamp(trimmed, loc)
+		if err != nil {
+			return err
+		}
+		out.Set(reflect.ValueOf(ts))
+		return nil
+	case hardwareAddrType:
+		mac, err := net.ParseMAC(trimmed)
+		if err != nil {
+			return err
+		}
+		out.Set(reflect.ValueOf(mac))
+		return nil
+	}
+
+	if u, ok := out.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(trimmed))
+	}
+
+	switch out.Kind() {
+	case reflect.String, reflect.Interface:
+		return setFieldValue(out, value, fieldBinding{})
+	case reflect.Map, reflect.Struct:
+		return json.Unmarshal([]byte(trimmed), out.Addr().Interface())
+	case reflect.Slice, reflect.Array:
+		if out.Type().Elem().Kind() != reflect.Uint8 && strings.HasPrefix(trimmed, "[") {
+			return json.Unmarshal([]byte(trimmed), out.Addr().Interface())
+		}
+	}
+	return setFieldValue(out, trimmed, fieldBinding{list: true})
+}
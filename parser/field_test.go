@@ -0,0 +1,136 @@
+// Tests for the generic field accessors.
+package parser
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFieldDefaultExtensions tests conversions from map-based extensions.
+func TestFieldDefaultExtensions(t *testing.T) {
+	ext := &DefaultExtensions{Fields: map[string]string{
+		"spt":   " 443",
+		"cn1":   "-7",
+		"cfp1":  "1.5",
+		"flag":  "true",
+		"src":   "10.0.0.1",
+		"smac":  "00:11:22:33:44:55",
+		"rt":    "Oct 16 2024 12:00:00",
+		"dtz":   "Europe/Paris",
+		"hosts": "a, b,c",
+		"ports": "[80, 443]",
+		"obj":   `{"k":"v"}`,
+		"msg":   " padded ",
+	}}
+
+	if v, err := Field[int](ext, "spt"); err != nil || v != 443 {
+		t.Errorf("Field[int](spt) = %v, %v", v, err)
+	}
+	if v, err := Field[int64](ext, "cn1"); err != nil || v != -7 {
+		t.Errorf("Field[int64](cn1) = %v, %v", v, err)
+	}
+	if v, err := Field[uint16](ext, "spt"); err != nil || v != 443 {
+		t.Errorf("Field[uint16](spt) = %v, %v", v, err)
+	}
+	if v, err := Field[float64](ext, "cfp1"); err != nil || v != 1.5 {
+		t.Errorf("Field[float64](cfp1) = %v, %v", v, err)
+	}
+	if v, err := Field[bool](ext, "flag"); err != nil || !v {
+		t.Errorf("Field[bool](flag) = %v, %v", v, err)
+	}
+	if v, err := Field[netip.Addr](ext, "src"); err != nil || v != netip.MustParseAddr("10.0.0.1") {
+		t.Errorf("Field[netip.Addr](src) = %v, %v", v, err)
+	}
+	if v, err := Field[net.IP](ext, "src"); err != nil || !v.Equal(net.ParseIP("10.0.0.1")) {
+		t.Errorf("Field[net.IP](src) = %v, %v", v, err)
+	}
+	if v, err := Field[net.HardwareAddr](ext, "smac"); err != nil || v.String() != "00:11:22:33:44:55" {
+		t.Errorf("Field[net.HardwareAddr](smac) = %v, %v", v, err)
+	}
+	paris, _ := time.LoadLocation("Europe/Paris")
+	if v, err := Field[time.Time](ext, "rt"); err != nil || !v.Equal(time.Date(2024, 10, 16, 12, 0, 0, 0, paris)) {
+		t.Errorf("Field[time.Time](rt) = %v, %v", v, err)
+	}
+	if v, err := Field[[]string](ext, "hosts"); err != nil || !reflect.DeepEqual(v, []string{"a", "b", "c"}) {
+		t.Errorf("Field[[]string](hosts) = %v, %v", v, err)
+	}
+	if v, err := Field[[]int](ext, "ports"); err != nil || !reflect.DeepEqual(v, []int{80, 443}) {
+		t.Errorf("Field[[]int](ports) = %v, %v", v, err)
+	}
+	if v, err := Field[map[string]string](ext, "obj"); err != nil || v["k"] != "v" {
+		t.Errorf("Field[map[string]string](obj) = %v, %v", v, err)
+	}
+	if v, err := Field[string](ext, "msg"); err != nil || v != " padded " {
+		t.Errorf("Field[string](msg) = %q, %v", v, err)
+	}
+	if v, err := Field[*int](ext, "spt"); err != nil || v == nil || *v != 443 {
+		t.Errorf("Field[*int](spt) = %v, %v", v, err)
+	}
+}
+
+// TestFieldStructExtensions tests fields of struct-based extension types.
+func TestFieldStructExtensions(t *testing.T) {
+	cefEvent, err := ParseCEF(ImpervaCEFCombined)
+	if err != nil {
+		t.Fatalf("ParseCEF() error = %v", err)
+	}
+	ext := cefEvent.Extensions
+
+	if v, err := Field[[]string](ext, "XFF"); err != nil || !reflect.DeepEqual(v, []string{"44.44.44.44"}) {
+		t.Errorf("Field[[]string](XFF) = %v, %v", v, err)
+	}
+	if v, err := Field[netip.Addr](ext, "xff"); err != nil || v != netip.MustParseAddr("44.44.44.44") {
+		t.Errorf("Field[netip.Addr](xff) = %v, %v", v, err)
+	}
+	if v, err := Field[[]interface{}](ext, "CS11"); err != nil || len(v) != 1 {
+		t.Errorf("Field[[]interface{}](CS11) = %v, %v", v, err)
+	}
+	if v, err := Field[string](ext, "cs11"); err != nil || !strings.HasPrefix(v, `[{"api_specification_violation_type"`) {
+		t.Errorf("Field[string](cs11) = %v, %v", v, err)
+	}
+	if v, err := Field[int](ext, "CN1"); err != nil || v != 200 {
+		t.Errorf("Field[int](CN1) = %v, %v", v, err)
+	}
+	if v, err := Field[time.Time](ext, "end"); err != nil || v.UnixMilli() != 1566300670892 {
+		t.Errorf("Field[time.Time](end) = %v, %v", v, err)
+	}
+	if v, err := Field[interface{}](ext, "SIP"); err == nil {
+		t.Errorf("expected error for an empty struct field, got %v", v)
+	}
+	if v := MustField[string](ext, "requestMethod"); v != "GET" {
+		t.Errorf("MustField[string](requestMethod) = %q", v)
+	}
+}
+
+// TestFieldErrors tests missing fields and failed conversions.
+func TestFieldErrors(t *testing.T) {
+	ext := &DefaultExtensions{Fields: map[string]string{"spt": "http", "src": "not-an-ip"}}
+
+	if _, err := Field[string](ext, "missing"); err == nil || err.Error() != "field missing not found" {
+		t.Errorf("expected not found error, got %v", err)
+	}
+
+	_, err := Field[int](ext, "spt")
+	var convErr *ConversionError
+	if !errors.As(err, &convErr) || convErr.GoType != reflect.TypeOf(0) {
+		t.Fatalf("expected *ConversionError, got %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), `cannot convert spt="http" to int: `) {
+		t.Errorf("unexpected message: %v", err)
+	}
+	if _, err := Field[netip.Addr](ext, "src"); !errors.As(err, &convErr) {
+		t.Errorf("expected *ConversionError, got %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected MustField to panic")
+		}
+	}()
+	MustField[bool](ext, "spt")
+}
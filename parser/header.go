@@ -0,0 +1,101 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"strings"
+)
+
+// cefPrefix is the prefix that starts every CEF record.
+const cefPrefix = "CEF:"
+
+// cefHeaderFields is the number of pipe-delimited fields in a CEF header.
+const cefHeaderFields = 7
+
+// scanHeader splits a CEF record into its seven header fields and the raw
+// extension string. Header fields are separated by unescaped pipes; the escape
+// sequences `\|` and `\\` are unescaped in the returned fields and any other
+// backslash is kept verbatim. It returns false if the record does not start
+// with "CEF:" or has fewer than seven header fields.
+func scanHeader(cef string) ([cefHeaderFields]string, string, bool) {
+	if !strings.HasPrefix(cef, cefPrefix) {
+		return [cefHeaderFields]string{}, "", false
+	}
+	header, n, rest := splitHeader(cef)
+	if n < cefHeaderFields {
+		return header, "", false
+	}
+	return header, rest, true
+}
+
+// splitHeader splits the header fields of a record that starts with the CEF
+// prefix. It returns the number of complete, pipe-terminated fields and the
+// text that follows them: the extension string when all seven fields are
+// present, otherwise the unterminated remainder of the header.
+func splitHeader(cef string) ([cefHeaderFields]string, int, string) {
+	var header [cefHeaderFields]string
+
+	start := len(cefPrefix)
+	field := 0
+	escaped := false
+	for i := start; i < len(cef); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case cef[i] == '\\':
+			escaped = true
+		case cef[i] == '|':
+			header[field] = unescapeHeaderValue(cef[start:i])
+			field++
+			start = i + 1
+			if field == cefHeaderFields {
+				return header, field, cef[start:]
+			}
+		}
+	}
+
+	return header, field, cef[start:]
+}
+
+// headerFieldOffset returns the byte offset of header field i in a CEF record,
+// or 0 if the record has fewer fields.
+func headerFieldOffset(cef string, i int) int {
+	if !strings.HasPrefix(cef, cefPrefix) || i < 0 {
+		return 0
+	}
+	if i == 0 {
+		return len(cefPrefix)
+	}
+	field := 0
+	escaped := false
+	for j := len(cefPrefix); j < len(cef); j++ {
+		switch {
+		case escaped:
+			escaped = false
+		case cef[j] == '\\':
+			escaped = true
+		case cef[j] == '|':
+			field++
+			if field == i {
+				return j + 1
+			}
+		}
+	}
+	return 0
+}
+
+// unescapeHeaderValue resolves the `\|` and `\\` escape sequences in a header field.
+func unescapeHeaderValue(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && (s[i+1] == '|' || s[i+1] == '\\') {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
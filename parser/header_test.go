@@ -0,0 +1,88 @@
+// Tests for the CEF header scanner.
+package parser
+
+import (
+	"testing"
+)
+
+// TestScanHeader tests splitting and unescaping of CEF header fields.
+func TestScanHeader(t *testing.T) {
+	tests := []struct {
+		name      string
+		cef       string
+		header    [cefHeaderFields]string
+		extension string
+		ok        bool
+	}{
+		{
+			name:      "Plain header",
+			cef:       "CEF:0|Vendor|Product|1.0|100|Name|5|src=1.1.1.1",
+			header:    [cefHeaderFields]string{"0", "Vendor", "Product", "1.0", "100", "Name", "5"},
+			extension: "src=1.1.1.1",
+			ok:        true,
+		},
+		{
+			name:      "Escaped pipe in vendor",
+			cef:       `CEF:0|Security\|Audit|Product|1.0|100|Name|5|src=1.1.1.1`,
+			header:    [cefHeaderFields]string{"0", "Security|Audit", "Product", "1.0", "100", "Name", "5"},
+			extension: "src=1.1.1.1",
+			ok:        true,
+		},
+		{
+			name:      "Escaped backslash before separator",
+			cef:       `CEF:0|Vendor|Product\\|1.0|100|Name|5|`,
+			header:    [cefHeaderFields]string{"0", "Vendor", `Product\`, "1.0", "100", "Name", "5"},
+			extension: "",
+			ok:        true,
+		},
+		{
+			name:      "Pipes in extension",
+			cef:       "CEF:0|Vendor|Product|1.0|100|Name|5|msg=a|b",
+			header:    [cefHeaderFields]string{"0", "Vendor", "Product", "1.0", "100", "Name", "5"},
+			extension: "msg=a|b",
+			ok:        true,
+		},
+		{
+			name: "Too few fields",
+			cef:  `CEF:0|Vendor|Product\|1.0|100|Name|5`,
+			ok:   false,
+		},
+		{
+			name: "Missing prefix",
+			cef:  "LEEF:1.0|Vendor|Product|1.0|100|",
+			ok:   false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			header, extension, ok := scanHeader(test.cef)
+			if ok != test.ok {
+				t.Fatalf("scanHeader(%q) ok = %v, want %v", test.cef, ok, test.ok)
+			}
+			if !ok {
+				return
+			}
+			if header != test.header {
+				t.Errorf("scanHeader(%q) header = %q, want %q", test.cef, header, test.header)
+			}
+			if extension != test.extension {
+				t.Errorf("scanHeader(%q) extension = %q, want %q", test.cef, extension, test.extension)
+			}
+		})
+	}
+}
+
+// TestParseCEFEscapedHeader tests that escaped header values populate the CEF struct.
+func TestParseCEFEscapedHeader(t *testing.T) {
+	cefEvent, err := ParseCEF(`CEF:0|Security\|Audit|Product|1.0|100|Name|5|src=1.1.1.1`)
+	if err != nil {
+		t.Fatalf("ParseCEF() error = %v", err)
+	}
+	if cefEvent.DeviceVendor != "Security|Audit" {
+		t.Errorf("expected DeviceVendor to be 'Security|Audit', got '%s'", cefEvent.DeviceVendor)
+	}
+	if cefEvent.DeviceProduct != "Product" || cefEvent.Severity != "5" {
+		t.Errorf("header columns shifted: %+v", cefEvent)
+	}
+}
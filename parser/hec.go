@@ -0,0 +1,392 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HECEvent is a Splunk HTTP Event Collector envelope.
+type HECEvent struct {
+	// Time is the event time in seconds since the Unix epoch, with
+	// millisecond precision.
+	Time       float64                `json:"time,omitempty"`
+	Host       string                 `json:"host,omitempty"`
+	Source     string                 `json:"source,omitempty"`
+	Sourcetype string                 `json:"sourcetype,omitempty"`
+	Index      string                 `json:"index,omitempty"`
+	Event      map[string]interface{} `json:"event"`
+	// Fields holds the index-time fields of the event.
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// HECOption configures an HECConverter.
+type HECOption func(*HECConverter)
+
+// HECConverter converts CEF events to HEC envelopes.
+type HECConverter struct {
+	host       string
+	source     string
+	sourcetype string
+	index      string
+	fields     map[string]string
+}
+
+// defaultHECConverter is the HECConverter used by ToHEC.
+var defaultHECConverter = NewHECConverter()
+
+// NewHECConverter returns an HECConverter with the given options. By default
+// the sourcetype is "cef" and the source is "<vendor>:<product>".
+func NewHECConverter(opts ...HECOption) *HECConverter {
+	c := &HECConverter{sourcetype: "cef"}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithHECHost sets the host of events that name no device or syslog host.
+func WithHECHost(host string) HECOption {
+	return func(c *HECConverter) {
+		c.host = host
+	}
+}
+
+// WithHECSource sets the source of every event.
+func WithHECSource(source string) HECOption {
+	return func(c *HECConverter) {
+		c.source = source
+	}
+}
+
+// WithHECSourcetype sets the sourcetype of every event.
+func WithHECSourcetype(sourcetype string) HECOption {
+	return func(c *HECConverter) {
+		c.sourcetype = sourcetype
+	}
+}
+
+// WithHECIndex sets the index of every event. By default the index is left to
+// the HEC token.
+func WithHECIndex(index string) HECOption {
+	return func(c *HECConverter) {
+		c.index = index
+	}
+}
+
+// WithHECFields adds index-time fields to every event.
+func WithHECFields(fields map[string]string) HECOption {
+	return func(c *HECConverter) {
+		if c.fields == nil {
+			c.fields = make(map[string]string, len(fields))
+		}
+		for name, value := range fields {
+			c.fields[name] = value
+		}
+	}
+}
+
+// Convert returns the HEC envelope for the CEF event. The event holds the CIM
+// fields from ToCIM and the raw extension fields under "cef"; the datamodel
+// index-time field names the CIM data model, if any. The time is taken from rt, then
+// the syslog envelope, and is left to the collector otherwise. The host is
+// taken from dvchost, then the syslog envelope, then dvc.
+func (c *HECConverter) Convert(cef *CEF) *HECEvent {
+	model, event := ToCIM(cef)
+	fields := extensionFields(cef.Extensions)
+	raw := make(map[string]string, len(fields))
+	for key, value := range fields {
+		raw[key] = value
+	}
+	event["cef"] = raw
+
+	out := &HECEvent{
+		Host:       c.host,
+		Source:     c.source,
+		Sourcetype: c.sourcetype,
+		Index:      c.index,
+		Event:      event,
+		Fields:     make(map[string]string, len(c.fields)+1),
+	}
+	if model != "" {
+		out.Fields["datamodel"] = string(model)
+	}
+	for name, value := range c.fields {
+		out.Fields[name] = value
+	}
+	if out.Source == "" {
+		out.Source = cef.DeviceVendor + ":" + cef.DeviceProduct
+	}
+
+	if ts, err := cef.ReceiptTime(); err == nil {
+		out.Time = float64(ts.UnixMilli()) / 1000
+	} else if cef.Syslog != nil && !cef.Syslog.Timestamp.IsZero() {
+		out.Time = float64(cef.Syslog.Timestamp.UnixMilli()) / 1000
+	}
+	if host, ok := lookupKey(fields, "dvchost"); ok && host != "" {
+		out.Host = host
+	} else if cef.Syslog != nil && cef.Syslog.Hostname != "" && cef.Syslog.Hostname != "-" {
+		out.Host = cef.Syslog.Hostname
+	} else if addr, ok := lookupKey(fields, "dvc"); ok && addr != "" {
+		out.Host = addr
+	}
+	return out
+}
+
+// ToHEC converts the CEF event to an HEC envelope with the default settings.
+// See HECConverter.Convert.
+func ToHEC(cef *CEF) *HECEvent {
+	return defaultHECConverter.Convert(cef)
+}
+
+// HECError reports a request rejected by the HTTP Event Collector.
+type HECError struct {
+	StatusCode int    // HTTP status code
+	Code       int    // HEC status code from the response body
+	Text       string // HEC status text from the response body
+}
+
+// Error implements the error interface.
+func (e *HECError) Error() string {
+	if e.Text == "" {
+		return fmt.Sprintf("HEC request failed with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("HEC request failed with status %d: %s (code %d)", e.StatusCode, e.Text, e.Code)
+}
+
+// ErrWriterClosed is returned when writing to a closed HECWriter.
+var ErrWriterClosed = errors.New("writer is closed")
+
+// HECWriterOption configures an HECWriter.
+type HECWriterOption func(*HECWriter)
+
+// HECWriter sends HEC envelopes to an HTTP Event Collector endpoint in batches.
+// An HECWriter is safe for concurrent use.
+type HECWriter struct {
+	url       string
+	token     string
+	client    *http.Client
+	converter *HECConverter
+	batchSize int
+	maxBytes  int
+	interval  time.Duration
+
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	count  int
+	err    error
+	closed bool
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewHECWriter returns an HECWriter that posts batches to url, such as
+// "https://splunk:8088/services/collector/event", authenticating with token.
+// By default batches hold up to 100 events or 1 MiB and are sent when full or
+// on Flush and Close.
+func NewHECWriter(url, token string, opts ...HECWriterOption) *HECWriter {
+	w := &HECWriter{
+		url:       url,
+		token:     token,
+		client:    http.DefaultClient,
+		converter: defaultHECConverter,
+		batchSize: 100,
+		maxBytes:  1 << 20,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if w.interval > 0 {
+		w.stop = make(chan struct{})
+		w.done = make(chan struct{})
+		go w.flushPeriodically()
+	}
+	return w
+}
+
+// WithHECClient sets the HTTP client used to send batches.
+func WithHECClient(client *http.Client) HECWriterOption {
+	return func(w *HECWriter) {
+		if client != nil {
+			w.client = client
+		}
+	}
+}
+
+// WithHECConverter sets the converter used by HECWriter.Write.
+func WithHECConverter(c *HECConverter) HECWriterOption {
+	return func(w *HECWriter) {
+		if c != nil {
+			w.converter = c
+		}
+	}
+}
+
+// WithHECBatchSize sets the maximum number of events in a batch.
+func WithHECBatchSize(n int) HECWriterOption {
+	return func(w *HECWriter) {
+		if n > 0 {
+			w.batchSize = n
+		}
+	}
+}
+
+// WithHECMaxBytes sets the maximum size of a batch in bytes. An event larger
+// than n is sent on its own.
+func WithHECMaxBytes(n int) HECWriterOption {
+	return func(w *HECWriter) {
+		if n > 0 {
+			w.maxBytes = n
+		}
+	}
+}
+
+// WithHECFlushInterval makes the writer send pending events every d. Errors
+// from these sends are returned by the next call to Write, Flush or Close.
+func WithHECFlushInterval(d time.Duration) HECWriterOption {
+	return func(w *HECWriter) {
+		w.interval = d
+	}
+}
+
+// Write converts the CEF event with the writer's converter and queues it.
+func (w *HECWriter) Write(cef *CEF) error {
+	return w.WriteEvent(w.converter.Convert(cef))
+}
+
+// WriteEvent queues an HEC envelope, sending the pending batch first if the
+// envelope would not fit in it, and sending the batch once it is full.
+func (w *HECWriter) WriteEvent(event *HECEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrWriterClosed
+	}
+	if err := w.takeErr(); err != nil {
+		return err
+	}
+
+	if w.count > 0 && w.buf.Len()+len(data) > w.maxBytes {
+		if err := w.send(context.Background()); err != nil {
+			return err
+		}
+	}
+	w.buf.Write(data)
+	w.count++
+	if w.count >= w.batchSize || w.buf.Len() >= w.maxBytes {
+		return w.send(context.Background())
+	}
+	return nil
+}
+
+// Flush sends the pending events.
+func (w *HECWriter) Flush(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.takeErr(); err != nil {
+		return err
+	}
+	return w.send(ctx)
+}
+
+// Close sends the pending events and stops the writer. Further writes return
+// ErrWriterClosed.
+func (w *HECWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+
+	if w.stop != nil {
+		close(w.stop)
+		<-w.done
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.takeErr(); err != nil {
+		return err
+	}
+	return w.send(context.Background())
+}
+
+// flushPeriodically sends the pending events every interval until the writer
+// is closed.
+func (w *HECWriter) flushPeriodically() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			if err := w.send(context.Background()); err != nil && w.err == nil {
+				w.err = err
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+// takeErr returns and clears the error of a periodic send. The caller must
+// hold w.mu.
+func (w *HECWriter) takeErr() error {
+	err := w.err
+	w.err = nil
+	return err
+}
+
+// send posts the pending events as one request and clears them, whether or not
+// the request succeeds. The caller must hold w.mu.
+func (w *HECWriter) send(ctx context.Context) error {
+	if w.count == 0 {
+		return nil
+	}
+	body := bytes.NewReader(append([]byte(nil), w.buf.Bytes()...))
+	w.buf.Reset()
+	w.count = 0
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Splunk "+w.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	hecErr := &HECError{StatusCode: resp.StatusCode}
+	var status struct {
+		Text string `json:"text"`
+		Code int    `json:"code"`
+	}
+	if json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&status) == nil {
+		hecErr.Text, hecErr.Code = status.Text, status.Code
+	}
+	return hecErr
+}
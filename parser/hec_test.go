@@ -0,0 +1,182 @@
+// Tests for the Splunk HTTP Event Collector envelopes and writer.
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// hecStandIn is a local stand-in for an HTTP Event Collector endpoint.
+type hecStandIn struct {
+	*httptest.Server
+	mu      sync.Mutex
+	batches [][]HECEvent
+	fail    bool
+}
+
+// newHECStandIn starts a stand-in that accepts the token "secret".
+func newHECStandIn(t *testing.T) *hecStandIn {
+	s := &hecStandIn{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Splunk secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = io.WriteString(w, `{"text":"Invalid token","code":4}`)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var batch []HECEvent
+		dec := json.NewDecoder(r.Body)
+		for dec.More() {
+			var event HECEvent
+			if err := dec.Decode(&event); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = io.WriteString(w, `{"text":"Invalid data format","code":6}`)
+				return
+			}
+			batch = append(batch, event)
+		}
+		s.batches = append(s.batches, batch)
+		_, _ = io.WriteString(w, `{"text":"Success","code":0}`)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// sizes returns the number of events in each batch received.
+func (s *hecStandIn) sizes() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sizes := make([]int, len(s.batches))
+	for i, batch := range s.batches {
+		sizes[i] = len(batch)
+	}
+	return sizes
+}
+
+// TestToHEC tests the envelope built for an event.
+func TestToHEC(t *testing.T) {
+	cefEvent, _ := ParseCEF(CentrifyCEF)
+	event := ToHEC(cefEvent)
+	if event.Time != 1525844566.655 || event.Host != "dinesh-VirtualBox" || event.Source != "Centrify:Centrify_Cloud" ||
+		event.Sourcetype != "cef" || event.Fields["datamodel"] != "Authentication" {
+		t.Errorf("unexpected envelope: %+v", event)
+	}
+	if event.Event["user"] != "cloudadmin@persistent.com01" {
+		t.Errorf("user = %v", event.Event["user"])
+	}
+	if raw, _ := event.Event["cef"].(map[string]string); raw["dpriv"] != "WebRole" {
+		t.Errorf("cef = %v", event.Event["cef"])
+	}
+
+	c := NewHECConverter(WithHECSource("udp:514"), WithHECSourcetype("cef:centrify"), WithHECIndex("sec"),
+		WithHECHost("fallback"), WithHECFields(map[string]string{"env": "prod"}))
+	syslog, _ := NewParser(WithSyslog(true)).Parse("<134>1 2024-10-16T12:00:00Z host app - - - CEF:0|V|P|1|2|N|3|src=10.0.0.1")
+	event = c.Convert(syslog)
+	if event.Time != 1729080000 || event.Host != "host" || event.Source != "udp:514" || event.Sourcetype != "cef:centrify" ||
+		event.Index != "sec" || event.Fields["env"] != "prod" {
+		t.Errorf("unexpected envelope: %+v", event)
+	}
+	if model, ok := event.Fields["datamodel"]; ok {
+		t.Errorf("datamodel = %q for an event without a data model", model)
+	}
+	plain, _ := ParseCEF("CEF:0|V|P|1|2|N|3|src=10.0.0.1")
+	if event := c.Convert(plain); event.Host != "fallback" || event.Time != 0 {
+		t.Errorf("unexpected envelope: %+v", event)
+	}
+}
+
+// TestHECWriter tests batching by count and size against a local stand-in.
+func TestHECWriter(t *testing.T) {
+	s := newHECStandIn(t)
+	w := NewHECWriter(s.URL, "secret", WithHECClient(s.Client()), WithHECBatchSize(2))
+	cefEvent, _ := ParseCEF(ImpervaCEF1)
+	for i := 0; i < 5; i++ {
+		if err := w.Write(cefEvent); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if got := s.sizes(); len(got) != 2 {
+		t.Errorf("batches before Close = %v, want [2 2]", got)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got := s.sizes(); len(got) != 3 || got[2] != 1 {
+		t.Errorf("batches = %v, want [2 2 1]", got)
+	}
+	if s.batches[0][0].Event["http_method"] != "GET" {
+		t.Errorf("unexpected event: %+v", s.batches[0][0])
+	}
+	if err := w.Write(cefEvent); !errors.Is(err, ErrWriterClosed) {
+		t.Errorf("expected ErrWriterClosed, got %v", err)
+	}
+
+	s = newHECStandIn(t)
+	w = NewHECWriter(s.URL, "secret", WithHECClient(s.Client()), WithHECMaxBytes(1))
+	for i := 0; i < 3; i++ {
+		if err := w.WriteEvent(&HECEvent{Event: map[string]interface{}{"n": i}}); err != nil {
+			t.Fatalf("WriteEvent() error = %v", err)
+		}
+	}
+	if got := s.sizes(); len(got) != 3 {
+		t.Errorf("batches = %v, want one event per batch", got)
+	}
+}
+
+// TestHECWriterErrors tests rejected requests.
+func TestHECWriterErrors(t *testing.T) {
+	s := newHECStandIn(t)
+	w := NewHECWriter(s.URL, "wrong", WithHECClient(s.Client()))
+	_ = w.WriteEvent(&HECEvent{Event: map[string]interface{}{}})
+	err := w.Flush(context.Background())
+	var hecErr *HECError
+	if !errors.As(err, &hecErr) || hecErr.StatusCode != http.StatusUnauthorized || hecErr.Code != 4 {
+		t.Fatalf("expected *HECError, got %v", err)
+	}
+	if err.Error() != "HEC request failed with status 401: Invalid token (code 4)" {
+		t.Errorf("unexpected message: %v", err)
+	}
+	if err := w.Flush(context.Background()); err != nil {
+		t.Errorf("expected the failed batch to be dropped, got %v", err)
+	}
+
+	s.mu.Lock()
+	s.fail = true
+	s.mu.Unlock()
+	w = NewHECWriter(s.URL, "secret", WithHECClient(s.Client()))
+	_ = w.WriteEvent(&HECEvent{Event: map[string]interface{}{}})
+	if err := w.Close(); err == nil || err.Error() != "HEC request failed with status 503" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// TestHECWriterInterval tests periodic flushing.
+func TestHECWriterInterval(t *testing.T) {
+	s := newHECStandIn(t)
+	w := NewHECWriter(s.URL, "secret", WithHECClient(s.Client()), WithHECFlushInterval(10*time.Millisecond))
+	defer w.Close()
+	if err := w.WriteEvent(&HECEvent{Event: map[string]interface{}{"n": 1}}); err != nil {
+		t.Fatalf("WriteEvent() error = %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(s.sizes()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a periodic flush")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
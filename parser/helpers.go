@@ -2,6 +2,8 @@
 package parser
 
 import (
+	"encoding/json"
+	"fmt"
 	"reflect"
 	"strings"
 )
@@ -13,7 +15,9 @@ func getFieldNames(obj interface{}) []string {
 	var fieldNames []string
 
 	for i := 0; i < val.NumField(); i++ {
-		fieldNames = append(fieldNames, typ.Field(i).Name)
+		if typ.Field(i).IsExported() {
+			fieldNames = append(fieldNames, typ.Field(i).Name)
+		}
 	}
 
 	return fieldNames
@@ -26,6 +30,9 @@ func structToMap(obj interface{}) map[string]string {
 	fields := make(map[string]string)
 
 	for i := 0; i < val.NumField(); i++ {
+		if !typ.Field(i).IsExported() {
+			continue
+		}
 		fieldName := typ.Field(i).Name
 		fieldValue := val.Field(i).Interface()
 		if strValue, ok := fieldValue.(string); ok {
@@ -36,10 +43,102 @@ func structToMap(obj interface{}) map[string]string {
 	return fields
 }
 
-// removeCEFEscapeChars normalizes strings that contain backlashes.
-// Particularly useful for JSON strings with unnecessary escapes.
-func removeCEFEscapeChars(s string) string {
-	// remove escape for `=`
-	s = strings.Replace(s, `\=`, "=", -1)
-	return s
+// extensionValue returns the raw string value of a CEF extension key from any
+// Extensions implementation. Struct-based types are resolved through their
+// `cef` tags; other types fall back to AsMap. Keys are matched exactly first
+// and then case-insensitively.
+func extensionValue(ext Extensions, key string) (string, bool) {
+	if ext == nil {
+		return "", false
+	}
+	if de, ok := ext.(*DefaultExtensions); ok {
+		return lookupKey(de.Fields, key)
+	}
+	if oe, ok := ext.(*OrderedExtensions); ok {
+		return oe.Get(key)
+	}
+
+	val := reflect.ValueOf(ext)
+	if val.Kind() == reflect.Ptr && !val.IsNil() && val.Elem().Kind() == reflect.Struct {
+		val = val.Elem()
+		lower := strings.ToLower(key)
+		for _, binding := range structBindings(val.Type()) {
+			for _, name := range binding.names {
+				if name == key || strings.ToLower(name) == lower {
+					return stringifyField(val.FieldByIndex(binding.index))
+				}
+			}
+		}
+		if extra := unboundMap(ext); extra != nil {
+			return lookupKey(*extra, key)
+		}
+	}
+
+	return lookupKey(ext.AsMap(), key)
+}
+
+// unboundFielder is implemented by struct-based extensions that keep the
+// extension fields none of their struct fields is bound to.
+type unboundFielder interface {
+	unboundFields() *map[string]string
+}
+
+// unboundMap returns the unbound fields of v, or nil if v does not keep them.
+func unboundMap(v interface{}) *map[string]string {
+	if u, ok := v.(unboundFielder); ok {
+		return u.unboundFields()
+	}
+	return nil
+}
+
+// collectUnbound returns the fields that the struct type t has no binding
+// for, or nil if there are none.
+func collectUnbound(t reflect.Type, fields map[string]string) map[string]string {
+	var extra map[string]string
+	for k, v := range fields {
+		if _, ok := findBinding(t, k); ok {
+			continue
+		}
+		if extra == nil {
+			extra = make(map[string]string)
+		}
+		extra[k] = v
+	}
+	return extra
+}
+
+// lookupKey looks up key in fields exactly and then case-insensitively.
+func lookupKey(fields map[string]string, key string) (string, bool) {
+	if value, ok := fields[key]; ok {
+		return value, true
+	}
+	for k, value := range fields {
+		if strings.EqualFold(k, key) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// stringifyField returns the string form of a bound struct field. Zero values,
+// including an empty string held in an interface and a list of empty strings,
+// are reported as absent.
+func stringifyField(field reflect.Value) (string, bool) {
+	if field.IsZero() {
+		return "", false
+	}
+	switch value := field.Interface().(type) {
+	case string:
+		return value, value != ""
+	case []string:
+		joined := strings.Join(value, ", ")
+		return joined, strings.Trim(joined, ", ") != ""
+	case fmt.Stringer:
+		return value.String(), true
+	}
+	data, err := json.Marshal(field.Interface())
+	if err != nil {
+		return fmt.Sprint(field.Interface()), true
+	}
+	return string(data), true
 }
@@ -4,162 +4,103 @@ package parser
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
 	"reflect"
-	"strings"
 )
 
 // ImpervaExtensions represents the specific extension fields for Imperva.
 type ImpervaExtensions struct {
-	FileID                   string
-	SourceServiceName        string
-	SiteID                   string
-	SUID                     string
-	RequestClientApplication string
-	DeviceFacility           string
-	CS2                      string
-	CS2Label                 string
-	CS3                      string
-	CS3Label                 string
-	CS1                      string
-	CS1Label                 string
-	CS4                      string
-	CS4Label                 string
-	CS5                      string
-	CS5Label                 string
-	DProc                    string
-	CS6                      string
-	CS6Label                 string
-	CCCode                   string
-	CS7                      string
-	CS7Label                 string
-	CS8                      string
-	CS8Label                 string
-	CS9                      string
-	CS9Label                 string
-	AdditionalReqHeaders     interface{}
-	AdditionalResHeaders     interface{}
-	Customer                 string
-	Start                    string
-	Request                  string
-	Ref                      string
-	RequestMethod            string
-	CN1                      string
-	App                      string
-	Act                      string
-	DeviceExternalID         string
-	SIP                      string
-	SPT                      string
-	In                       string
-	XFF                      []string
-	CS10                     interface{}
-	CS10Label                string
-	CS11                     interface{}
-	CS11Label                string
-	CPT                      string
-	Src                      string
-	Ver                      string
-	End                      string
+	FileID                   string      `cef:"fileId,alias=fileid"`
+	SourceServiceName        string      `cef:"sourceServiceName"`
+	SiteID                   string      `cef:"siteid"`
+	SUID                     string      `cef:"suid"`
+	RequestClientApplication string      `cef:"requestClientApplication"`
+	DeviceFacility           string      `cef:"deviceFacility"`
+	CS2                      string      `cef:"cs2"`
+	CS2Label                 string      `cef:"cs2Label"`
+	CS3                      string      `cef:"cs3"`
+	CS3Label                 string      `cef:"cs3Label"`
+	CS1                      string      `cef:"cs1"`
+	CS1Label                 string      `cef:"cs1Label"`
+	CS4                      string      `cef:"cs4"`
+	CS4Label                 string      `cef:"cs4Label"`
+	CS5                      string      `cef:"cs5"`
+	CS5Label                 string      `cef:"cs5Label"`
+	DProc                    string      `cef:"dproc"`
+	CS6                      string      `cef:"cs6"`
+	CS6Label                 string      `cef:"cs6Label"`
+	CCCode                   string      `cef:"ccode"`
+	CS7                      string      `cef:"cs7"`
+	CS7Label                 string      `cef:"cs7Label"`
+	CS8                      string      `cef:"cs8"`
+	CS8Label                 string      `cef:"cs8Label"`
+	CS9                      string      `cef:"cs9"`
+	CS9Label                 string      `cef:"cs9Label"`
+	AdditionalReqHeaders     interface{} `cef:"additionalReqHeaders,json"`
+	AdditionalResHeaders     interface{} `cef:"additionalResHeaders,json"`
+	Customer                 string      `cef:"Customer"`
+	Start                    string      `cef:"start"`
+	Request                  string      `cef:"request"`
+	Ref                      string      `cef:"ref"`
+	RequestMethod            string      `cef:"requestMethod,alias=requestmethod"`
+	CN1                      string      `cef:"cn1"`
+	App                      string      `cef:"app"`
+	Act                      string      `cef:"act"`
+	DeviceExternalID         string      `cef:"deviceExternalId,alias=deviceExternalID"`
+	SIP                      string      `cef:"sip"`
+	SPT                      string      `cef:"spt"`
+	In                       string      `cef:"in"`
+	XFF                      []string    `cef:"xff,list"`
+	CS10                     interface{} `cef:"cs10,json"`
+	CS10Label                string      `cef:"cs10Label"`
+	CS11                     interface{} `cef:"cs11,json"`
+	CS11Label                string      `cef:"cs11Label"`
+	CPT                      string      `cef:"cpt"`
+	Src                      string      `cef:"src"`
+	Ver                      string      `cef:"ver"`
+	End                      string      `cef:"end"`
+
+	extra map[string]string // fields without a struct field, keyed as received
 }
 
 // ParseExtensions parses the extension string into the ImpervaExtensions struct.
+// Quoted and JSON values are read whole, since Imperva sends them unescaped;
+// see WithQuotedValues.
 func (ie *ImpervaExtensions) ParseExtensions(extension string) map[string]string {
-	fields := parseExtensions(extension)
-	ie.FileID = fields["fileId"]
-	ie.SourceServiceName = fields["sourceServiceName"]
-	ie.SiteID = fields["siteid"]
-	ie.SUID = fields["suid"]
-	ie.RequestClientApplication = fields["requestClientApplication"]
-	ie.DeviceFacility = fields["deviceFacility"]
-	ie.CS2 = fields["cs2"]
-	ie.CS2Label = fields["cs2Label"]
-	ie.CS3 = fields["cs3"]
-	ie.CS3Label = fields["cs3Label"]
-	ie.CS1 = fields["cs1"]
-	ie.CS1Label = fields["cs1Label"]
-	ie.CS4 = fields["cs4"]
-	ie.CS4Label = fields["cs4Label"]
-	ie.CS5 = fields["cs5"]
-	ie.CS5Label = fields["cs5Label"]
-	ie.DProc = fields["dproc"]
-	ie.CS6 = fields["cs6"]
-	ie.CS6Label = fields["cs6Label"]
-	ie.CCCode = fields["ccode"]
-	ie.CS7 = fields["cs7"]
-	ie.CS7Label = fields["cs7Label"]
-	ie.CS8 = fields["cs8"]
-	ie.CS8Label = fields["cs8Label"]
-	ie.CS9 = fields["cs9"]
-	ie.CS9Label = fields["cs9Label"]
-	ie.Customer = fields["Customer"]
-	ie.Start = fields["start"]
-	ie.Request = fields["request"]
-	ie.Ref = fields["ref"]
-	ie.RequestMethod = fields["requestMethod"]
-	ie.CN1 = fields["cn1"]
-	ie.App = fields["app"]
-	ie.Act = fields["act"]
-	ie.DeviceExternalID = fields["deviceExternalId"]
-	ie.SIP = fields["sip"]
-	ie.SPT = fields["spt"]
-	ie.In = fields["in"]
-	ie.XFF = strings.Split(fields["xff"], ", ")
-	ie.CS10 = fields["cs10"]
-	ie.CS10Label = fields["cs10Label"]
-	ie.CS11 = fields["cs11"]
-	ie.CS11Label = fields["cs11Label"]
-	ie.CPT = fields["cpt"]
-	ie.Src = fields["src"]
-	ie.Ver = fields["ver"]
-	ie.End = fields["end"]
-
-	if additionalResHeaders, ok := fields["additionalResHeaders"]; ok {
-		additionalResHeaders = removeCEFEscapeChars(additionalResHeaders)
-		var additionalResHeadersJSON interface{}
-		if err := json.Unmarshal([]byte(additionalResHeaders), &additionalResHeadersJSON); err == nil {
-			ie.AdditionalResHeaders = additionalResHeadersJSON
-		} else {
-			ie.AdditionalResHeaders = additionalResHeaders
-		}
-	}
+	tokens, _ := tokenizeExtensions(extension, ie.quotedValues())
+	fields := fieldsToMap(tokens)
+	ie.loadFields(fields)
+	return fields
+}
 
-	if additionalReqHeaders, ok := fields["additionalReqHeaders"]; ok {
-		additionalReqHeaders = removeCEFEscapeChars(additionalReqHeaders)
-		var additionalReqHeadersJSON interface{}
-		if err := json.Unmarshal([]byte(additionalReqHeaders), &additionalReqHeadersJSON); err == nil {
-			ie.AdditionalReqHeaders = additionalReqHeadersJSON
-		} else {
-			ie.AdditionalReqHeaders = additionalReqHeaders
-		}
-	}
+// quotedValues reports that Imperva extension values may be quoted or JSON.
+func (ie *ImpervaExtensions) quotedValues() bool {
+	return true
+}
 
-	if cs10, ok := fields["cs10"]; ok {
-		cs10 = removeCEFEscapeChars(cs10)
-		var cs10JSON interface{}
-		if err := json.Unmarshal([]byte(cs10), &cs10JSON); err == nil {
-			ie.CS10 = cs10JSON
-		} else {
-			ie.CS10 = cs10
-		}
-	}
+// loadFields populates the ImpervaExtensions struct from the tokenized extension fields.
+func (ie *ImpervaExtensions) loadFields(fields map[string]string) {
+	*ie = ImpervaExtensions{}
+	_ = BindFields(ie, fields)
+	ie.extra = collectUnbound(reflect.TypeOf(ie).Elem(), fields)
 
-	if cs11, ok := fields["cs11"]; ok {
-		cs11 = removeCEFEscapeChars(cs11)
-		var cs11JSON interface{}
-		if err := json.Unmarshal([]byte(cs11), &cs11JSON); err == nil {
-			ie.CS11 = cs11JSON
-		} else {
-			ie.CS11 = cs11
-		}
+	// Absent fields keep the zero values ParseExtensions has always given them.
+	if ie.CS10 == nil {
+		ie.CS10 = ""
+	}
+	if ie.CS11 == nil {
+		ie.CS11 = ""
+	}
+	if ie.XFF == nil {
+		ie.XFF = []string{""}
 	}
-
-	return fields
 }
 
 // GetField dynamically retrieves a field value by name using reflection.
 func (ie *ImpervaExtensions) GetField(fieldName string) (interface{}, error) {
 	r := reflect.ValueOf(ie)
 	f := reflect.Indirect(r).FieldByName(fieldName)
-	if f.IsValid() {
+	if f.IsValid() && f.CanInterface() {
 		return f.Interface(), nil
 	}
 	return nil, fmt.Errorf("field %s not found", fieldName)
@@ -180,3 +121,39 @@ func (ie *ImpervaExtensions) AsMap() map[string]string {
 func (ie *ImpervaExtensions) GetFieldNames() []string {
 	return getFieldNames(ie)
 }
+
+// CustomFields returns the custom fields keyed by their labels. Fields sharing a
+// label are resolved with LabelCollisionSuffix.
+func (ie *ImpervaExtensions) CustomFields() map[string]string {
+	folded, _ := FoldCustomFields(ie, LabelCollisionSuffix)
+	return folded
+}
+
+// SetField converts value and stores it in the field bound to key, or as an
+// unbound field if no field is bound to it.
+func (ie *ImpervaExtensions) SetField(key, value string) error {
+	return setStructField(ie, key, value)
+}
+
+// DeleteField clears the field or unbound field of key and reports whether it
+// was set.
+func (ie *ImpervaExtensions) DeleteField(key string) bool {
+	return deleteStructField(ie, key)
+}
+
+// RenameField moves the value bound to oldKey to the field bound to newKey.
+func (ie *ImpervaExtensions) RenameField(oldKey, newKey string) error {
+	return renameStructField(ie, oldKey, newKey)
+}
+
+// Clone returns a deep copy of the extensions.
+func (ie *ImpervaExtensions) Clone() Extensions {
+	clone := deepCopy(reflect.ValueOf(ie)).Interface().(*ImpervaExtensions)
+	clone.extra = maps.Clone(ie.extra)
+	return clone
+}
+
+// unboundFields returns the extension fields that have no struct field.
+func (ie *ImpervaExtensions) unboundFields() *map[string]string {
+	return &ie.extra
+}
@@ -0,0 +1,314 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// extensionsTypes maps the names recorded in the JSON form of events to their
+// Extensions types.
+var extensionsTypes = struct {
+	sync.RWMutex
+	factories map[string]func() Extensions
+	names     map[reflect.Type]string
+}{
+	factories: map[string]func() Extensions{
+		"default":  func() Extensions { return &DefaultExtensions{} },
+		"ordered":  func() Extensions { return &OrderedExtensions{} },
+		"imperva":  func() Extensions { return &ImpervaExtensions{} },
+		"centrify": func() Extensions { return &CentrifyExtensions{} },
+	},
+	names: map[reflect.Type]string{
+		reflect.TypeOf(&DefaultExtensions{}):  "default",
+		reflect.TypeOf(&OrderedExtensions{}):  "ordered",
+		reflect.TypeOf(&ImpervaExtensions{}):  "imperva",
+		reflect.TypeOf(&CentrifyExtensions{}): "centrify",
+	},
+}
+
+// RegisterExtensionsType registers an Extensions type under name, which is
+// recorded as extensionsType in the JSON form of events so that UnmarshalJSON
+// can restore the same type. The built-in types are registered as "default",
+// "ordered", "imperva" and "centrify". Registering a name again replaces it.
+func RegisterExtensionsType(name string, factory func() Extensions) error {
+	if name == "" {
+		return fmt.Errorf("name must not be empty")
+	}
+	if factory == nil {
+		return fmt.Errorf("factory must not be nil")
+	}
+	ext := factory()
+	if ext == nil {
+		return fmt.Errorf("factory must not return nil")
+	}
+
+	extensionsTypes.Lock()
+	defer extensionsTypes.Unlock()
+	extensionsTypes.factories[name] = factory
+	extensionsTypes.names[reflect.TypeOf(ext)] = name
+	return nil
+}
+
+// extensionsTypeName returns the registered name of the type of ext, or its Go
+// type name if it is not registered.
+func extensionsTypeName(ext Extensions) string {
+	if ext == nil {
+		return ""
+	}
+	extensionsTypes.RLock()
+	defer extensionsTypes.RUnlock()
+	if name, ok := extensionsTypes.names[reflect.TypeOf(ext)]; ok {
+		return name
+	}
+	return reflect.TypeOf(ext).String()
+}
+
+// newExtensionsType returns a new value of the Extensions type registered as
+// name.
+func newExtensionsType(name string) (Extensions, bool) {
+	extensionsTypes.RLock()
+	factory, ok := extensionsTypes.factories[name]
+	extensionsTypes.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return factory(), true
+}
+
+// cefJSON is the JSON form of a CEF event. See CEF.MarshalJSON.
+type cefJSON struct {
+	Version        string            `json:"version"`
+	DeviceVendor   string            `json:"deviceVendor"`
+	DeviceProduct  string            `json:"deviceProduct"`
+	DeviceVersion  string            `json:"deviceVersion"`
+	SignatureID    string            `json:"signatureId"`
+	Name           string            `json:"name"`
+	Severity       string            `json:"severity"`
+	ExtensionsType string            `json:"extensionsType,omitempty"`
+	Extensions     json.RawMessage   `json:"extensions"`
+	CustomFields   map[string]string `json:"customFields,omitempty"`
+	Syslog         *syslogJSON       `json:"syslog,omitempty"`
+}
+
+// syslogJSON is the JSON form of a syslog envelope.
+type syslogJSON struct {
+	Priority       int                          `json:"priority"`
+	Facility       int                          `json:"facility"`
+	Severity       int                          `json:"severity"`
+	Version        int                          `json:"version"`
+	Timestamp      *time.Time                   `json:"timestamp,omitempty"`
+	Hostname       string                       `json:"hostname,omitempty"`
+	AppName        string                       `json:"appName,omitempty"`
+	ProcID         string                       `json:"procId,omitempty"`
+	MsgID          string                       `json:"msgId,omitempty"`
+	StructuredData map[string]map[string]string `json:"structuredData,omitempty"`
+}
+
+// MarshalText encodes the event as a CEF line. See Format.
+func (cef *CEF) MarshalText() ([]byte, error) {
+	return cef.MarshalCEF()
+}
+
+// UnmarshalText parses a CEF line into the event using the default parser.
+func (cef *CEF) UnmarshalText(text []byte) error {
+	parsed, err := ParseCEF(string(text))
+	if err != nil {
+		return err
+	}
+	*cef = *parsed
+	return nil
+}
+
+// MarshalJSON encodes the event as a JSON object with the same shape for every
+// vendor:
+//
+//	{
+//	  "version": "0",
+//	  "deviceVendor": "Incapsula",
+//	  "deviceProduct": "SIEMintegration",
+//	  "deviceVersion": "1",
+//	  "signatureId": "1",
+//	  "name": "Normal",
+//	  "severity": "0",
+//	  "extensionsType": "imperva",
+//	  "extensions": {"fileId": "123", "xff": "1.1.1.1, 2.2.2.2"},
+//	  "customFields": {"Rule name": "..."},
+//	  "syslog": {"priority": 134, "facility": 16, "severity": 6, "version": 1,
+//	             "timestamp": "2024-10-16T12:00:00Z", "hostname": "host",
+//	             "appName": "app", "procId": "1", "msgId": "ID",
+//	             "structuredData": {"id@1": {"k": "v"}}}
+//	}
+//
+// extensionsType names the Extensions type of the event, as registered with
+// RegisterExtensionsType. extensions maps CEF extension keys to their string
+// values, in the order Format writes them; a key that OrderedExtensions holds
+// more than once has an array of its values. customFields, syslog and the
+// empty members of syslog are omitted when absent.
+func (cef *CEF) MarshalJSON() ([]byte, error) {
+	extensions, err := marshalFields(formatFields(cef.Extensions))
+	if err != nil {
+		return nil, err
+	}
+
+	out := cefJSON{
+		Version:        cef.Version,
+		DeviceVendor:   cef.DeviceVendor,
+		DeviceProduct:  cef.DeviceProduct,
+		DeviceVersion:  cef.DeviceVersion,
+		SignatureID:    cef.SignatureID,
+		Name:           cef.Name,
+		Severity:       cef.Severity,
+		ExtensionsType: extensionsTypeName(cef.Extensions),
+		Extensions:     extensions,
+		CustomFields:   cef.CustomFields,
+	}
+	if sl := cef.Syslog; sl != nil {
+		out.Syslog = &syslogJSON{
+			Priority: sl.Priority, Facility: sl.Facility, Severity: sl.Severity, Version: sl.Version,
+			Hostname: sl.Hostname, AppName: sl.AppName, ProcID: sl.ProcID, MsgID: sl.MsgID,
+			StructuredData: sl.StructuredData,
+		}
+		if !sl.Timestamp.IsZero() {
+			out.Syslog.Timestamp = &sl.Timestamp
+		}
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes an event encoded by MarshalJSON. The extensions are
+// stored in the type named by extensionsType, or in the type registered for
+// the device in the package-level registry when it is missing or unknown.
+func (cef *CEF) UnmarshalJSON(data []byte) error {
+	var in cefJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	fields, err := unmarshalFields(in.Extensions)
+	if err != nil {
+		return fmt.Errorf("invalid extensions: %w", err)
+	}
+
+	ext, ok := newExtensionsType(in.ExtensionsType)
+	if !ok {
+		ext = NewExtensions(in.DeviceVendor, in.DeviceProduct, in.DeviceVersion)
+	}
+	loadExtensions(ext, joinFields(fields), fields, DuplicateCollect)
+
+	*cef = CEF{
+		Version:       in.Version,
+		DeviceVendor:  in.DeviceVendor,
+		DeviceProduct: in.DeviceProduct,
+		DeviceVersion: in.DeviceVersion,
+		SignatureID:   in.SignatureID,
+		Name:          in.Name,
+		Severity:      in.Severity,
+		Extensions:    ext,
+		CustomFields:  in.CustomFields,
+	}
+	if sl := in.Syslog; sl != nil {
+		cef.Syslog = &Syslog{
+			Priority: sl.Priority, Facility: sl.Facility, Severity: sl.Severity, Version: sl.Version,
+			Hostname: sl.Hostname, AppName: sl.AppName, ProcID: sl.ProcID, MsgID: sl.MsgID,
+			StructuredData: sl.StructuredData,
+		}
+		if sl.Timestamp != nil {
+			cef.Syslog.Timestamp = *sl.Timestamp
+		}
+	}
+	return nil
+}
+
+// marshalFields encodes extension fields as a JSON object in order, with the
+// values of a repeated key collected into an array at its first position.
+func marshalFields(fields []extensionField) ([]byte, error) {
+	var keys []string
+	values := make(map[string][]string, len(fields))
+	for _, field := range fields {
+		if _, ok := values[field.Key]; !ok {
+			keys = append(keys, field.Key)
+		}
+		values[field.Key] = append(values[field.Key], field.Value)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+
+		var data []byte
+		if vs := values[key]; len(vs) == 1 {
+			data, err = json.Marshal(vs[0])
+		} else {
+			data, err = json.Marshal(vs)
+		}
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// unmarshalFields decodes a JSON object of extension fields encoded by
+// marshalFields, keeping the order of its keys.
+func unmarshalFields(data []byte) ([]extensionField, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("expected a JSON object")
+	}
+
+	var fields []extensionField
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := tok.(string)
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		var value string
+		if err := json.Unmarshal(raw, &value); err == nil {
+			fields = append(fields, extensionField{Key: key, Value: value})
+			continue
+		}
+		var values []string
+		if err := json.Unmarshal(raw, &values); err != nil {
+			return nil, fmt.Errorf("value of %q must be a string or an array of strings", key)
+		}
+		for _, value := range values {
+			fields = append(fields, extensionField{Key: key, Value: value})
+		}
+	}
+	return fields, nil
+}
+
+// joinFields builds an extension string from fields for Extensions types that
+// only accept the raw string.
+func joinFields(fields []extensionField) string {
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		parts[i] = field.Key + "=" + extensionEscaper.Replace(field.Value)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,143 @@
+// Tests for the text and JSON encodings of CEF events.
+package parser
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestCEFJSONRoundTrip tests that events survive a JSON round trip with their
+// extension type.
+func TestCEFJSONRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		p    *Parser
+		line string
+	}{
+		{"Imperva", defaultParser, ImpervaCEF1},
+		{"Imperva combined", defaultParser, ImpervaCEFCombined},
+		{"Centrify", defaultParser, CentrifyCEF},
+		{"Default", defaultParser, `CEF:0|Ven\|dor|P|1|2|N|3|msg=a\=b c=d`},
+		{"Ordered", NewParser(WithOrderedExtensions(), WithDuplicateKeys(DuplicateCollect)), "CEF:0|V|P|1|2|N|3|dst=2 act=a act=b src=1"},
+		{"Labels", NewParser(WithLabelFolding(LabelCollisionSuffix)), CentrifyCEF},
+		{"Syslog", defaultParser, `<134>1 2024-10-16T12:00:00Z host app 1 ID [id@1 k="v"] CEF:0|V|P|1|2|N|3|src=1`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expected, err := test.p.Parse(test.line)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			data, err := json.Marshal(expected)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+
+			var decoded CEF
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if expected.Syslog != nil {
+				decoded.Syslog.Timestamp = decoded.Syslog.Timestamp.In(expected.Syslog.Timestamp.Location())
+			}
+			if !reflect.DeepEqual(&decoded, expected) {
+				t.Errorf("round trip = %+v, want %+v\nJSON: %s", decoded, expected, data)
+			}
+		})
+	}
+}
+
+// TestCEFJSONSchema tests the shape of the JSON form.
+func TestCEFJSONSchema(t *testing.T) {
+	cefEvent, err := NewParser(WithOrderedExtensions(), WithDuplicateKeys(DuplicateCollect)).Parse("CEF:0|V|P|1|2|N|3|dst=2 act=a src=1 act=b")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	data, err := json.Marshal(cefEvent)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	expected := `{"version":"0","deviceVendor":"V","deviceProduct":"P","deviceVersion":"1","signatureId":"2","name":"N","severity":"3","extensionsType":"ordered","extensions":{"dst":"2","act":["a","b"],"src":"1"}}`
+	if string(data) != expected {
+		t.Errorf("json.Marshal() = %s, want %s", data, expected)
+	}
+
+	imperva, _ := ParseCEF(ImpervaCEFCombined)
+	data, _ = json.Marshal(imperva)
+	if !strings.Contains(string(data), `"extensionsType":"imperva","extensions":{"fileId":"3412341160002518171",`) {
+		t.Errorf("unexpected Imperva JSON: %s", data)
+	}
+}
+
+// TestCEFUnmarshalJSON tests type selection and invalid input.
+func TestCEFUnmarshalJSON(t *testing.T) {
+	var cefEvent CEF
+	input := `{"deviceVendor":"Centrify","deviceProduct":"Centrify_Cloud","extensions":{"dhost":"h"}}`
+	if err := json.Unmarshal([]byte(input), &cefEvent); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if ce, ok := cefEvent.Extensions.(*CentrifyExtensions); !ok || ce.DHost != "h" {
+		t.Errorf("expected CentrifyExtensions from the registry, got %#v", cefEvent.Extensions)
+	}
+
+	input = `{"extensionsType":"default","extensions":{"ccode":["IL","US"]}}`
+	if err := json.Unmarshal([]byte(input), &cefEvent); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if fields := cefEvent.Extensions.AsMap(); fields["ccode"] != "IL, US" {
+		t.Errorf("AsMap() = %v", fields)
+	}
+
+	for _, input := range []string{`{"extensions":[]}`, `{"extensions":{"k":1}}`, `[]`} {
+		if err := json.Unmarshal([]byte(input), &cefEvent); err == nil {
+			t.Errorf("expected error for %s", input)
+		}
+	}
+}
+
+// testExtensions is a custom Extensions type.
+type testExtensions struct {
+	DefaultExtensions
+}
+
+// TestRegisterExtensionsType tests custom extension types in the JSON form.
+func TestRegisterExtensionsType(t *testing.T) {
+	if err := RegisterExtensionsType("", nil); err == nil {
+		t.Errorf("expected error for an empty name")
+	}
+	if err := RegisterExtensionsType("test", func() Extensions { return &testExtensions{} }); err != nil {
+		t.Fatalf("RegisterExtensionsType() error = %v", err)
+	}
+
+	original := &CEF{Version: "0", Extensions: &testExtensions{DefaultExtensions: DefaultExtensions{Fields: map[string]string{"msg": "a=b"}}}}
+	data, err := json.Marshal(original)
+	if err != nil || !strings.Contains(string(data), `"extensionsType":"test"`) {
+		t.Fatalf("json.Marshal() = %s, %v", data, err)
+	}
+	var decoded CEF
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if te, ok := decoded.Extensions.(*testExtensions); !ok || te.Fields["msg"] != "a=b" {
+		t.Errorf("decoded extensions = %#v", decoded.Extensions)
+	}
+}
+
+// TestCEFText tests the text encoding of events.
+func TestCEFText(t *testing.T) {
+	line := `CEF:0|V|P|1|2|N|3|act=blocked msg=a\=b`
+	var cefEvent CEF
+	if err := cefEvent.UnmarshalText([]byte(line)); err != nil {
+		t.Fatalf("UnmarshalText() error = %v", err)
+	}
+	text, err := cefEvent.MarshalText()
+	if err != nil || string(text) != line {
+		t.Errorf("MarshalText() = %s, %v", text, err)
+	}
+	if err := cefEvent.UnmarshalText([]byte("not cef")); err == nil {
+		t.Errorf("expected error for invalid input")
+	}
+}
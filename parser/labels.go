@@ -0,0 +1,162 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LabelCollision selects how FoldCustomFields handles custom fields that share
+// a label.
+type LabelCollision int
+
+const (
+	// LabelCollisionSuffix keeps the first field under its label and stores
+	// later ones as "label (key)", e.g. "latitude (cs8)".
+	LabelCollisionSuffix LabelCollision = iota
+	// LabelCollisionFirst keeps the first field and drops later ones.
+	LabelCollisionFirst
+	// LabelCollisionLast keeps the last field and drops earlier ones.
+	LabelCollisionLast
+	// LabelCollisionError reports an error when two fields share a label.
+	LabelCollisionError
+)
+
+// customFieldPrefixes lists the custom extension keys that are paired with a
+// "<key>Label" field, in folding order.
+var customFieldPrefixes = []string{
+	"cs", "cn", "cfp", "c6a", "deviceCustomDate", "flexString", "flexNumber", "flexDate",
+}
+
+// customField is a custom extension field and its label.
+type customField struct {
+	key    string
+	label  string
+	value  string
+	prefix int
+	number int
+}
+
+// FoldCustomFields returns the custom fields of ext (csN, cnN, cfpN, c6aN,
+// deviceCustomDateN, flexStringN, flexNumberN and flexDateN) keyed by their
+// labels, so that cs7=31.8969 cs7Label=latitude becomes {"latitude": "31.8969"}.
+// Fields without a label are keyed by their own name. Collisions are resolved
+// according to collision, in key order (cs1 before cs2, csN before cnN).
+func FoldCustomFields(ext Extensions, collision LabelCollision) (map[string]string, error) {
+	fields := extensionFields(ext)
+	custom := collectCustomFields(fields)
+
+	folded := make(map[string]string, len(custom))
+	owner := make(map[string]string, len(custom))
+	for _, field := range custom {
+		first, exists := owner[field.label]
+		if !exists {
+			folded[field.label] = field.value
+			owner[field.label] = field.key
+			continue
+		}
+		switch collision {
+		case LabelCollisionFirst:
+		case LabelCollisionLast:
+			folded[field.label] = field.value
+			owner[field.label] = field.key
+		case LabelCollisionError:
+			return nil, fmt.Errorf("custom fields %s and %s share the label %q", first, field.key, field.label)
+		default:
+			folded[field.label+" ("+field.key+")"] = field.value
+		}
+	}
+	return folded, nil
+}
+
+// collectCustomFields returns the labelled custom fields in fields, sorted by
+// prefix and number.
+func collectCustomFields(fields map[string]string) []customField {
+	var custom []customField
+	for key, value := range fields {
+		prefix, number, ok := customFieldKey(key)
+		if !ok || value == "" {
+			continue
+		}
+		label, _ := lookupKey(fields, key+"Label")
+		label = strings.TrimSpace(label)
+		if label == "" {
+			label = key
+		}
+		custom = append(custom, customField{key: key, label: label, value: value, prefix: prefix, number: number})
+	}
+
+	sort.Slice(custom, func(i, j int) bool {
+		if custom[i].prefix != custom[j].prefix {
+			return custom[i].prefix < custom[j].prefix
+		}
+		if custom[i].number != custom[j].number {
+			return custom[i].number < custom[j].number
+		}
+		return custom[i].key < custom[j].key
+	})
+	return custom
+}
+
+// customFieldKey reports whether key is a numbered custom field, returning the
+// index of its prefix in customFieldPrefixes and its number.
+func customFieldKey(key string) (int, int, bool) {
+	for i, prefix := range customFieldPrefixes {
+		if len(key) <= len(prefix) || !strings.EqualFold(key[:len(prefix)], prefix) {
+			continue
+		}
+		if suffix := key[len(prefix):]; isDigits(suffix) {
+			number, err := strconv.Atoi(suffix)
+			return i, number, err == nil
+		}
+	}
+	return 0, 0, false
+}
+
+// extensionFields returns the extension fields of ext keyed by CEF extension
+// key. Structs with `cef` tags are read through them; other types fall back to
+// AsMap.
+func extensionFields(ext Extensions) map[string]string {
+	if ext == nil {
+		return nil
+	}
+	if de, ok := ext.(*DefaultExtensions); ok {
+		return de.Fields
+	}
+
+	val := reflect.ValueOf(ext)
+	if val.Kind() != reflect.Ptr || val.IsNil() || val.Elem().Kind() != reflect.Struct {
+		return ext.AsMap()
+	}
+	val = val.Elem()
+	if !hasCEFTags(val.Type()) {
+		return ext.AsMap()
+	}
+	bindings := structBindings(val.Type())
+
+	fields := make(map[string]string, len(bindings))
+	for _, binding := range bindings {
+		if value, ok := stringifyField(val.FieldByIndex(binding.index)); ok {
+			fields[binding.names[0]] = value
+		}
+	}
+	if extra := unboundMap(ext); extra != nil {
+		for k, v := range *extra {
+			fields[k] = v
+		}
+	}
+	return fields
+}
+
+// hasCEFTags reports whether any field of the struct type t has a `cef` tag.
+func hasCEFTags(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := t.Field(i).Tag.Lookup("cef"); ok {
+			return true
+		}
+	}
+	return false
+}
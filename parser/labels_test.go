@@ -0,0 +1,108 @@
+// Tests for custom field label folding.
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestFoldCustomFields tests folding and each collision policy.
+func TestFoldCustomFields(t *testing.T) {
+	ext := &DefaultExtensions{Fields: map[string]string{
+		"cs1": "alpha", "cs1Label": "Rule name",
+		"cs2": "beta", "cs2Label": "Rule name",
+		"cn1": "42", "cn1Label": "count",
+		"cfp1": "1.5", "cfp1Label": "ratio",
+		"c6a3": "2001:db8::1", "c6a3Label": "peer",
+		"deviceCustomDate1": "Jan 21 2016 12:22:01", "deviceCustomDate1Label": "seen",
+		"flexString1": "x", "flexString1Label": "flex",
+		"flexNumber2": "7",
+		"msg":         "not custom",
+	}}
+
+	tests := []struct {
+		name      string
+		collision LabelCollision
+		expected  map[string]string
+		expectErr bool
+	}{
+		{"Suffix", LabelCollisionSuffix, map[string]string{"Rule name": "alpha", "Rule name (cs2)": "beta"}, false},
+		{"First", LabelCollisionFirst, map[string]string{"Rule name": "alpha"}, false},
+		{"Last", LabelCollisionLast, map[string]string{"Rule name": "beta"}, false},
+		{"Error", LabelCollisionError, nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folded, err := FoldCustomFields(ext, test.collision)
+			if test.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", folded)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("did not expect error, got '%v'", err)
+			}
+			expected := map[string]string{
+				"count": "42", "ratio": "1.5", "peer": "2001:db8::1",
+				"seen": "Jan 21 2016 12:22:01", "flex": "x", "flexNumber2": "7",
+			}
+			for k, v := range test.expected {
+				expected[k] = v
+			}
+			if !reflect.DeepEqual(folded, expected) {
+				t.Errorf("FoldCustomFields() = %v, want %v", folded, expected)
+			}
+		})
+	}
+}
+
+// TestVendorCustomFields tests CustomFields on the vendor extension types.
+func TestVendorCustomFields(t *testing.T) {
+	cefEvent, err := ParseCEF(ImpervaCEF1)
+	if err != nil {
+		t.Fatalf("ParseCEF() error = %v", err)
+	}
+	folded := cefEvent.Extensions.(*ImpervaExtensions).CustomFields()
+	if folded["latitude"] != "37.751" || folded["longitude"] != "-97.822" || folded["clapp"] != "Microsoft Edge" {
+		t.Errorf("unexpected Imperva custom fields: %v", folded)
+	}
+	if _, ok := folded["Rule Info"]; !ok {
+		t.Errorf("expected Rule Info in Imperva custom fields: %v", folded)
+	}
+
+	cefEvent, err = ParseCEF(CentrifyCEF)
+	if err != nil {
+		t.Fatalf("ParseCEF() error = %v", err)
+	}
+	folded = cefEvent.Extensions.(*CentrifyExtensions).CustomFields()
+	if folded["applicationName"] != "Instagram" || folded["clientIPAddress"] != "103.6.32.100" {
+		t.Errorf("unexpected Centrify custom fields: %v", folded)
+	}
+}
+
+// TestWithLabelFolding tests label folding during parsing.
+func TestWithLabelFolding(t *testing.T) {
+	cef := "CEF:0|Vendor|Product|1.0|100|Name|5|cs1=a cs1Label=dup cs2=b cs2Label=dup"
+
+	cefEvent, err := NewParser(WithLabelFolding(LabelCollisionLast)).Parse(cef)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if !reflect.DeepEqual(cefEvent.CustomFields, map[string]string{"dup": "b"}) {
+		t.Errorf("CustomFields = %v", cefEvent.CustomFields)
+	}
+
+	if _, err := NewParser(WithLabelFolding(LabelCollisionError)).Parse(cef); err == nil {
+		t.Errorf("expected error for shared label, got nil")
+	}
+
+	cefEvent, err = ParseCEF(cef)
+	if err != nil {
+		t.Fatalf("ParseCEF() error = %v", err)
+	}
+	if cefEvent.CustomFields != nil {
+		t.Errorf("expected no CustomFields without the option, got %v", cefEvent.CustomFields)
+	}
+}
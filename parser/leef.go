@@ -0,0 +1,458 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// leefPrefix is the prefix that starts every LEEF record.
+const leefPrefix = "LEEF:"
+
+// RecordFormat identifies the format of an event record.
+type RecordFormat int
+
+const (
+	// RecordUnknown is neither a CEF nor a LEEF record.
+	RecordUnknown RecordFormat = iota
+	// RecordCEF is an ArcSight CEF record.
+	RecordCEF
+	// RecordLEEF is an IBM LEEF 1.0 or 2.0 record.
+	RecordLEEF
+)
+
+// String returns the name of the record format.
+func (f RecordFormat) String() string {
+	switch f {
+	case RecordCEF:
+		return "CEF"
+	case RecordLEEF:
+		return "LEEF"
+	}
+	return "unknown"
+}
+
+// DetectFormat reports whether line is a CEF or a LEEF record, possibly inside
+// a syslog envelope, from the first "CEF:" or "LEEF:" prefix that starts a
+// space-separated token, as SplitSyslog finds the record.
+func DetectFormat(line string) RecordFormat {
+	start := cefMessageStart(line)
+	switch {
+	case start < 0:
+		return RecordUnknown
+	case strings.HasPrefix(line[start:], leefPrefix):
+		return RecordLEEF
+	}
+	return RecordCEF
+}
+
+// leefTimeLayout is the default LEEF devTime format, MMM dd yyyy HH:mm:ss.SSS zzz.
+const leefTimeLayout = "Jan 02 2006 15:04:05.000 MST"
+
+// leefAttributes pairs the predefined LEEF attributes with the CEF extension
+// keys of the same meaning. devTime is paired with rt only when the record has
+// no devTimeFormat, since custom formats are not understood by ParseTimestamp.
+var leefAttributes = [][2]string{
+	{"cat", "cat"},
+	{"devTime", "rt"},
+	{"proto", "proto"},
+	{"src", "src"},
+	{"dst", "dst"},
+	{"srcPort", "spt"},
+	{"dstPort", "dpt"},
+	{"srcPostNAT", "sourceTranslatedAddress"},
+	{"dstPostNAT", "destinationTranslatedAddress"},
+	{"srcPostNATPort", "sourceTranslatedPort"},
+	{"dstPostNATPort", "destinationTranslatedPort"},
+	{"usrName", "suser"},
+	{"srcMAC", "smac"},
+	{"dstMAC", "dmac"},
+	{"srcBytes", "in"},
+	{"dstBytes", "out"},
+}
+
+// leefToCEFKeys and cefToLEEFKeys index leefAttributes in each direction.
+var leefToCEFKeys, cefToLEEFKeys = func() (map[string]string, map[string]string) {
+	toCEF := make(map[string]string, len(leefAttributes))
+	toLEEF := make(map[string]string, len(leefAttributes))
+	for _, pair := range leefAttributes {
+		toCEF[pair[0]], toLEEF[pair[1]] = pair[1], pair[0]
+	}
+	return toCEF, toLEEF
+}()
+
+// ParseLEEF parses a LEEF record with the default parser. See Parser.ParseLEEF.
+func ParseLEEF(line string) (*CEF, error) {
+	return defaultParser.ParseLEEF(line)
+}
+
+// ParseAuto parses a CEF or LEEF record with the default parser. See
+// Parser.ParseAuto.
+func ParseAuto(line string) (*CEF, error) {
+	return defaultParser.ParseAuto(line)
+}
+
+// ParseAuto parses line with Parse or ParseLEEF, as detected by DetectFormat.
+func (p *Parser) ParseAuto(line string) (*CEF, error) {
+	if DetectFormat(line) == RecordLEEF {
+		return p.ParseLEEF(line)
+	}
+	return p.Parse(line)
+}
+
+// ParseLEEF parses a LEEF 1.0 or 2.0 record into a CEF event:
+//
+//	LEEF:1.0|Vendor|Product|Version|EventID|key=value<tab>key=value
+//	LEEF:2.0|Vendor|Product|Version|EventID|^|key=value^key=value
+//
+// The vendor, product and version map to the device fields and the event ID
+// to SignatureID. The LEEF 2.0 delimiter is a single character or a hex code
+// such as "x09", and defaults to a tab. Predefined attributes such as srcPort
+// and usrName are stored under the CEF keys of the same meaning (spt, suser),
+// the sev attribute becomes the Severity ("Unknown" when absent) and a name
+// attribute becomes the Name, which otherwise repeats the event ID. Other
+// attributes are stored as they are.
+//
+// The parser's extension types, limits, duplicate-key policy, header
+// validation, label folding and syslog settings apply; its lenient mode does
+// not.
+func (p *Parser) ParseLEEF(line string) (*CEF, error) {
+	if len(line) == 0 {
+		return nil, newParseError(ErrInvalidLength, ReasonEmpty, line, 0)
+	}
+	if p.maxLineLength > 0 && len(line) > p.maxLineLength {
+		return nil, newParseError(ErrInvalidLength, ReasonTooLong, line, p.maxLineLength)
+	}
+
+	record := line
+	var envelope *Syslog
+	if p.syslog && !strings.HasPrefix(record, leefPrefix) {
+		sl, msg, err := splitSyslogAt(record, p.location, p.now())
+		if err != nil {
+			reason := ReasonSyslog
+			if !strings.HasPrefix(line, "<") && !strings.Contains(line, leefPrefix) {
+				reason = ReasonMissingPrefix
+			}
+			return nil, newParseError(ErrInvalidFormat, reason, line, 0)
+		}
+		envelope, record = sl, msg
+	}
+	base := len(line) - len(record)
+	if !strings.HasPrefix(record, leefPrefix) {
+		return nil, newParseError(ErrInvalidFormat, ReasonMissingPrefix, line, base)
+	}
+
+	header, attributes, delimiter, err := splitLEEFHeader(record[len(leefPrefix):])
+	if err != nil {
+		err.Offset += base + len(leefPrefix)
+		err.Snippet = snippet(line, err.Offset)
+		return nil, err
+	}
+	attrOffset := len(line) - len(attributes)
+
+	fields, err := p.splitLEEFAttributes(attributes, delimiter, attrOffset)
+	if err != nil {
+		err.Snippet = snippet(line, err.Offset)
+		return nil, err
+	}
+
+	cefEvent := &CEF{
+		Version:       "0",
+		DeviceVendor:  header[1],
+		DeviceProduct: header[2],
+		DeviceVersion: header[3],
+		SignatureID:   header[4],
+		Name:          header[4],
+		Severity:      "Unknown",
+		Syslog:        envelope,
+		times:         p.timeSettings(),
+	}
+	if p.duplicates == DuplicateError {
+		if err := firstDuplicate(fields); err != nil {
+			return nil, extensionError(err, line, line)
+		}
+	}
+	fields = leefFieldsToCEF(cefEvent, fields)
+
+	// The Name and Severity come from attributes, so header problems are
+	// placed at the start of the record rather than at a header field.
+	if err := validateHeader([cefHeaderFields]string{
+		cefEvent.Version, cefEvent.DeviceVendor, cefEvent.DeviceProduct, cefEvent.DeviceVersion,
+		cefEvent.SignatureID, cefEvent.Name, cefEvent.Severity,
+	}, p.validation, p.maxHeaderLength); err != nil {
+		var perr *ParseError
+		if errors.As(err, &perr) {
+			perr.Offset = base
+			perr.Snippet = snippet(line, base)
+		}
+		return nil, err
+	}
+
+	cefEvent.Extensions = p.newExtensions(cefEvent.DeviceVendor, cefEvent.DeviceProduct, cefEvent.DeviceVersion)
+	loadExtensions(cefEvent.Extensions, joinFields(fields), fields, p.duplicates)
+	if p.foldLabels {
+		var ferr error
+		if cefEvent.CustomFields, ferr = FoldCustomFields(cefEvent.Extensions, p.labelCollision); ferr != nil {
+			perr := newParseError(ErrInvalidExtension, ReasonLabelCollision, line, attrOffset)
+			perr.Detail = ferr.Error()
+			return nil, perr
+		}
+	}
+	return cefEvent, nil
+}
+
+// splitLEEFHeader splits the header of a LEEF record following the prefix into
+// its version, vendor, product, product version and event ID, and returns the
+// attributes and their delimiter. Error offsets are relative to s.
+func splitLEEFHeader(s string) ([5]string, string, string, *ParseError) {
+	var header [5]string
+	rest := s
+	for i := range header {
+		field, after, ok := cutHeaderField(rest)
+		if !ok && i < len(header)-1 {
+			return header, "", "", newParseError(ErrInvalidFormat, ReasonMissingFields, s, len(s))
+		}
+		header[i] = unescapeHeaderValue(field)
+		rest = after
+	}
+
+	switch header[0] {
+	case "1.0", "1":
+		return header, rest, "\t", nil
+	case "2.0", "2":
+	default:
+		perr := newParseError(ErrInvalidHeader, ReasonInvalidVersion, s, 0)
+		perr.Field = 0
+		perr.Detail = fmt.Sprintf("unsupported LEEF version %q", header[0])
+		return header, "", "", perr
+	}
+
+	// The delimiter field is optional in LEEF 2.0; a field holding an
+	// attribute means it was left out.
+	field, after, ok := cutHeaderField(rest)
+	if !ok || strings.Contains(field, "=") {
+		return header, rest, "\t", nil
+	}
+	delimiter, valid := parseLEEFDelimiter(field)
+	if !valid {
+		perr := newParseError(ErrInvalidHeader, ReasonInvalidDelimiter, s, len(s)-len(rest))
+		perr.Detail = fmt.Sprintf("invalid LEEF delimiter %q", field)
+		return header, "", "", perr
+	}
+	return header, after, delimiter, nil
+}
+
+// cutHeaderField cuts s at the first unescaped pipe.
+func cutHeaderField(s string) (string, string, bool) {
+	escaped := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case s[i] == '\\':
+			escaped = true
+		case s[i] == '|':
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
+
+// parseLEEFDelimiter parses a LEEF 2.0 delimiter: empty for a tab, a single
+// character, or a hex code such as "x09", "0x5E" or "xa6".
+func parseLEEFDelimiter(field string) (string, bool) {
+	if field == "" {
+		return "\t", true
+	}
+	if r, size := utf8.DecodeRuneInString(field); size == len(field) {
+		return field, r != utf8.RuneError && r != '='
+	}
+
+	hex := strings.TrimPrefix(field, "0")
+	if len(hex) < 2 || (hex[0] != 'x' && hex[0] != 'X') || len(hex) > 5 {
+		return "", false
+	}
+	code, err := strconv.ParseUint(hex[1:], 16, 32)
+	if err != nil || code == '=' || !utf8.ValidRune(rune(code)) {
+		return "", false
+	}
+	return string(rune(code)), true
+}
+
+// splitLEEFAttributes splits the attributes of a LEEF record, enforcing the
+// parser's limits. offset is the position of attributes within the record.
+func (p *Parser) splitLEEFAttributes(attributes, delimiter string, offset int) ([]extensionField, *ParseError) {
+	var fields []extensionField
+	pos := offset
+	for _, attr := range strings.Split(attributes, delimiter) {
+		start := pos
+		pos += len(attr) + len(delimiter)
+		if strings.TrimSpace(attr) == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(attr, "=")
+		if !ok || key == "" || strings.ContainsAny(key, " \t\r\n") {
+			perr := &ParseError{Err: ErrInvalidExtension, Reason: ReasonInvalidAttribute, Field: -1, Offset: start}
+			perr.Detail = fmt.Sprintf("attribute %q is not a key=value pair", attr)
+			return nil, perr
+		}
+		field := extensionField{Key: key, Value: value, Offset: start}
+
+		switch {
+		case p.maxExtensions > 0 && len(fields) == p.maxExtensions:
+			return nil, p.tooManyExtensions(field).(*ParseError)
+		case p.maxKeyLength > 0 && len(key) > p.maxKeyLength:
+			return nil, &ParseError{
+				Err: ErrInvalidExtension, Reason: ReasonKeyTooLong, Field: -1, Key: key, Offset: start,
+				Detail: fmt.Sprintf("key %q is longer than %d characters", key, p.maxKeyLength),
+			}
+		case p.maxValueLength > 0 && len(value) > p.maxValueLength:
+			return nil, &ParseError{
+				Err: ErrInvalidExtension, Reason: ReasonValueTooLong, Field: -1, Key: key, Offset: start + len(key) + 1,
+				Detail: fmt.Sprintf("value of %q is longer than %d characters", key, p.maxValueLength),
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// leefFieldsToCEF moves the sev and name attributes of a LEEF record into the
+// header of cefEvent and renames the predefined attributes to CEF keys.
+func leefFieldsToCEF(cefEvent *CEF, fields []extensionField) []extensionField {
+	customTime := false
+	for _, field := range fields {
+		if field.Key == "devTimeFormat" {
+			customTime = true
+		}
+	}
+
+	out := fields[:0]
+	for _, field := range fields {
+		switch field.Key {
+		case "sev":
+			cefEvent.Severity = field.Value
+			continue
+		case "name":
+			cefEvent.Name = field.Value
+			continue
+		}
+		if key, ok := leefToCEFKeys[field.Key]; ok && !(customTime && field.Key == "devTime") {
+			field.Key = key
+		}
+		out = append(out, field)
+	}
+	return out
+}
+
+// FormatLEEF encodes the CEF event as a LEEF record of the given version,
+// "1.0" or "2.0". LEEF 1.0 records are tab-delimited; for LEEF 2.0 delimiter
+// is a single character, or empty for a tab. CEF keys with a predefined LEEF
+// attribute are renamed (spt to srcPort, suser to usrName, ...), the Severity
+// is written as sev when it is 1-10 or a textual severity, and the Name as
+// name when it differs from the SignatureID. An rt given in epoch time is
+// written in the default LEEF date format. Values may not contain the
+// delimiter or line breaks.
+func FormatLEEF(cef *CEF, version, delimiter string) (string, error) {
+	if cef == nil {
+		return "", fmt.Errorf("cannot format nil CEF event")
+	}
+	switch version {
+	case "1.0", "1":
+		if delimiter != "" && delimiter != "\t" {
+			return "", fmt.Errorf("LEEF %s records are tab-delimited", version)
+		}
+	case "2.0", "2":
+		if r, size := utf8.DecodeRuneInString(delimiter); delimiter != "" && (size != len(delimiter) || r == '=' || r == '|') {
+			return "", fmt.Errorf("invalid LEEF delimiter %q", delimiter)
+		}
+	default:
+		return "", fmt.Errorf("unsupported LEEF version %q", version)
+	}
+	if delimiter == "" {
+		delimiter = "\t"
+	}
+
+	var b strings.Builder
+	b.WriteString(leefPrefix)
+	b.WriteString(version)
+	for i, value := range []string{cef.DeviceVendor, cef.DeviceProduct, cef.DeviceVersion, cef.SignatureID} {
+		if strings.ContainsAny(value, "\r\n") {
+			return "", fmt.Errorf("cannot format %s: contains a line break", headerFieldNames[i+1])
+		}
+		b.WriteByte('|')
+		b.WriteString(headerEscaper.Replace(value))
+	}
+	b.WriteByte('|')
+	if strings.HasPrefix(version, "2") {
+		if r, _ := utf8.DecodeRuneInString(delimiter); r < 0x20 || r == 0x7f {
+			fmt.Fprintf(&b, "x%02X", r)
+		} else {
+			b.WriteString(delimiter)
+		}
+		b.WriteByte('|')
+	}
+
+	fields := formatFields(cef.Extensions)
+	if severity, ok := ecsSeverity(cef.Severity); ok && severity >= 1 && severity <= 10 {
+		fields = append([]extensionField{{Key: "sev", Value: strconv.Itoa(severity)}}, fields...)
+	}
+	if cef.Name != "" && cef.Name != cef.SignatureID {
+		fields = append([]extensionField{{Key: "name", Value: cef.Name}}, fields...)
+	}
+
+	for i, field := range fields {
+		key := field.Key
+		if name, ok := cefToLEEFKeys[key]; ok {
+			key = name
+		}
+		value := field.Value
+		if key == "devTime" {
+			if ts, ok := parseEpoch(strings.TrimSpace(value)); ok {
+				value = ts.UTC().Format(leefTimeLayout)
+			}
+		}
+		if key == "" || strings.ContainsAny(key, "= \t\r\n") || strings.Contains(key, delimiter) {
+			return "", fmt.Errorf("cannot format attribute key %q", field.Key)
+		}
+		if strings.ContainsAny(value, "\r\n") || strings.Contains(value, delimiter) {
+			return "", fmt.Errorf("cannot format value of %q: contains the delimiter or a line break", field.Key)
+		}
+		if i > 0 {
+			b.WriteString(delimiter)
+		}
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(value)
+	}
+	return b.String(), nil
+}
+
+// convertParser parses the records given to ConvertCEFToLEEF and
+// ConvertLEEFToCEF, keeping their fields in order.
+var convertParser = NewParser(WithOrderedExtensions(), WithSyslog(true))
+
+// ConvertCEFToLEEF converts a CEF record to a LEEF record of the given version
+// and delimiter, keeping the order of the fields. See FormatLEEF.
+func ConvertCEFToLEEF(line, version, delimiter string) (string, error) {
+	cefEvent, err := convertParser.Parse(line)
+	if err != nil {
+		return "", err
+	}
+	return FormatLEEF(cefEvent, version, delimiter)
+}
+
+// ConvertLEEFToCEF converts a LEEF record to a CEF record, keeping the order of
+// the fields. Attributes whose keys are not valid CEF keys cannot be
+// converted. See ParseLEEF and Format.
+func ConvertLEEFToCEF(line string) (string, error) {
+	cefEvent, err := convertParser.ParseLEEF(line)
+	if err != nil {
+		return "", err
+	}
+	return Format(cefEvent)
+}
@@ -0,0 +1,238 @@
+// Tests for LEEF parsing, format detection and CEF/LEEF conversion.
+package parser
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestParseLEEF tests the header mapping, delimiters and attribute renaming.
+func TestParseLEEF(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		header   [7]string
+		expected map[string]string
+	}{
+		{
+			name:     "LEEF 1.0",
+			line:     "LEEF:1.0|Microsoft|MSExchange|4.0 SP1|15345|src=192.0.2.0\tdst=172.50.123.1\tsev=5\tcat=anomaly\tsrcPort=81\tdstPort=21\tusrName=joe.black",
+			header:   [7]string{"0", "Microsoft", "MSExchange", "4.0 SP1", "15345", "15345", "5"},
+			expected: map[string]string{"src": "192.0.2.0", "dst": "172.50.123.1", "cat": "anomaly", "spt": "81", "dpt": "21", "suser": "joe.black"},
+		},
+		{
+			name:     "LEEF 2.0 with a caret",
+			line:     "LEEF:2.0|Lancope|StealthWatch|1.0|41|^|src=10.0.1.8^dst=10.0.0.5^sev=5^srcPort=41234^name=Port scan",
+			header:   [7]string{"0", "Lancope", "StealthWatch", "1.0", "41", "Port scan", "5"},
+			expected: map[string]string{"src": "10.0.1.8", "dst": "10.0.0.5", "spt": "41234"},
+		},
+		{
+			name:     "LEEF 2.0 with a hex delimiter",
+			line:     "LEEF:2.0|V|P|1|E|x09|proto=TCP\tsrcBytes=10\tdstBytes=20",
+			header:   [7]string{"0", "V", "P", "1", "E", "E", "Unknown"},
+			expected: map[string]string{"proto": "TCP", "in": "10", "out": "20"},
+		},
+		{
+			name:     "LEEF 2.0 without a delimiter",
+			line:     "LEEF:2.0|V|P|1|E|src=1\tdst=2",
+			header:   [7]string{"0", "V", "P", "1", "E", "E", "Unknown"},
+			expected: map[string]string{"src": "1", "dst": "2"},
+		},
+		{
+			name:     "Custom devTime format",
+			line:     "LEEF:1.0|V|P|1|E|devTime=1729080000000\tdevTimeFormat=epoch\tcustom=x",
+			header:   [7]string{"0", "V", "P", "1", "E", "E", "Unknown"},
+			expected: map[string]string{"devTime": "1729080000000", "devTimeFormat": "epoch", "custom": "x"},
+		},
+		{
+			name:     "No attributes",
+			line:     "LEEF:1.0|V|P|1|E|",
+			header:   [7]string{"0", "V", "P", "1", "E", "E", "Unknown"},
+			expected: map[string]string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cefEvent, err := ParseLEEF(test.line)
+			if err != nil {
+				t.Fatalf("ParseLEEF() error = %v", err)
+			}
+			header := [7]string{cefEvent.Version, cefEvent.DeviceVendor, cefEvent.DeviceProduct, cefEvent.DeviceVersion,
+				cefEvent.SignatureID, cefEvent.Name, cefEvent.Severity}
+			if header != test.header {
+				t.Errorf("header = %q, want %q", header, test.header)
+			}
+			if fields := extensionFields(cefEvent.Extensions); !reflect.DeepEqual(fields, test.expected) {
+				t.Errorf("fields = %v, want %v", fields, test.expected)
+			}
+		})
+	}
+}
+
+// TestParseLEEFTime tests that devTime is usable as the receipt time.
+func TestParseLEEFTime(t *testing.T) {
+	cefEvent, err := ParseLEEF("LEEF:1.0|V|P|1|E|devTime=Oct 16 2024 12:00:00.000 UTC")
+	if err != nil {
+		t.Fatalf("ParseLEEF() error = %v", err)
+	}
+	if ts, err := cefEvent.ReceiptTime(); err != nil || !ts.Equal(time.Date(2024, 10, 16, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("ReceiptTime() = %v, %v", ts, err)
+	}
+}
+
+// TestParseLEEFSyslog tests LEEF records inside syslog envelopes.
+func TestParseLEEFSyslog(t *testing.T) {
+	p := NewParser(WithSyslog(true))
+	for _, line := range []string{
+		"<13>Oct 16 12:00:00 qradar LEEF:1.0|V|P|1|E|src=10.0.0.1",
+		"<13>1 2024-10-16T12:00:00Z qradar app - - - LEEF:1.0|V|P|1|E|src=10.0.0.1",
+	} {
+		cefEvent, err := p.ParseLEEF(line)
+		if err != nil {
+			t.Fatalf("ParseLEEF(%q) error = %v", line, err)
+		}
+		if cefEvent.Syslog == nil || cefEvent.Syslog.Hostname != "qradar" || extensionFields(cefEvent.Extensions)["src"] != "10.0.0.1" {
+			t.Errorf("unexpected event for %q: %+v", line, cefEvent)
+		}
+	}
+	line := "<13>Jan 1 00:00:00 hostCEF: LEEF:1.0|V|P|1|E|a=1"
+	cefEvent, err := ParseAuto(line)
+	if err != nil {
+		t.Fatalf("ParseAuto(%q) error = %v", line, err)
+	}
+	if cefEvent.DeviceVendor != "V" || cefEvent.SignatureID != "E" || extensionFields(cefEvent.Extensions)["a"] != "1" {
+		t.Errorf("unexpected event for %q: %+v", line, cefEvent)
+	}
+}
+
+// TestParseLEEFErrors tests the sentinel, reason and offset of LEEF parse errors.
+func TestParseLEEFErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		p        *Parser
+		line     string
+		sentinel error
+		reason   Reason
+		offset   int
+	}{
+		{"Empty", defaultParser, "", ErrInvalidLength, ReasonEmpty, 0},
+		{"Missing prefix", defaultParser, "CEF:0|V|P|1|2|N|3|", ErrInvalidFormat, ReasonMissingPrefix, 0},
+		{"Missing fields", defaultParser, "LEEF:1.0|V|P", ErrInvalidFormat, ReasonMissingFields, 12},
+		{"Invalid version", defaultParser, "LEEF:3.0|V|P|1|E|", ErrInvalidHeader, ReasonInvalidVersion, 5},
+		{"Invalid delimiter", defaultParser, "LEEF:2.0|V|P|1|E|xZZ|a=1", ErrInvalidHeader, ReasonInvalidDelimiter, 17},
+		{"Malformed attribute", defaultParser, "LEEF:1.0|V|P|1|E|src=1\tbad", ErrInvalidExtension, ReasonInvalidAttribute, 23},
+		{"Invalid severity", defaultParser, "LEEF:1.0|V|P|1|E|sev=11", ErrInvalidHeader, ReasonInvalidSeverity, 0},
+		{"Too many attributes", NewParser(WithMaxExtensions(1)), "LEEF:1.0|V|P|1|E|a=1\tb=2", ErrInvalidExtension, ReasonTooManyExtensions, 21},
+		{"Duplicate key", NewParser(WithDuplicateKeys(DuplicateError)), "LEEF:1.0|V|P|1|E|a=1\ta=2", ErrInvalidExtension, ReasonDuplicateKey, 21},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.p.ParseLEEF(test.line)
+			if !errors.Is(err, test.sentinel) {
+				t.Fatalf("expected %v, got %v", test.sentinel, err)
+			}
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("expected *ParseError, got %T", err)
+			}
+			if perr.Reason != test.reason || perr.Offset != test.offset {
+				t.Errorf("got reason %v, offset %d; want %v, %d", perr.Reason, perr.Offset, test.reason, test.offset)
+			}
+		})
+	}
+}
+
+// TestDetectFormat tests format detection with and without syslog envelopes.
+func TestDetectFormat(t *testing.T) {
+	for line, expected := range map[string]RecordFormat{
+		"CEF:0|V|P|1|2|N|3|":                               RecordCEF,
+		"LEEF:1.0|V|P|1|E|":                                RecordLEEF,
+		"<13>Oct 16 12:00:00 host LEEF:2.0|V|P":            RecordLEEF,
+		"<13>Oct 16 12:00:00 host CEF:0|V|P":               RecordCEF,
+		"CEF:0|V|P|1|2|N|3|msg=LEEF:1.0":                   RecordCEF,
+		"<13>Jan 1 00:00:00 hostCEF: LEEF:1.0|V|P|1|E|a=1": RecordLEEF,
+		"<13>Jan 1 00:00:00 host app:CEF:0|V":              RecordUnknown,
+		"hello":                                            RecordUnknown,
+	} {
+		if got := DetectFormat(line); got != expected {
+			t.Errorf("DetectFormat(%q) = %v, want %v", line, got, expected)
+		}
+	}
+
+	for _, line := range []string{"CEF:0|V|P|1|2|N|3|src=10.0.0.1", "LEEF:1.0|V|P|1|2|src=10.0.0.1\tname=N\tsev=3"} {
+		cefEvent, err := ParseAuto(line)
+		if err != nil {
+			t.Fatalf("ParseAuto(%q) error = %v", line, err)
+		}
+		if cefEvent.Name != "N" || cefEvent.Severity != "3" || extensionFields(cefEvent.Extensions)["src"] != "10.0.0.1" {
+			t.Errorf("unexpected event for %q: %+v", line, cefEvent)
+		}
+	}
+}
+
+// TestFormatLEEF tests attribute renaming, sev and name, and delimiters.
+func TestFormatLEEF(t *testing.T) {
+	cefEvent, _ := NewParser(WithOrderedExtensions()).Parse("CEF:0|Security|threatmanager|1.0|100|worm stopped|High|src=10.0.0.1 spt=1232 suser=joe rt=1729080000000 cs1=a")
+	tests := []struct {
+		version   string
+		delimiter string
+		expected  string
+	}{
+		{"1.0", "", "LEEF:1.0|Security|threatmanager|1.0|100|name=worm stopped\tsev=8\tsrc=10.0.0.1\tsrcPort=1232\tusrName=joe\tdevTime=Oct 16 2024 12:00:00.000 UTC\tcs1=a"},
+		{"2.0", "^", "LEEF:2.0|Security|threatmanager|1.0|100|^|name=worm stopped^sev=8^src=10.0.0.1^srcPort=1232^usrName=joe^devTime=Oct 16 2024 12:00:00.000 UTC^cs1=a"},
+		{"2.0", "", "LEEF:2.0|Security|threatmanager|1.0|100|x09|name=worm stopped\tsev=8\tsrc=10.0.0.1\tsrcPort=1232\tusrName=joe\tdevTime=Oct 16 2024 12:00:00.000 UTC\tcs1=a"},
+	}
+	for _, test := range tests {
+		got, err := FormatLEEF(cefEvent, test.version, test.delimiter)
+		if err != nil {
+			t.Fatalf("FormatLEEF(%s, %q) error = %v", test.version, test.delimiter, err)
+		}
+		if got != test.expected {
+			t.Errorf("FormatLEEF(%s, %q) =\n%q, want\n%q", test.version, test.delimiter, got, test.expected)
+		}
+	}
+
+	for _, args := range [][2]string{{"1.0", "^"}, {"3.0", ""}, {"2.0", "|"}, {"2.0", "ab"}, {"2.0", "="}, {"2.0", "."}} {
+		if _, err := FormatLEEF(cefEvent, args[0], args[1]); err == nil {
+			t.Errorf("FormatLEEF(%s, %q): expected an error", args[0], args[1])
+		}
+	}
+}
+
+// TestConvertLEEF tests CEF to LEEF conversion and back.
+func TestConvertLEEF(t *testing.T) {
+	line := "CEF:0|Security|threatmanager|1.0|100|worm stopped|10|src=10.0.0.1 dst=2.1.2.2 spt=1232 dpt=80 proto=TCP act=blocked"
+	leef, err := ConvertCEFToLEEF(line, "2.0", "^")
+	if err != nil {
+		t.Fatalf("ConvertCEFToLEEF() error = %v", err)
+	}
+	back, err := ConvertLEEFToCEF(leef)
+	if err != nil {
+		t.Fatalf("ConvertLEEFToCEF() error = %v", err)
+	}
+	if back != line {
+		t.Errorf("round trip =\n%s, want\n%s", back, line)
+	}
+
+	imperva, _ := ParseCEF(ImpervaCEF1)
+	leef, err = FormatLEEF(imperva, "2.0", "\x1f")
+	if err != nil {
+		t.Fatalf("FormatLEEF() error = %v", err)
+	}
+	again, err := ParseLEEF(leef)
+	if err != nil {
+		t.Fatalf("ParseLEEF() error = %v", err)
+	}
+	if !reflect.DeepEqual(extensionFields(again.Extensions), extensionFields(imperva.Extensions)) ||
+		again.Name != imperva.Name || again.SignatureID != imperva.SignatureID {
+		t.Errorf("round trip lost fields:\n%v\n%v", extensionFields(again.Extensions), extensionFields(imperva.Extensions))
+	}
+
+	if _, err := ConvertLEEFToCEF("LEEF:1.0|V|P|1|E|bad-key=1"); err == nil {
+		t.Errorf("expected an error for a key that is not a valid CEF key")
+	}
+}
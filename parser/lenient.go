@@ -0,0 +1,204 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Warnings lists the problems found while parsing a record in lenient mode, in
+// the order they occur in the record.
+type Warnings []*ParseError
+
+// Error implements the error interface.
+func (w Warnings) Error() string {
+	switch len(w) {
+	case 0:
+		return "no warnings"
+	case 1:
+		return w[0].Error()
+	}
+	msgs := make([]string, len(w))
+	for i, warning := range w {
+		msgs[i] = warning.Error()
+	}
+	return fmt.Sprintf("%d problems: %s", len(w), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the warnings so that errors.Is and errors.As can match any of
+// them.
+func (w Warnings) Unwrap() []error {
+	errs := make([]error, len(w))
+	for i, warning := range w {
+		errs[i] = warning
+	}
+	return errs
+}
+
+// ParseCEFLenient parses a CEF record in lenient mode using the default parser.
+// See Parser.ParseLenient.
+func ParseCEFLenient(cef string) (*CEF, Warnings) {
+	return defaultParser.ParseLenient(cef)
+}
+
+// ParseLenient parses a CEF record on a best-effort basis. It never fails:
+// the returned event is always non-nil and holds whatever could be recovered,
+// while every problem found along the way is returned as a warning.
+//
+// Records longer than the maximum line length are truncated. Text before the
+// "CEF:" prefix that is not a valid syslog envelope is skipped. A header cut
+// short keeps the fields that are present, with the last one possibly partial.
+// Invalid header fields, malformed escapes and extension fields beyond the
+// configured limits are kept as they are. Duplicate keys are reported unless the
+// DuplicateKeyPolicy is DuplicateCollect; DuplicateError keeps the last value.
+// A label collision under LabelCollisionError falls back to LabelCollisionSuffix.
+func (p *Parser) ParseLenient(line string) (*CEF, Warnings) {
+	var warnings Warnings
+	cefEvent := &CEF{times: p.timeSettings()}
+
+	cef := line
+	if len(cef) == 0 {
+		warnings = append(warnings, newParseError(ErrInvalidLength, ReasonEmpty, line, 0))
+		return p.finishLenient(cefEvent, "", nil), warnings
+	}
+	if p.maxLineLength > 0 && len(cef) > p.maxLineLength {
+		warnings = append(warnings, newParseError(ErrInvalidLength, ReasonTooLong, line, p.maxLineLength))
+		end := p.maxLineLength
+		for end > 0 && !utf8.RuneStart(cef[end]) {
+			end--
+		}
+		cef = cef[:end]
+	}
+
+	record := cef
+	if !strings.HasPrefix(record, cefPrefix) {
+		skipped := false
+		if p.syslog {
+			sl, rest, err := splitSyslogAt(record, p.location, p.now())
+			if err == nil {
+				cefEvent.Syslog, record = sl, rest
+			} else if strings.HasPrefix(record, "<") {
+				warnings = append(warnings, newParseError(ErrInvalidFormat, ReasonSyslog, line, 0))
+				skipped = true
+			}
+		}
+		if !strings.HasPrefix(record, cefPrefix) {
+			start := strings.Index(record, cefPrefix)
+			if start < 0 || !skipped {
+				warnings = append(warnings, newParseError(ErrInvalidFormat, ReasonMissingPrefix, line, len(cef)-len(record)))
+			}
+			if start < 0 {
+				return p.finishLenient(cefEvent, "", nil), warnings
+			}
+			record = record[start:]
+		}
+	}
+	base := len(cef) - len(record)
+
+	header, n, extension := splitHeader(record)
+	if n < cefHeaderFields {
+		header[n] = unescapeHeaderValue(extension)
+		extension = ""
+		perr := newParseError(ErrInvalidFormat, ReasonTruncated, line, len(cef))
+		perr.Detail = fmt.Sprintf("header ends after %d of %d fields", n, cefHeaderFields)
+		warnings = append(warnings, perr)
+	}
+	checkHeader(header, p.validation, p.maxHeaderLength, func(perr *ParseError) bool {
+		// Fields missing from a truncated header are already reported.
+		if perr.Field <= n {
+			perr.Offset = base + headerFieldOffset(record, perr.Field)
+			perr.Snippet = snippet(line, perr.Offset)
+			warnings = append(warnings, perr)
+		}
+		return true
+	})
+
+	cefEvent.Version = header[0]
+	cefEvent.DeviceVendor = header[1]
+	cefEvent.DeviceProduct = header[2]
+	cefEvent.DeviceVersion = header[3]
+	cefEvent.SignatureID = header[4]
+	cefEvent.Name = header[5]
+	cefEvent.Severity = header[6]
+
+	cefEvent.Extensions = p.newExtensions(cefEvent.DeviceVendor, cefEvent.DeviceProduct, cefEvent.DeviceVersion)
+	var fields []extensionField
+	seen := make(map[string]bool)
+	warn := func(err error) {
+		var perr *ParseError
+		if errors.As(extensionError(err, cef, extension), &perr) {
+			warnings = append(warnings, perr)
+		}
+	}
+	_ = scanExtensions(extension, p.quotedValues(cefEvent.Extensions), func(field extensionField, escapeErr *ParseError) {
+		if p.maxExtensions > 0 && len(fields) == p.maxExtensions {
+			warn(p.tooManyExtensions(field))
+		}
+		if seen[field.Key] && p.duplicates != DuplicateCollect {
+			warn(duplicateKeyError(field))
+		}
+		seen[field.Key] = true
+		if err := p.checkExtensionField(field); err != nil {
+			warn(err)
+		}
+		if escapeErr != nil {
+			if escapeErr.Offset == len(extension)-1 {
+				// A lone backslash at the very end: the record was cut mid-escape.
+				escapeErr.Reason = ReasonTruncated
+				escapeErr.Detail = "record ends inside an escape sequence"
+			}
+			warn(escapeErr)
+		}
+		fields = append(fields, field)
+	})
+
+	p.finishLenient(cefEvent, extension, fields)
+	return cefEvent, append(warnings, p.foldLenient(cefEvent, line, len(cef)-len(extension))...)
+}
+
+// finishLenient attaches the extensions for the event's device to cefEvent,
+// creating them unless the header has already been read.
+func (p *Parser) finishLenient(cefEvent *CEF, extension string, fields []extensionField) *CEF {
+	policy := p.duplicates
+	if policy == DuplicateError {
+		policy = DuplicateLast
+	}
+	if cefEvent.Extensions == nil {
+		cefEvent.Extensions = p.newExtensions(cefEvent.DeviceVendor, cefEvent.DeviceProduct, cefEvent.DeviceVersion)
+	}
+	loadExtensions(cefEvent.Extensions, extension, fields, policy)
+	return cefEvent
+}
+
+// foldLenient folds the custom fields of cefEvent when label folding is
+// enabled, falling back to LabelCollisionSuffix on a collision.
+func (p *Parser) foldLenient(cefEvent *CEF, line string, offset int) Warnings {
+	if !p.foldLabels {
+		return nil
+	}
+	folded, err := FoldCustomFields(cefEvent.Extensions, p.labelCollision)
+	if err == nil {
+		cefEvent.CustomFields = folded
+		return nil
+	}
+	perr := newParseError(ErrInvalidExtension, ReasonLabelCollision, line, offset)
+	perr.Detail = err.Error()
+	cefEvent.CustomFields, _ = FoldCustomFields(cefEvent.Extensions, LabelCollisionSuffix)
+	return Warnings{perr}
+}
+
+// parseLenientContext is ParseContext in lenient mode: it returns the event
+// along with its warnings, if any, as the error.
+func (p *Parser) parseLenientContext(ctx context.Context, line string) (*CEF, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	cefEvent, warnings := p.ParseLenient(line)
+	if len(warnings) > 0 {
+		return cefEvent, warnings
+	}
+	return cefEvent, nil
+}
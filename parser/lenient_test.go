@@ -0,0 +1,160 @@
+// Tests for lenient parsing.
+package parser
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestParseLenient tests the events and warnings of lenient parsing.
+func TestParseLenient(t *testing.T) {
+	tests := []struct {
+		name       string
+		p          *Parser
+		cef        string
+		header     [cefHeaderFields]string
+		extensions map[string]string
+		reasons    []Reason
+	}{
+		{
+			"Valid", defaultParser, "CEF:0|V|P|1|2|N|3|src=1",
+			[7]string{"0", "V", "P", "1", "2", "N", "3"}, map[string]string{"src": "1"}, nil,
+		},
+		{
+			"Empty", defaultParser, "",
+			[7]string{}, map[string]string{}, []Reason{ReasonEmpty},
+		},
+		{
+			"Not CEF", defaultParser, "InvalidCEFString",
+			[7]string{}, map[string]string{}, []Reason{ReasonMissingPrefix},
+		},
+		{
+			"Leading garbage", NewParser(WithSyslog(false)), "garbage CEF:0|V|P|1|2|N|3|src=1",
+			[7]string{"0", "V", "P", "1", "2", "N", "3"}, map[string]string{"src": "1"}, []Reason{ReasonMissingPrefix},
+		},
+		{
+			"Invalid syslog", defaultParser, "<999>Oct 16 12:00:00 host CEF:0|V|P|1|2|N|3|src=1",
+			[7]string{"0", "V", "P", "1", "2", "N", "3"}, map[string]string{"src": "1"}, []Reason{ReasonSyslog},
+		},
+		{
+			"Truncated header", defaultParser, "CEF:0|V|P|1|2|Na",
+			[7]string{"0", "V", "P", "1", "2", "Na", ""}, map[string]string{}, []Reason{ReasonTruncated},
+		},
+		{
+			"Invalid header", defaultParser, "CEF:x|V|P|1||N|11|src=1",
+			[7]string{"x", "V", "P", "1", "", "N", "11"}, map[string]string{"src": "1"},
+			[]Reason{ReasonInvalidVersion, ReasonEmptyField, ReasonInvalidSeverity},
+		},
+		{
+			"Malformed escapes", defaultParser, `CEF:0|V|P|1|2|N|3|msg=a\qb dst=2\`,
+			[7]string{"0", "V", "P", "1", "2", "N", "3"}, map[string]string{"msg": `a\qb`, "dst": `2\`},
+			[]Reason{ReasonMalformedEscape, ReasonTruncated},
+		},
+		{
+			"Duplicate key", defaultParser, "CEF:0|V|P|1|2|N|3|src=1 src=2",
+			[7]string{"0", "V", "P", "1", "2", "N", "3"}, map[string]string{"src": "2"}, []Reason{ReasonDuplicateKey},
+		},
+		{
+			"Limits", NewParser(WithMaxExtensions(1), WithMaxValueLength(2)), "CEF:0|V|P|1|2|N|3|src=1 dst=123",
+			[7]string{"0", "V", "P", "1", "2", "N", "3"}, map[string]string{"src": "1", "dst": "123"},
+			[]Reason{ReasonTooManyExtensions, ReasonValueTooLong},
+		},
+		{
+			"Too long", NewParser(WithMaxLineLength(23)), "CEF:0|V|P|1|2|N|3|src=1234",
+			[7]string{"0", "V", "P", "1", "2", "N", "3"}, map[string]string{"src": "1"}, []Reason{ReasonTooLong},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cefEvent, warnings := test.p.ParseLenient(test.cef)
+			if cefEvent == nil {
+				t.Fatal("expected an event, got nil")
+			}
+			header := [cefHeaderFields]string{
+				cefEvent.Version, cefEvent.DeviceVendor, cefEvent.DeviceProduct, cefEvent.DeviceVersion,
+				cefEvent.SignatureID, cefEvent.Name, cefEvent.Severity,
+			}
+			if header != test.header {
+				t.Errorf("header = %q, want %q", header, test.header)
+			}
+			if fields := cefEvent.Extensions.(*DefaultExtensions).Fields; !reflect.DeepEqual(fields, test.extensions) {
+				t.Errorf("extensions = %v, want %v", fields, test.extensions)
+			}
+
+			var reasons []Reason
+			for _, warning := range warnings {
+				reasons = append(reasons, warning.Reason)
+				if warning.Offset < 0 || warning.Offset > len(test.cef) {
+					t.Errorf("warning %v has offset %d out of range", warning, warning.Offset)
+				}
+			}
+			if !reflect.DeepEqual(reasons, test.reasons) {
+				t.Errorf("reasons = %v, want %v", reasons, test.reasons)
+			}
+		})
+	}
+}
+
+// TestParseLenientLabelCollision tests the fallback for colliding labels.
+func TestParseLenientLabelCollision(t *testing.T) {
+	p := NewParser(WithLabelFolding(LabelCollisionError))
+	cefEvent, warnings := p.ParseLenient("CEF:0|V|P|1|2|N|3|cs1=a cs1Label=x cs2=b cs2Label=x")
+	if len(warnings) != 1 || warnings[0].Reason != ReasonLabelCollision {
+		t.Fatalf("unexpected warnings: %v", warnings)
+	}
+	if !reflect.DeepEqual(cefEvent.CustomFields, map[string]string{"x": "a", "x (cs2)": "b"}) {
+		t.Errorf("CustomFields = %v", cefEvent.CustomFields)
+	}
+}
+
+// TestParseLenientVendorValues tests that vendor samples are read with their
+// quoted and JSON values whole, as by Parse.
+func TestParseLenientVendorValues(t *testing.T) {
+	for _, line := range []string{ImpervaCEF1, ImpervaCEF2, CentrifyCEF} {
+		expected, err := ParseCEF(line)
+		if err != nil {
+			t.Fatalf("ParseCEF() error = %v", err)
+		}
+		cefEvent, warnings := defaultParser.ParseLenient(line)
+		if len(warnings) != 0 {
+			t.Errorf("unexpected warnings: %v", warnings)
+		}
+		if !reflect.DeepEqual(cefEvent, expected) {
+			t.Errorf("ParseLenient() = %v, want %v", cefEvent.Extensions, expected.Extensions)
+		}
+	}
+}
+
+// TestWithLenient tests Parse with the lenient option.
+func TestWithLenient(t *testing.T) {
+	p := NewParser(WithLenient(true))
+
+	cefEvent, err := p.Parse("CEF:0|V|P|1|2|N|3|src=1")
+	if err != nil || cefEvent == nil {
+		t.Fatalf("Parse() = %v, %v", cefEvent, err)
+	}
+
+	cefEvent, err = p.Parse("CEF:0|V|P|1|2|N|11|msg=a\\qb")
+	if cefEvent == nil || cefEvent.Severity != "11" {
+		t.Fatalf("expected a best-effort event, got %v", cefEvent)
+	}
+	var warnings Warnings
+	if !errors.As(err, &warnings) || len(warnings) != 2 {
+		t.Fatalf("expected two warnings, got %v", err)
+	}
+	if !errors.Is(err, ErrInvalidHeader) || !errors.Is(err, ErrInvalidExtension) {
+		t.Errorf("expected the warnings to match both sentinels: %v", err)
+	}
+	if err.Error() != `2 problems: one or more CEF components are invalid: invalid Severity "11": must be 0-10 or one of Unknown, Low, Medium, High, Very-High; invalid CEF extension: malformed escape sequence "\\q"` {
+		t.Errorf("unexpected message: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if cefEvent, err := p.ParseContext(ctx, "CEF:0|V|P|1|2|N|3|"); cefEvent != nil || !errors.Is(err, context.Canceled) {
+		t.Errorf("ParseContext() = %v, %v; want nil, context.Canceled", cefEvent, err)
+	}
+}
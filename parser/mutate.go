@@ -0,0 +1,248 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"strings"
+)
+
+// MutableExtensions is implemented by Extensions types that can be modified in
+// place, such as by enrichment stages. All built-in extension types implement
+// it.
+//
+// Keys are CEF extension keys. Struct-based types such as ImpervaExtensions
+// resolve keys through their `cef` tags, exactly first and then
+// case-insensitively, convert values the way BindFields does and keep keys
+// they have no field for as unbound string fields. Their map views (AsMap,
+// Format and the typed accessors) are derived from the struct fields, and all
+// but AsMap include the unbound fields, so they stay in sync.
+type MutableExtensions interface {
+	Extensions
+	// SetField sets the value of key, adding the field if it is absent.
+	SetField(key, value string) error
+	// DeleteField removes key and reports whether it was present.
+	DeleteField(key string) bool
+	// RenameField moves the value of oldKey to newKey, replacing any value
+	// newKey already has. It fails if oldKey is absent.
+	RenameField(oldKey, newKey string) error
+	// Clone returns a deep copy of the extensions.
+	Clone() Extensions
+}
+
+// Clone returns a deep copy of the CEF event. Extensions that do not implement
+// MutableExtensions are shared with the original.
+func (cef *CEF) Clone() *CEF {
+	clone := *cef
+	if ext, ok := cef.Extensions.(MutableExtensions); ok {
+		clone.Extensions = ext.Clone()
+	}
+	clone.CustomFields = maps.Clone(cef.CustomFields)
+	if cef.Syslog != nil {
+		clone.Syslog = deepCopy(reflect.ValueOf(cef.Syslog)).Interface().(*Syslog)
+	}
+	return &clone
+}
+
+// checkSetKey returns an error if key cannot be written as an extension key.
+func checkSetKey(key string) error {
+	if !isValidCEFKey(key, 0) {
+		return fmt.Errorf("invalid extension key %q", key)
+	}
+	return nil
+}
+
+// findBinding returns the binding of the struct type t for key, matching the
+// key and its aliases exactly first and then case-insensitively.
+func findBinding(t reflect.Type, key string) (fieldBinding, bool) {
+	bindings := structBindings(t)
+	for _, binding := range bindings {
+		for _, name := range binding.names {
+			if name == key {
+				return binding, true
+			}
+		}
+	}
+	for _, binding := range bindings {
+		for _, name := range binding.names {
+			if strings.EqualFold(name, key) {
+				return binding, true
+			}
+		}
+	}
+	return fieldBinding{}, false
+}
+
+// setStructField converts value and stores it in the field of the struct
+// pointed to by v that is bound to key, or among the unbound fields of v if it
+// keeps them. The field is left unchanged on error.
+func setStructField(v interface{}, key, value string) error {
+	rv := reflect.ValueOf(v).Elem()
+	binding, ok := findBinding(rv.Type(), key)
+	if !ok {
+		extra := unboundMap(v)
+		if extra == nil {
+			return fmt.Errorf("field %s not found", key)
+		}
+		if err := checkSetKey(key); err != nil {
+			return err
+		}
+		if *extra == nil {
+			*extra = make(map[string]string)
+		}
+		(*extra)[unboundKey(*extra, key)] = value
+		return nil
+	}
+	field := rv.FieldByIndex(binding.index)
+	converted := reflect.New(field.Type()).Elem()
+	if err := setFieldValue(converted, value, binding); err != nil {
+		return fmt.Errorf("field %s: %w", binding.display, err)
+	}
+	field.Set(converted)
+	return nil
+}
+
+// deleteStructField zeroes the field of the struct pointed to by v that is
+// bound to key, or removes it from the unbound fields of v, and reports
+// whether it was set.
+func deleteStructField(v interface{}, key string) bool {
+	rv := reflect.ValueOf(v).Elem()
+	binding, ok := findBinding(rv.Type(), key)
+	if !ok {
+		extra := unboundMap(v)
+		if extra == nil {
+			return false
+		}
+		k := unboundKey(*extra, key)
+		if _, ok := (*extra)[k]; !ok {
+			return false
+		}
+		delete(*extra, k)
+		return true
+	}
+	field := rv.FieldByIndex(binding.index)
+	if field.IsZero() {
+		return false
+	}
+	field.Set(reflect.Zero(field.Type()))
+	return true
+}
+
+// renameStructField moves the value bound to oldKey to the field bound to
+// newKey in the struct pointed to by v. Values are copied as they are between
+// fields of the same type and converted through their string form otherwise.
+// Keys without a field are read from and written to the unbound fields of v.
+func renameStructField(v interface{}, oldKey, newKey string) error {
+	rv := reflect.ValueOf(v).Elem()
+	from, fromBound := findBinding(rv.Type(), oldKey)
+	to, toBound := findBinding(rv.Type(), newKey)
+	if !fromBound || !toBound {
+		return renameUnboundField(v, oldKey, newKey)
+	}
+	if rv.FieldByIndex(from.index).IsZero() {
+		return fmt.Errorf("field %s not found", oldKey)
+	}
+	if reflect.DeepEqual(from.index, to.index) {
+		return nil
+	}
+
+	src, dst := rv.FieldByIndex(from.index), rv.FieldByIndex(to.index)
+	if src.Type() == dst.Type() {
+		dst.Set(src)
+	} else {
+		value, _ := stringifyField(src)
+		converted := reflect.New(dst.Type()).Elem()
+		if err := setFieldValue(converted, value, to); err != nil {
+			return fmt.Errorf("field %s: %w", to.display, err)
+		}
+		dst.Set(converted)
+	}
+	src.Set(reflect.Zero(src.Type()))
+	return nil
+}
+
+// renameUnboundField renames a field of the struct pointed to by v when
+// oldKey or newKey is unbound, going through the string form of the value.
+func renameUnboundField(v interface{}, oldKey, newKey string) error {
+	value, ok := extensionValue(v.(Extensions), oldKey)
+	if !ok {
+		return fmt.Errorf("field %s not found", oldKey)
+	}
+	if strings.EqualFold(oldKey, newKey) {
+		if extra := unboundMap(v); extra != nil {
+			delete(*extra, unboundKey(*extra, oldKey))
+		}
+		return setStructField(v, newKey, value)
+	}
+	if err := setStructField(v, newKey, value); err != nil {
+		return err
+	}
+	deleteStructField(v, oldKey)
+	return nil
+}
+
+// unboundKey returns the key of extra that matches key exactly or
+// case-insensitively, or key itself if there is none.
+func unboundKey(extra map[string]string, key string) string {
+	if _, ok := extra[key]; ok {
+		return key
+	}
+	for k := range extra {
+		if strings.EqualFold(k, key) {
+			return k
+		}
+	}
+	return key
+}
+
+// deepCopy returns a copy of v that shares no slices, maps or pointers with
+// it. Unexported struct fields are copied shallowly.
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(deepCopy(v.Elem()))
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopy(v.Elem()))
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				c.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+		return c
+	default:
+		return v
+	}
+}
@@ -0,0 +1,207 @@
+// Tests for the mutable extensions API.
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestMutableExtensions tests SetField, DeleteField and RenameField on every
+// built-in extension type.
+func TestMutableExtensions(t *testing.T) {
+	tests := []struct {
+		name string
+		ext  MutableExtensions
+	}{
+		{"Default", &DefaultExtensions{}},
+		{"Ordered", &OrderedExtensions{}},
+		{"Imperva", &ImpervaExtensions{}},
+		{"Centrify", &CentrifyExtensions{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ext := test.ext
+			if err := ext.SetField("src", "10.0.0.1"); err != nil {
+				t.Fatalf("SetField() error = %v", err)
+			}
+			if value, ok := extensionValue(ext, "src"); !ok || value != "10.0.0.1" {
+				t.Errorf("src = %q, %v after SetField", value, ok)
+			}
+			if err := ext.SetField("cs1", "rule"); err != nil {
+				t.Fatalf("SetField() error = %v", err)
+			}
+
+			if err := ext.RenameField("cs1", "cs2"); err != nil {
+				t.Fatalf("RenameField() error = %v", err)
+			}
+			if _, ok := extensionValue(ext, "cs1"); ok {
+				t.Errorf("cs1 still set after RenameField")
+			}
+			if value, ok := extensionValue(ext, "cs2"); !ok || value != "rule" {
+				t.Errorf("cs2 = %q, %v after RenameField", value, ok)
+			}
+			if err := ext.RenameField("cs1", "cs3"); err == nil {
+				t.Errorf("expected error renaming a missing field")
+			}
+
+			if !ext.DeleteField("src") || ext.DeleteField("src") {
+				t.Errorf("DeleteField() should report the field once")
+			}
+			if _, ok := extensionValue(ext, "src"); ok {
+				t.Errorf("src still set after DeleteField")
+			}
+
+			line, err := Format(&CEF{Version: "0", Extensions: ext})
+			if err != nil || line != "CEF:0|||||||cs2=rule" {
+				t.Errorf("Format() = %q, %v", line, err)
+			}
+		})
+	}
+}
+
+// TestMutableStructExtensions tests conversions and errors on struct types.
+func TestMutableStructExtensions(t *testing.T) {
+	ie := &ImpervaExtensions{}
+	if err := ie.SetField("xff", "1.1.1.1, 2.2.2.2"); err != nil || !reflect.DeepEqual(ie.XFF, []string{"1.1.1.1", "2.2.2.2"}) {
+		t.Errorf("SetField(xff) = %v, XFF = %v", err, ie.XFF)
+	}
+	if err := ie.SetField("CS10", `[{"rule_id":"1"}]`); err != nil || !reflect.DeepEqual(ie.CS10, []interface{}{map[string]interface{}{"rule_id": "1"}}) {
+		t.Errorf("SetField(CS10) = %v, CS10 = %v", err, ie.CS10)
+	}
+	if err := ie.SetField("fileid", "42"); err != nil || ie.FileID != "42" {
+		t.Errorf("SetField(fileid) = %v, FileID = %q", err, ie.FileID)
+	}
+	if err := ie.SetField("bad key", "x"); err == nil {
+		t.Errorf("expected error for an invalid key")
+	}
+	if err := ie.RenameField("xff", "request"); err != nil || ie.Request != "1.1.1.1, 2.2.2.2" || ie.XFF != nil {
+		t.Errorf("RenameField() = %v, Request = %q, XFF = %v", err, ie.Request, ie.XFF)
+	}
+	if err := ie.RenameField("request", "bad key"); err == nil || ie.Request == "" {
+		t.Errorf("expected a failed rename to keep the value")
+	}
+
+	var ce CentrifyExtensions
+	if err := ce.SetField("dhost", "host1"); err != nil || ce.DHost != "host1" {
+		t.Errorf("SetField(dhost) = %v, DHost = %q", err, ce.DHost)
+	}
+}
+
+// TestMutableStructExtensionsUnbound tests that struct types keep keys they
+// have no field for.
+func TestMutableStructExtensionsUnbound(t *testing.T) {
+	cefEvent, err := ParseCEF("CEF:0|Incapsula|SIEMintegration|0|1|N|5|act=blocked tag=waf cicode=IL siteTag=shop")
+	if err != nil {
+		t.Fatalf("ParseCEF() error = %v", err)
+	}
+	ie := cefEvent.Extensions.(*ImpervaExtensions)
+	if value, err := Field[string](ie, "siteTag"); err != nil || value != "shop" {
+		t.Errorf("Field(siteTag) = %q, %v", value, err)
+	}
+
+	if err := ie.SetField("Tag", "cdn"); err != nil {
+		t.Fatalf("SetField(Tag) error = %v", err)
+	}
+	if err := ie.SetField("newKey", "1"); err != nil {
+		t.Fatalf("SetField(newKey) error = %v", err)
+	}
+	if !ie.DeleteField("cicode") || ie.DeleteField("cicode") {
+		t.Errorf("DeleteField(cicode) should report the field once")
+	}
+	if err := ie.RenameField("siteTag", "request"); err != nil || ie.Request != "shop" {
+		t.Errorf("RenameField(siteTag) = %v, Request = %q", err, ie.Request)
+	}
+	if err := ie.RenameField("act", "action"); err != nil || ie.Act != "" {
+		t.Errorf("RenameField(act) = %v, Act = %q", err, ie.Act)
+	}
+
+	clone := ie.Clone().(*ImpervaExtensions)
+	_ = clone.SetField("tag", "changed")
+
+	fields := extensionFields(ie)
+	expected := map[string]string{"tag": "cdn", "newKey": "1", "action": "blocked", "request": "shop"}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("extension fields = %v, want %v", fields, expected)
+	}
+	asMap := ie.AsMap()
+	if _, ok := asMap["tag"]; ok || asMap["request"] != "shop" {
+		t.Errorf("AsMap() = %v, want the string struct fields only", asMap)
+	}
+
+	line, err := Format(cefEvent)
+	if err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+	if want := "request=shop action=blocked newKey=1 tag=cdn"; !strings.HasSuffix(line, "|"+want) {
+		t.Errorf("Format() = %s, want extensions %s", line, want)
+	}
+}
+
+// TestMutableExtensionsInvalidKey tests that map-based types reject invalid keys.
+func TestMutableExtensionsInvalidKey(t *testing.T) {
+	for _, ext := range []MutableExtensions{&DefaultExtensions{}, &OrderedExtensions{}} {
+		if err := ext.SetField("bad key", "x"); err == nil {
+			t.Errorf("%T: expected error for an invalid key", ext)
+		}
+	}
+}
+
+// TestOrderedExtensionsMutation tests how ordered extensions handle repeated keys.
+func TestOrderedExtensionsMutation(t *testing.T) {
+	oe := &OrderedExtensions{Fields: []KeyValue{{"act", "a"}, {"src", "1"}, {"act", "b"}, {"dst", "2"}}}
+	if err := oe.SetField("act", "c"); err != nil {
+		t.Fatalf("SetField() error = %v", err)
+	}
+	if want := []KeyValue{{"act", "c"}, {"src", "1"}, {"dst", "2"}}; !reflect.DeepEqual(oe.Fields, want) {
+		t.Errorf("Fields = %v, want %v", oe.Fields, want)
+	}
+	if err := oe.RenameField("src", "dst"); err != nil {
+		t.Fatalf("RenameField() error = %v", err)
+	}
+	if want := []KeyValue{{"act", "c"}, {"dst", "1"}}; !reflect.DeepEqual(oe.Fields, want) {
+		t.Errorf("Fields = %v, want %v", oe.Fields, want)
+	}
+}
+
+// TestClone tests that clones share no mutable state with the original.
+func TestClone(t *testing.T) {
+	for _, line := range []string{ImpervaCEFCombined, CentrifyCEF, "<134>1 2024-10-16T12:00:00Z host app - - [a@1 k=\"v\"] CEF:0|V|P|1|2|N|3|src=1"} {
+		original, err := NewParser(WithLabelFolding(LabelCollisionSuffix)).Parse(line)
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		snapshot, _ := ParseCEF(line)
+
+		clone := original.Clone()
+		if !reflect.DeepEqual(clone, original) {
+			t.Fatalf("Clone() = %v, want %v", clone, original)
+		}
+
+		ext := clone.Extensions.(MutableExtensions)
+		for _, key := range ext.GetFieldNames() {
+			ext.DeleteField(key)
+		}
+		if ie, ok := clone.Extensions.(*ImpervaExtensions); ok {
+			ie.CS10 = nil
+			orig := original.Extensions.(*ImpervaExtensions)
+			orig.AdditionalReqHeaders.([]interface{})[0] = "changed"
+			if reflect.DeepEqual(ie.AdditionalReqHeaders, orig.AdditionalReqHeaders) {
+				t.Errorf("clone shares decoded JSON with the original")
+			}
+			orig.AdditionalReqHeaders = snapshot.Extensions.(*ImpervaExtensions).AdditionalReqHeaders
+		}
+		for k := range clone.CustomFields {
+			clone.CustomFields[k] = "changed"
+		}
+		if clone.Syslog != nil {
+			clone.Syslog.StructuredData["a@1"]["k"] = "changed"
+		}
+
+		original.CustomFields = nil
+		if !reflect.DeepEqual(original, snapshot) {
+			t.Errorf("modifying the clone changed the original: %v", original)
+		}
+	}
+}
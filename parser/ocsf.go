@@ -0,0 +1,530 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"encoding/json"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OCSFEvent is an event of one of the OCSF classes produced by ToOCSF. Only the
+// attributes of its class are set; see ValidateOCSF.
+type OCSFEvent struct {
+	ActivityID   int    `json:"activity_id"`
+	ActivityName string `json:"activity_name,omitempty"`
+	CategoryUID  int    `json:"category_uid"`
+	CategoryName string `json:"category_name,omitempty"`
+	ClassUID     int    `json:"class_uid"`
+	ClassName    string `json:"class_name,omitempty"`
+	TypeUID      int    `json:"type_uid"`
+	TypeName     string `json:"type_name,omitempty"`
+	// Time is the time of the event in milliseconds since the Unix epoch.
+	Time       int64        `json:"time"`
+	StartTime  int64        `json:"start_time,omitempty"`
+	EndTime    int64        `json:"end_time,omitempty"`
+	Count      int          `json:"count,omitempty"`
+	SeverityID int          `json:"severity_id"`
+	Severity   string       `json:"severity,omitempty"`
+	StatusID   int          `json:"status_id,omitempty"`
+	Status     string       `json:"status,omitempty"`
+	Message    string       `json:"message,omitempty"`
+	Metadata   OCSFMetadata `json:"metadata"`
+
+	// Security Finding
+	Finding *OCSFFinding `json:"finding,omitempty"`
+	StateID int          `json:"state_id,omitempty"`
+	State   string       `json:"state,omitempty"`
+
+	// Authentication
+	User    *OCSFUser    `json:"user,omitempty"`
+	Service *OCSFService `json:"service,omitempty"`
+
+	// Authentication and HTTP Activity
+	SrcEndpoint *OCSFEndpoint `json:"src_endpoint,omitempty"`
+	DstEndpoint *OCSFEndpoint `json:"dst_endpoint,omitempty"`
+
+	// HTTP Activity
+	HTTPRequest  *OCSFHTTPRequest  `json:"http_request,omitempty"`
+	HTTPResponse *OCSFHTTPResponse `json:"http_response,omitempty"`
+	TLS          *OCSFTLS          `json:"tls,omitempty"`
+
+	// Unmapped holds the CEF extension fields without an OCSF attribute.
+	Unmapped map[string]string `json:"unmapped,omitempty"`
+}
+
+// OCSFMetadata is the OCSF metadata object.
+type OCSFMetadata struct {
+	Version string      `json:"version"`
+	Product OCSFProduct `json:"product"`
+	UID     string      `json:"uid,omitempty"`
+}
+
+// OCSFProduct is the OCSF product object, filled from the CEF device fields.
+type OCSFProduct struct {
+	Name       string `json:"name,omitempty"`
+	VendorName string `json:"vendor_name"`
+	Version    string `json:"version,omitempty"`
+}
+
+// OCSFFinding is the OCSF finding object, filled from the CEF signature and name.
+type OCSFFinding struct {
+	UID   string `json:"uid"`
+	Title string `json:"title"`
+	Desc  string `json:"desc,omitempty"`
+}
+
+// OCSFUser is the OCSF user object.
+type OCSFUser struct {
+	Name string `json:"name,omitempty"`
+	UID  string `json:"uid,omitempty"`
+}
+
+// OCSFService is the OCSF service object.
+type OCSFService struct {
+	Name string `json:"name,omitempty"`
+	UID  string `json:"uid,omitempty"`
+}
+
+// OCSFEndpoint is the OCSF network endpoint object.
+type OCSFEndpoint struct {
+	IP       string        `json:"ip,omitempty"`
+	Port     int           `json:"port,omitempty"`
+	Hostname string        `json:"hostname,omitempty"`
+	Location *OCSFLocation `json:"location,omitempty"`
+}
+
+// OCSFLocation is the OCSF geographical location object. Coordinates holds the
+// longitude and latitude, in that order.
+type OCSFLocation struct {
+	Country     string    `json:"country,omitempty"`
+	City        string    `json:"city,omitempty"`
+	Coordinates []float64 `json:"coordinates,omitempty"`
+}
+
+// OCSFHTTPRequest is the OCSF HTTP request object.
+type OCSFHTTPRequest struct {
+	HTTPMethod    string   `json:"http_method,omitempty"`
+	URL           *OCSFURL `json:"url,omitempty"`
+	UserAgent     string   `json:"user_agent,omitempty"`
+	Referrer      string   `json:"referrer,omitempty"`
+	XForwardedFor []string `json:"x_forwarded_for,omitempty"`
+}
+
+// OCSFURL is the OCSF uniform resource locator object.
+type OCSFURL struct {
+	URLString   string `json:"url_string,omitempty"`
+	Hostname    string `json:"hostname,omitempty"`
+	Path        string `json:"path,omitempty"`
+	QueryString string `json:"query_string,omitempty"`
+}
+
+// OCSFHTTPResponse is the OCSF HTTP response object.
+type OCSFHTTPResponse struct {
+	Code   int `json:"code"`
+	Length int `json:"length,omitempty"`
+}
+
+// OCSFTLS is the OCSF transport layer security object.
+type OCSFTLS struct {
+	Version string `json:"version"`
+	Cipher  string `json:"cipher,omitempty"`
+}
+
+// Validate checks the JSON encoding of the event with ValidateOCSF.
+func (e *OCSFEvent) Validate() error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return ValidateOCSF(data)
+}
+
+// UnmappedFields returns the sorted keys of the CEF extension fields without an
+// OCSF attribute.
+func (e *OCSFEvent) UnmappedFields() []string {
+	keys := make([]string, 0, len(e.Unmapped))
+	for key := range e.Unmapped {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ToOCSF maps the CEF event to an OCSF event of the class that fits it:
+//   - HTTP Activity for Imperva Cloud WAF events, with the activity given by
+//     the request method
+//   - Authentication for Centrify login, logout and application launch events
+//   - Security Finding for other events of Medium severity or higher, with the
+//     signature ID and name as the finding
+//   - Base Event for everything else
+//
+// The time of the event is taken from rt, then the syslog envelope, then the
+// parser's clock. Extension fields that have no attribute in the class are
+// kept in Unmapped; see UnmappedFields. The result passes ValidateOCSF when
+// the event names its device vendor.
+func ToOCSF(cef *CEF) *OCSFEvent {
+	m := newOCSFMapper(cef)
+	switch {
+	case cef.DeviceVendor == "Incapsula" && cef.DeviceProduct == "SIEMintegration":
+		m.mapImperva()
+	case cef.DeviceVendor == "Centrify" && centrifyAuthActivity(cef.Name) != 0:
+		m.mapCentrify()
+	case m.event.SeverityID >= 3:
+		m.mapFinding()
+	default:
+		m.setClass(OCSFClassBaseEvent, 0)
+	}
+	m.finish()
+	return m.event
+}
+
+// ToOCSF maps the CEF event to an OCSF event. See ToOCSF.
+func (cef *CEF) ToOCSF() *OCSFEvent {
+	return ToOCSF(cef)
+}
+
+// ocsfMapper builds an OCSFEvent from a CEF event, tracking which extension
+// fields have been mapped.
+type ocsfMapper struct {
+	cef    *CEF
+	event  *OCSFEvent
+	fields map[string]string
+	used   map[string]bool
+}
+
+// newOCSFMapper returns a mapper with the attributes common to every class set
+// from the CEF header.
+func newOCSFMapper(cef *CEF) *ocsfMapper {
+	m := &ocsfMapper{
+		cef:    cef,
+		fields: extensionFields(cef.Extensions),
+		used:   make(map[string]bool),
+		event: &OCSFEvent{Metadata: OCSFMetadata{
+			Version: OCSFVersion,
+			Product: OCSFProduct{Name: cef.DeviceProduct, VendorName: cef.DeviceVendor, Version: cef.DeviceVersion},
+		}},
+	}
+	m.event.SeverityID = ocsfSeverityID(cef.Severity)
+	return m
+}
+
+// lookup returns the key of fields matching key, exactly first and then
+// case-insensitively, and its value. Empty values are reported as absent.
+func (m *ocsfMapper) lookup(key string) (string, string, bool) {
+	if _, ok := m.fields[key]; !ok {
+		found := false
+		for k := range m.fields {
+			if strings.EqualFold(k, key) {
+				key, found = k, true
+				break
+			}
+		}
+		if !found {
+			return "", "", false
+		}
+	}
+	value := m.fields[key]
+	return key, value, strings.TrimSpace(value) != ""
+}
+
+// take returns the value of the extension key and marks it as mapped.
+func (m *ocsfMapper) take(key string) (string, bool) {
+	actual, value, ok := m.lookup(key)
+	if ok {
+		m.used[actual] = true
+	}
+	return value, ok
+}
+
+// takeInt returns the integer value of the extension key. Values that are not
+// integers are left unmapped.
+func (m *ocsfMapper) takeInt(key string) (int, bool) {
+	actual, value, ok := m.lookup(key)
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, false
+	}
+	m.used[actual] = true
+	return n, true
+}
+
+// takeIP returns the address value of the extension key. Values that are not
+// addresses are left unmapped.
+func (m *ocsfMapper) takeIP(key string) (string, bool) {
+	actual, value, ok := m.lookup(key)
+	if !ok {
+		return "", false
+	}
+	addr, err := netip.ParseAddr(strings.TrimSpace(value))
+	if err != nil {
+		return "", false
+	}
+	m.used[actual] = true
+	return addr.String(), true
+}
+
+// takeTime returns the timestamp value of the extension key in milliseconds.
+// Values that are not timestamps are left unmapped.
+func (m *ocsfMapper) takeTime(key string) (int64, bool) {
+	actual, value, ok := m.lookup(key)
+	if !ok {
+		return 0, false
+	}
+	ts, err := m.cef.parseTime(key, value)
+	if err != nil {
+		return 0, false
+	}
+	m.used[actual] = true
+	return ts.UnixMilli(), true
+}
+
+// takeFloat returns the floating point value of the extension key. Values that
+// are not numbers are left unmapped.
+func (m *ocsfMapper) takeFloat(key string) (float64, bool) {
+	actual, value, ok := m.lookup(key)
+	if !ok {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0, false
+	}
+	m.used[actual] = true
+	return f, true
+}
+
+// setClass sets the class, category, activity and type of the event.
+func (m *ocsfMapper) setClass(classUID, activityID int) {
+	class := ocsfClasses[classUID]
+	e := m.event
+	e.ClassUID, e.ClassName = classUID, class.name
+	e.CategoryUID, e.CategoryName = class.categoryUID, class.categoryName
+	e.ActivityID, e.ActivityName = activityID, class.activities[activityID]
+	e.TypeUID = classUID*100 + activityID
+	e.TypeName = class.name + ": " + e.ActivityName
+}
+
+// finish sets the attributes common to every class from the remaining fields
+// and collects the unmapped ones.
+func (m *ocsfMapper) finish() {
+	e, cef := m.event, m.cef
+	e.Severity = ocsfSeverities[e.SeverityID]
+	if e.StatusID != 0 {
+		e.Status = ocsfStatuses[e.StatusID]
+	}
+
+	if t, ok := m.takeTime("rt"); ok {
+		e.Time = t
+	} else if cef.Syslog != nil && !cef.Syslog.Timestamp.IsZero() {
+		e.Time = cef.Syslog.Timestamp.UnixMilli()
+	} else if cef.times != nil && cef.times.clock != nil {
+		e.Time = cef.times.clock().UnixMilli()
+	} else {
+		e.Time = time.Now().UnixMilli()
+	}
+	e.StartTime, _ = m.takeTime("start")
+	e.EndTime, _ = m.takeTime("end")
+	e.Count, _ = m.takeInt("cnt")
+	if e.Message == "" {
+		if msg, ok := m.take("msg"); ok {
+			e.Message = msg
+		} else {
+			e.Message = cef.Name
+		}
+	}
+	if e.Metadata.UID == "" {
+		e.Metadata.UID, _ = m.take("externalId")
+	}
+
+	for key, value := range m.fields {
+		if !m.used[key] && value != "" {
+			if e.Unmapped == nil {
+				e.Unmapped = make(map[string]string)
+			}
+			e.Unmapped[key] = value
+		}
+	}
+}
+
+// mapFinding maps the event to a new Security Finding.
+func (m *ocsfMapper) mapFinding() {
+	m.setClass(OCSFClassSecurityFinding, 1)
+	e := m.event
+	e.Finding = &OCSFFinding{UID: m.cef.SignatureID, Title: m.cef.Name}
+	e.Finding.Desc, _ = m.take("msg")
+	e.StateID, e.State = 1, "New"
+}
+
+// mapImperva maps an Imperva Cloud WAF event to HTTP Activity. In these events
+// src and cpt describe the client, sip and spt the protected server, and cs7
+// and cs8 the client's latitude and longitude.
+func (m *ocsfMapper) mapImperva() {
+	e := m.event
+	method, _ := m.take("requestMethod")
+	m.setClass(OCSFClassHTTPActivity, ocsfHTTPActivity(method))
+
+	src := &OCSFEndpoint{}
+	src.IP, _ = m.takeIP("src")
+	src.Port, _ = m.takeInt("cpt")
+	location := &OCSFLocation{}
+	location.Country, _ = m.take("ccode")
+	location.City, _ = m.take("cicode")
+	if latitude, ok := m.takeFloat("cs7"); ok {
+		if longitude, ok := m.takeFloat("cs8"); ok {
+			location.Coordinates = []float64{longitude, latitude}
+			m.take("cs7Label")
+			m.take("cs8Label")
+		}
+	}
+	if location.Country != "" || location.City != "" || location.Coordinates != nil {
+		src.Location = location
+	}
+	if *src != (OCSFEndpoint{}) {
+		e.SrcEndpoint = src
+	}
+
+	host, _ := m.take("sourceServiceName")
+	dst := &OCSFEndpoint{Hostname: host}
+	dst.IP, _ = m.takeIP("sip")
+	dst.Port, _ = m.takeInt("spt")
+	if *dst != (OCSFEndpoint{}) {
+		e.DstEndpoint = dst
+	}
+
+	request := &OCSFHTTPRequest{HTTPMethod: method}
+	if rawURL, ok := m.take("request"); ok {
+		url := &OCSFURL{URLString: rawURL, Hostname: host}
+		_, rest, found := strings.Cut(rawURL, "://")
+		if !found {
+			rest = rawURL
+		}
+		if i := strings.IndexAny(rest, "/?"); i >= 0 && rest[i] == '/' {
+			url.Path, _, _ = strings.Cut(rest[i:], "?")
+		}
+		url.QueryString, _ = m.take("qstr")
+		request.URL = url
+	}
+	request.UserAgent, _ = m.take("requestClientApplication")
+	request.Referrer, _ = m.take("ref")
+	if xff, ok := m.take("xff"); ok {
+		for _, addr := range strings.Split(xff, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				request.XForwardedFor = append(request.XForwardedFor, addr)
+			}
+		}
+	}
+	e.HTTPRequest = request
+
+	if code, ok := m.takeInt("cn1"); ok {
+		e.HTTPResponse = &OCSFHTTPResponse{Code: code}
+		e.HTTPResponse.Length, _ = m.takeInt("in")
+	}
+	// ver holds the protocol and cipher, e.g. "TLSv1.3 TLS_AES_128_GCM_SHA256".
+	if _, ver, ok := m.lookup("ver"); ok {
+		protocol, cipher, _ := strings.Cut(strings.TrimSpace(ver), " ")
+		if version, ok := strings.CutPrefix(protocol, "TLSv"); ok {
+			e.TLS = &OCSFTLS{Version: version, Cipher: strings.TrimSpace(cipher)}
+			m.take("ver")
+		}
+	}
+	e.Metadata.UID, _ = m.take("deviceExternalId")
+}
+
+// mapCentrify maps a Centrify login, logout or application launch event to
+// Authentication. In these events duser is the authenticating user.
+func (m *ocsfMapper) mapCentrify() {
+	e := m.event
+	m.setClass(OCSFClassAuthentication, centrifyAuthActivity(m.cef.Name))
+	e.StatusID = 1
+	if strings.Contains(strings.ToLower(m.cef.Name), "fail") {
+		e.StatusID = 2
+	}
+
+	user := &OCSFUser{}
+	user.Name, _ = m.take("duser")
+	user.UID, _ = m.take("suid")
+	e.User = user
+
+	src := &OCSFEndpoint{}
+	src.IP, _ = m.takeIP("src")
+	src.Hostname, _ = m.take("shost")
+	if *src != (OCSFEndpoint{}) {
+		e.SrcEndpoint = src
+	}
+	if host, ok := m.take("dhost"); ok {
+		e.DstEndpoint = &OCSFEndpoint{Hostname: host}
+	}
+	if name, ok := m.take("destinationServiceName"); ok {
+		e.Service = &OCSFService{Name: name}
+	}
+}
+
+// centrifyAuthActivity returns the Authentication activity_id for the Centrify
+// event type name, or 0 if it is not an authentication event. Application
+// launches are single sign-on authentications that fit none of the OCSF
+// activities and map to Other.
+func centrifyAuthActivity(name string) int {
+	name = strings.ToLower(name)
+	switch {
+	case strings.Contains(name, "logout"), strings.Contains(name, "logoff"):
+		return 2
+	case strings.Contains(name, "login"), strings.Contains(name, "logon"):
+		return 1
+	case strings.Contains(name, "applaunch"):
+		return 99
+	}
+	return 0
+}
+
+// ocsfHTTPActivity returns the HTTP Activity activity_id for a request method.
+func ocsfHTTPActivity(method string) int {
+	switch strings.ToUpper(strings.TrimSpace(method)) {
+	case "CONNECT":
+		return 1
+	case "DELETE":
+		return 2
+	case "GET":
+		return 3
+	case "HEAD":
+		return 4
+	case "OPTIONS":
+		return 5
+	case "POST":
+		return 6
+	case "PUT":
+		return 7
+	case "TRACE":
+		return 8
+	case "":
+		return 0
+	}
+	return 99
+}
+
+// ocsfSeverityID converts a numeric or textual CEF severity to an OCSF
+// severity_id: 0 is Informational, 1-3 Low, 4-6 Medium, 7-8 High and 9-10
+// Critical.
+func ocsfSeverityID(severity string) int {
+	n, ok := ecsSeverity(severity)
+	switch {
+	case !ok || n < 0 || n > 10:
+		return 0
+	case strings.EqualFold(severity, "unknown"):
+		return 0
+	case n == 0:
+		return 1
+	case n <= 3:
+		return 2
+	case n <= 6:
+		return 3
+	case n <= 8:
+		return 4
+	}
+	return 5
+}
@@ -0,0 +1,193 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// OCSFVersion is the version of the Open Cybersecurity Schema Framework that
+// the events produced by ToOCSF and checked by ValidateOCSF conform to.
+const OCSFVersion = "1.1.0"
+
+// OCSF class identifiers of the classes produced by ToOCSF.
+const (
+	OCSFClassBaseEvent       = 0
+	OCSFClassSecurityFinding = 2001
+	OCSFClassAuthentication  = 3002
+	OCSFClassHTTPActivity    = 4002
+)
+
+// ocsfClass describes an OCSF event class in the bundled schema.
+type ocsfClass struct {
+	name         string
+	categoryUID  int
+	categoryName string
+	activities   map[int]string
+	// attributes lists the class attributes beyond ocsfBaseAttributes.
+	attributes []string
+	// required lists the class attributes beyond ocsfBaseRequired that must be
+	// present.
+	required []string
+}
+
+// ocsfBaseAttributes lists the attributes every OCSF event class has.
+var ocsfBaseAttributes = []string{
+	"activity_id", "activity_name", "category_name", "category_uid", "class_name", "class_uid",
+	"count", "duration", "end_time", "enrichments", "message", "metadata", "observables",
+	"raw_data", "severity", "severity_id", "start_time", "status", "status_code",
+	"status_detail", "status_id", "time", "timezone_offset", "type_name", "type_uid", "unmapped",
+}
+
+// ocsfBaseRequired lists the attributes every OCSF event must have.
+var ocsfBaseRequired = []string{
+	"activity_id", "category_uid", "class_uid", "metadata", "severity_id", "time", "type_uid",
+}
+
+// ocsfClasses is the bundled subset of the OCSF schema, keyed by class_uid.
+var ocsfClasses = map[int]ocsfClass{
+	OCSFClassBaseEvent: {
+		name:         "Base Event",
+		categoryName: "Uncategorized",
+		activities:   map[int]string{0: "Unknown", 99: "Other"},
+	},
+	OCSFClassSecurityFinding: {
+		name:         "Security Finding",
+		categoryUID:  2,
+		categoryName: "Findings",
+		activities:   map[int]string{0: "Unknown", 1: "Create", 2: "Update", 3: "Close", 99: "Other"},
+		attributes: []string{
+			"analytic", "attacks", "cis_csc", "compliance", "confidence", "confidence_id",
+			"data_sources", "evidence", "finding", "impact", "impact_id", "impact_score",
+			"kill_chain", "malware", "nist", "process", "resources", "risk_level", "risk_level_id",
+			"risk_score", "state", "state_id", "vulnerabilities",
+		},
+		required: []string{"finding", "state_id"},
+	},
+	OCSFClassAuthentication: {
+		name:         "Authentication",
+		categoryUID:  3,
+		categoryName: "Identity & Access Management",
+		activities: map[int]string{
+			0: "Unknown", 1: "Logon", 2: "Logoff", 3: "Authentication Ticket",
+			4: "Service Ticket Request", 5: "Service Ticket Renew", 6: "Preauth", 99: "Other",
+		},
+		attributes: []string{
+			"actor", "auth_protocol", "auth_protocol_id", "certificate", "dst_endpoint",
+			"is_cleartext", "is_mfa", "is_new_logon", "is_remote", "logon_process", "logon_type",
+			"logon_type_id", "service", "session", "src_endpoint", "user",
+		},
+		required: []string{"user"},
+	},
+	OCSFClassHTTPActivity: {
+		name:         "HTTP Activity",
+		categoryUID:  4,
+		categoryName: "Network Activity",
+		activities: map[int]string{
+			0: "Unknown", 1: "Connect", 2: "Delete", 3: "Get", 4: "Head", 5: "Options",
+			6: "Post", 7: "Put", 8: "Trace", 99: "Other",
+		},
+		attributes: []string{
+			"app_name", "connection_info", "dst_endpoint", "file", "http_cookies", "http_request",
+			"http_response", "http_status", "proxy", "src_endpoint", "tls", "traffic",
+		},
+	},
+}
+
+// ocsfSeverities holds the OCSF severity names by severity_id.
+var ocsfSeverities = map[int]string{
+	0: "Unknown", 1: "Informational", 2: "Low", 3: "Medium", 4: "High", 5: "Critical", 6: "Fatal", 99: "Other",
+}
+
+// ocsfStatuses holds the OCSF status names by status_id.
+var ocsfStatuses = map[int]string{0: "Unknown", 1: "Success", 2: "Failure", 99: "Other"}
+
+// ValidateOCSF checks an OCSF event encoded as JSON against the bundled schema
+// for the classes produced by ToOCSF: the class must be known, the required
+// attributes present, no attributes outside the class present, the category,
+// activity, type and severity identifiers consistent, and the metadata must
+// name the schema version and the product vendor. All problems are reported.
+func ValidateOCSF(data []byte) error {
+	var event map[string]interface{}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return fmt.Errorf("invalid OCSF event: %w", err)
+	}
+
+	classUID, ok := ocsfInt(event, "class_uid")
+	if !ok {
+		return errors.New("missing required attribute class_uid")
+	}
+	class, ok := ocsfClasses[classUID]
+	if !ok {
+		return fmt.Errorf("unknown class_uid %d", classUID)
+	}
+
+	var errs []error
+	for _, name := range append(append([]string(nil), ocsfBaseRequired...), class.required...) {
+		if _, ok := event[name]; !ok {
+			errs = append(errs, fmt.Errorf("missing required attribute %s", name))
+		}
+	}
+
+	allowed := make(map[string]bool, len(ocsfBaseAttributes)+len(class.attributes))
+	for _, name := range append(append([]string(nil), ocsfBaseAttributes...), class.attributes...) {
+		allowed[name] = true
+	}
+	var unknown []string
+	for name := range event {
+		if !allowed[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		errs = append(errs, fmt.Errorf("attribute %s is not defined for %s", name, class.name))
+	}
+
+	if categoryUID, ok := ocsfInt(event, "category_uid"); ok && categoryUID != class.categoryUID {
+		errs = append(errs, fmt.Errorf("category_uid %d does not match %s", categoryUID, class.name))
+	}
+	activityID, ok := ocsfInt(event, "activity_id")
+	if _, known := class.activities[activityID]; ok && !known {
+		errs = append(errs, fmt.Errorf("activity_id %d is not defined for %s", activityID, class.name))
+	}
+	if typeUID, ok := ocsfInt(event, "type_uid"); ok && typeUID != classUID*100+activityID {
+		errs = append(errs, fmt.Errorf("type_uid %d does not match class_uid %d and activity_id %d", typeUID, classUID, activityID))
+	}
+	if severityID, ok := ocsfInt(event, "severity_id"); ok && ocsfSeverities[severityID] == "" {
+		errs = append(errs, fmt.Errorf("severity_id %d is not defined", severityID))
+	}
+	if statusID, ok := ocsfInt(event, "status_id"); ok && ocsfStatuses[statusID] == "" {
+		errs = append(errs, fmt.Errorf("status_id %d is not defined", statusID))
+	}
+	if t, ok := ocsfInt(event, "time"); !ok || t <= 0 {
+		if _, present := event["time"]; present {
+			errs = append(errs, errors.New("time must be a positive number of milliseconds"))
+		}
+	}
+
+	if metadata, ok := event["metadata"].(map[string]interface{}); ok {
+		if metadata["version"] != OCSFVersion {
+			errs = append(errs, fmt.Errorf("metadata.version must be %q", OCSFVersion))
+		}
+		product, _ := metadata["product"].(map[string]interface{})
+		if name, _ := product["vendor_name"].(string); name == "" {
+			errs = append(errs, errors.New("missing required attribute metadata.product.vendor_name"))
+		}
+	} else if _, present := event["metadata"]; present {
+		errs = append(errs, errors.New("metadata must be an object"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// ocsfInt returns the integer attribute name of a decoded OCSF event.
+func ocsfInt(event map[string]interface{}, name string) (int, bool) {
+	n, ok := event[name].(float64)
+	if !ok || n != float64(int(n)) {
+		return 0, false
+	}
+	return int(n), true
+}
@@ -0,0 +1,176 @@
+// Tests for the OCSF mapper and the bundled schema.
+package parser
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestToOCSFImperva tests the HTTP Activity mapping of Imperva events.
+func TestToOCSFImperva(t *testing.T) {
+	cefEvent, err := ParseCEF(ImpervaCEF1)
+	if err != nil {
+		t.Fatalf("ParseCEF() error = %v", err)
+	}
+	event := cefEvent.ToOCSF()
+	if err := event.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	if event.ClassUID != OCSFClassHTTPActivity || event.ActivityID != 3 || event.TypeUID != 400203 ||
+		event.TypeName != "HTTP Activity: Get" || event.CategoryUID != 4 {
+		t.Errorf("unexpected classification: %+v", event)
+	}
+	if event.SeverityID != 1 || event.StartTime != 1720396716929 || event.EndTime != 1720396717135 {
+		t.Errorf("severity_id = %d, start_time = %d, end_time = %d", event.SeverityID, event.StartTime, event.EndTime)
+	}
+
+	expectedSrc := &OCSFEndpoint{
+		IP:       "123.123.123.123",
+		Port:     10401,
+		Location: &OCSFLocation{Country: "US", Coordinates: []float64{-97.822, 37.751}},
+	}
+	if !reflect.DeepEqual(event.SrcEndpoint, expectedSrc) {
+		t.Errorf("src_endpoint = %+v, want %+v", event.SrcEndpoint, expectedSrc)
+	}
+	expectedDst := &OCSFEndpoint{IP: "123.123.123.123", Port: 443, Hostname: "example.com"}
+	if !reflect.DeepEqual(event.DstEndpoint, expectedDst) {
+		t.Errorf("dst_endpoint = %+v, want %+v", event.DstEndpoint, expectedDst)
+	}
+	request := event.HTTPRequest
+	if request.HTTPMethod != "GET" || request.URL.Path != "/path/to/resource" || request.URL.Hostname != "example.com" ||
+		request.Referrer != "https://example.com/path/to/referrer" || !reflect.DeepEqual(request.XForwardedFor, []string{"123.123.123.123"}) ||
+		!strings.HasPrefix(request.UserAgent, "Mozilla/5.0") {
+		t.Errorf("http_request = %+v", request)
+	}
+	if !reflect.DeepEqual(event.HTTPResponse, &OCSFHTTPResponse{Code: 200, Length: 451}) {
+		t.Errorf("http_response = %+v", event.HTTPResponse)
+	}
+	if !reflect.DeepEqual(event.TLS, &OCSFTLS{Version: "1.3", Cipher: "TLS_AES_128_GCM_SHA256"}) {
+		t.Errorf("tls = %+v", event.TLS)
+	}
+	if event.Metadata.UID != "12345678901234567" || event.Metadata.Product.VendorName != "Incapsula" {
+		t.Errorf("metadata = %+v", event.Metadata)
+	}
+
+	unmapped := event.UnmappedFields()
+	for _, key := range []string{"act", "app", "cs10", "fileId", "siteid"} {
+		if _, ok := event.Unmapped[key]; !ok {
+			t.Errorf("expected %s in unmapped fields %v", key, unmapped)
+		}
+	}
+	for _, key := range []string{"src", "cs7", "cs7Label", "requestMethod", "ver"} {
+		if _, ok := event.Unmapped[key]; ok {
+			t.Errorf("expected %s to be mapped", key)
+		}
+	}
+}
+
+// TestToOCSFCentrify tests the Authentication mapping of Centrify events.
+func TestToOCSFCentrify(t *testing.T) {
+	cefEvent, _ := ParseCEF(CentrifyCEF)
+	event := ToOCSF(cefEvent)
+	if err := event.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	if event.ClassUID != OCSFClassAuthentication || event.ActivityID != 99 || event.ActivityName != "Other" ||
+		event.TypeUID != 300299 || event.StatusID != 1 || event.Status != "Success" {
+		t.Errorf("unexpected classification: %+v", event)
+	}
+	if !reflect.DeepEqual(event.User, &OCSFUser{Name: "cloudadmin@persistent.com01", UID: "c2c7bcc6-9560-44e0-8dff-5be221cd37ee"}) {
+		t.Errorf("user = %+v", event.User)
+	}
+	if !reflect.DeepEqual(event.SrcEndpoint, &OCSFEndpoint{IP: "103.6.32.100", Hostname: "103.6.32.100"}) {
+		t.Errorf("src_endpoint = %+v", event.SrcEndpoint)
+	}
+	if event.Time != 1525844566655 || event.Message != "User cloudadmin@persistent.com01 launched Instagram from 103.6.32.100" ||
+		event.Metadata.UID != "772a4a904e82da87.W00.0315.1aa20afe647f09c" || event.Service.Name != "CDS" {
+		t.Errorf("unexpected event: %+v", event)
+	}
+
+	cefEvent.Name = "Cloud.Core.LoginFail"
+	if event := ToOCSF(cefEvent); event.ActivityID != 1 || event.StatusID != 2 {
+		t.Errorf("LoginFail activity_id = %d, status_id = %d", event.ActivityID, event.StatusID)
+	}
+	cefEvent.Name = "Cloud.Core.MfaChallenge"
+	cefEvent.Severity = "2"
+	if event := ToOCSF(cefEvent); event.ClassUID != OCSFClassBaseEvent {
+		t.Errorf("expected Base Event for a non-authentication event, got %d", event.ClassUID)
+	}
+}
+
+// TestToOCSFFallback tests the Security Finding and Base Event fallbacks.
+func TestToOCSFFallback(t *testing.T) {
+	clock := func() time.Time { return time.UnixMilli(1729080000000) }
+	p := NewParser(WithClock(clock))
+
+	finding, err := p.Parse("CEF:0|Security|threatmanager|1.0|100|worm stopped|Very-High|msg=stopped src=10.0.0.1 cnt=3")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	event := finding.ToOCSF()
+	if err := event.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if event.ClassUID != OCSFClassSecurityFinding || event.TypeUID != 200101 || event.SeverityID != 5 ||
+		!reflect.DeepEqual(event.Finding, &OCSFFinding{UID: "100", Title: "worm stopped", Desc: "stopped"}) ||
+		event.Time != 1729080000000 || event.Count != 3 || event.Message != "stopped" {
+		t.Errorf("unexpected Security Finding: %+v", event)
+	}
+	if !reflect.DeepEqual(event.UnmappedFields(), []string{"src"}) {
+		t.Errorf("UnmappedFields() = %v", event.UnmappedFields())
+	}
+
+	base, _ := p.Parse("CEF:0|Security|threatmanager|1.0|100|heartbeat|0|msg=ok")
+	event = base.ToOCSF()
+	if err := event.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if event.ClassUID != OCSFClassBaseEvent || event.TypeUID != 0 || event.SeverityID != 1 || event.Message != "ok" || event.Unmapped != nil {
+		t.Errorf("unexpected Base Event: %+v", event)
+	}
+}
+
+// TestValidateOCSF tests the checks against the bundled schema.
+func TestValidateOCSF(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{"Invalid JSON", `{`, "invalid OCSF event"},
+		{"Missing class", `{}`, "missing required attribute class_uid"},
+		{"Unknown class", `{"class_uid":1001}`, "unknown class_uid 1001"},
+		{"Missing attributes", `{"class_uid":3002}`, "missing required attribute user"},
+		{"Unknown attribute", `{"class_uid":0,"http_request":{}}`, "attribute http_request is not defined for Base Event"},
+		{"Type mismatch", `{"class_uid":4002,"category_uid":4,"activity_id":3,"type_uid":400206}`, "type_uid 400206 does not match"},
+		{"Bad activity", `{"class_uid":4002,"activity_id":42}`, "activity_id 42 is not defined for HTTP Activity"},
+		{"Bad category", `{"class_uid":4002,"category_uid":3}`, "category_uid 3 does not match HTTP Activity"},
+		{"Bad severity", `{"class_uid":0,"severity_id":7}`, "severity_id 7 is not defined"},
+		{"Bad time", `{"class_uid":0,"time":"now"}`, "time must be a positive number"},
+		{"Bad metadata", `{"class_uid":0,"metadata":{"version":"1.0.0","product":{}}}`, `metadata.version must be "1.1.0"`},
+		{"Missing vendor", `{"class_uid":0,"metadata":{"version":"1.1.0"}}`, "metadata.product.vendor_name"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateOCSF([]byte(test.input))
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("ValidateOCSF() error = %v, want %q", err, test.err)
+			}
+		})
+	}
+
+	cefEvent, _ := ParseCEF(ImpervaCEF1)
+	data, err := json.Marshal(cefEvent.ToOCSF())
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if err := ValidateOCSF(data); err != nil {
+		t.Errorf("ValidateOCSF() error = %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"time"
+)
+
+// Default limits used by NewParser and the package-level parsing functions.
+const (
+	// DefaultMaxLineLength is the longest CEF record, in bytes, accepted by default.
+	DefaultMaxLineLength = 10000
+	// DefaultMaxHeaderLength is the longest header component, in characters,
+	// accepted by default. It matches the SignatureID limit in the CEF specification.
+	DefaultMaxHeaderLength = 1023
+)
+
+// ExtensionsFactory returns the Extensions implementation for a vendor, product and version.
+type ExtensionsFactory func(vendor, product, version string) Extensions
+
+// Parser parses CEF events using a configurable set of limits and policies.
+// A Parser is safe for concurrent use once constructed.
+type Parser struct {
+	maxLineLength   int
+	maxHeaderLength int
+	maxExtensions   int
+	maxKeyLength    int
+	maxValueLength  int
+	validation      ValidationLevel
+	newExtensions   ExtensionsFactory
+	location        *time.Location
+	clock           func() time.Time
+	foldLabels      bool
+	labelCollision  LabelCollision
+	syslog          bool
+	lenient         bool
+	quoted          bool
+	duplicates      DuplicateKeyPolicy
+}
+
+// Option configures a Parser.
+type Option func(*Parser)
+
+// NewParser returns a Parser configured with the given options. Without options
+// it behaves like ParseCEF and ParseCEFWithContext.
+func NewParser(opts ...Option) *Parser {
+	p := &Parser{
+		maxLineLength:   DefaultMaxLineLength,
+		maxHeaderLength: DefaultMaxHeaderLength,
+		validation:      ValidationDefault,
+		newExtensions:   NewExtensions,
+		syslog:          true,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// WithMaxLineLength sets the longest CEF record, in bytes, that will be parsed.
+// A value of zero or less removes the limit.
+func WithMaxLineLength(n int) Option {
+	return func(p *Parser) {
+		p.maxLineLength = n
+	}
+}
+
+// WithMaxHeaderLength sets the longest header component, in characters, accepted
+// under ValidationDefault. A value of zero or less removes the limit.
+func WithMaxHeaderLength(n int) Option {
+	return func(p *Parser) {
+		p.maxHeaderLength = n
+	}
+}
+
+// WithMaxExtensions sets the largest number of extension fields in a record.
+// A value of zero or less removes the limit, which is the default.
+func WithMaxExtensions(n int) Option {
+	return func(p *Parser) {
+		p.maxExtensions = n
+	}
+}
+
+// WithMaxKeyLength sets the longest extension key, in bytes.
+// A value of zero or less removes the limit, which is the default.
+func WithMaxKeyLength(n int) Option {
+	return func(p *Parser) {
+		p.maxKeyLength = n
+	}
+}
+
+// WithMaxValueLength sets the longest unescaped extension value, in bytes.
+// A value of zero or less removes the limit, which is the default.
+func WithMaxValueLength(n int) Option {
+	return func(p *Parser) {
+		p.maxValueLength = n
+	}
+}
+
+// WithValidationLevel sets how strictly header components are validated.
+func WithValidationLevel(level ValidationLevel) Option {
+	return func(p *Parser) {
+		p.validation = level
+	}
+}
+
+// WithExtensionsFactory sets the function used to select the Extensions
+// implementation for each event. The default is NewExtensions.
+func WithExtensionsFactory(factory ExtensionsFactory) Option {
+	return func(p *Parser) {
+		if factory != nil {
+			p.newExtensions = factory
+		}
+	}
+}
+
+// WithRegistry sets the Registry used to select the Extensions implementation
+// for each event, in place of the package-level registry.
+func WithRegistry(r *Registry) Option {
+	return func(p *Parser) {
+		if r != nil {
+			p.newExtensions = r.NewExtensions
+		}
+	}
+}
+
+// WithLabelFolding makes the parser fold custom fields into the CustomFields
+// map of each event, resolving shared labels according to collision. With
+// LabelCollisionError, events with a shared label fail to parse.
+func WithLabelFolding(collision LabelCollision) Option {
+	return func(p *Parser) {
+		p.foldLabels = true
+		p.labelCollision = collision
+	}
+}
+
+// WithDuplicateKeys sets how extension keys that appear more than once in a
+// record are handled. The default is DuplicateLast.
+func WithDuplicateKeys(policy DuplicateKeyPolicy) Option {
+	return func(p *Parser) {
+		p.duplicates = policy
+	}
+}
+
+// WithOrderedExtensions makes the parser store extensions in an
+// OrderedExtensions for every vendor, keeping the fields in their original
+// order. It replaces the registry and any extensions factory set before it.
+func WithOrderedExtensions() Option {
+	return WithExtensionsFactory(func(string, string, string) Extensions {
+		return &OrderedExtensions{}
+	})
+}
+
+// WithLenient makes Parse and ParseContext return a best-effort event for every
+// record instead of failing on invalid data. Problems are then reported as a
+// Warnings error returned alongside the non-nil event. ParseBytes is not
+// affected. See Parser.ParseLenient.
+func WithLenient(enabled bool) Option {
+	return func(p *Parser) {
+		p.lenient = enabled
+	}
+}
+
+// WithQuotedValues enables or disables the legacy reading of extension values
+// that open with a double quote, '[' or '{'. When enabled, such a value is read
+// up to its closing quote or bracket even if it contains ` key=` sequences, and
+// enclosing double quotes are removed, as some vendors (such as Imperva) send
+// unescaped quoted and JSON values. The CEF specification has no quoting, so
+// this is disabled by default and values end at the last space before the next
+// key. The built-in Imperva and Centrify extension types always read quoted
+// values, whatever this setting.
+func WithQuotedValues(enabled bool) Option {
+	return func(p *Parser) {
+		p.quoted = enabled
+	}
+}
+
+// WithSyslog enables or disables the detection of syslog envelopes around CEF
+// records. When enabled, the default, RFC 3164 and RFC 5424 envelopes are
+// stripped and exposed through the Syslog field of the event.
+func WithSyslog(enabled bool) Option {
+	return func(p *Parser) {
+		p.syslog = enabled
+	}
+}
+
+// WithLocation sets the location used for event timestamps that carry no zone
+// and no dtz field. The default is UTC.
+func WithLocation(loc *time.Location) Option {
+	return func(p *Parser) {
+		p.location = loc
+	}
+}
+
+// WithClock sets the function the parser uses to obtain the current time, which
+// places timestamps that omit the year. The default is time.Now.
+func WithClock(clock func() time.Time) Option {
+	return func(p *Parser) {
+		p.clock = clock
+	}
+}
+
+// now returns the current time according to the parser's clock.
+func (p *Parser) now() time.Time {
+	if p.clock != nil {
+		return p.clock()
+	}
+	return time.Now()
+}
+
+// timeSettings returns the timestamp settings to attach to parsed events, or
+// nil when the defaults apply.
+func (p *Parser) timeSettings() *timeSettings {
+	if p.location == nil && p.clock == nil {
+		return nil
+	}
+	return &timeSettings{location: p.location, clock: p.clock}
+}
@@ -0,0 +1,92 @@
+// Tests for the configurable Parser.
+package parser
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestNewParserDefaults tests that a default Parser matches ParseCEF.
+func TestNewParserDefaults(t *testing.T) {
+	expected, err := ParseCEF(ImpervaCEF1)
+	if err != nil {
+		t.Fatalf("ParseCEF() error = %v", err)
+	}
+
+	cefEvent, err := NewParser().Parse(ImpervaCEF1)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(cefEvent, expected) {
+		t.Errorf("Parse() = %v, want %v", cefEvent, expected)
+	}
+}
+
+// TestParserLimits tests the limits configured through options.
+func TestParserLimits(t *testing.T) {
+	cef := "CEF:0|Vendor|Product|1.0|100|Name|5|src=10.0.0.1 dst=10.0.0.2 msg=" + strings.Repeat("a", 2000)
+
+	tests := []struct {
+		name      string
+		opts      []Option
+		cef       string
+		expectErr bool
+	}{
+		{"Defaults", nil, cef, false},
+		{"Line too long", []Option{WithMaxLineLength(100)}, cef, true},
+		{"Unlimited line length", []Option{WithMaxLineLength(0)}, cef + strings.Repeat("b", DefaultMaxLineLength), false},
+		{"Too many extensions", []Option{WithMaxExtensions(2)}, cef, true},
+		{"Enough extensions", []Option{WithMaxExtensions(3)}, cef, false},
+		{"Value too long", []Option{WithMaxValueLength(1000)}, cef, true},
+		{"Key too long", []Option{WithMaxKeyLength(2)}, cef, true},
+		{"Header too long", []Option{WithMaxHeaderLength(3)}, cef, true},
+		{"Strict validation", []Option{WithValidationLevel(ValidationStrict)}, "CEF:0||Product|1.0|100|Name|5|", true},
+		{"No validation", []Option{WithValidationLevel(ValidationNone)}, "CEF:x||Product|1.0||Name|99|", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewParser(test.opts...).ParseContext(context.Background(), test.cef)
+			if test.expectErr && err == nil {
+				t.Errorf("expected error, got nil")
+			}
+			if !test.expectErr && err != nil {
+				t.Errorf("did not expect error, got '%v'", err)
+			}
+		})
+	}
+}
+
+// TestWithExtensionsFactory tests that a custom factory selects the Extensions type.
+func TestWithExtensionsFactory(t *testing.T) {
+	p := NewParser(WithExtensionsFactory(func(vendor, product, version string) Extensions {
+		return &CentrifyExtensions{}
+	}))
+
+	cefEvent, err := p.Parse("CEF:0|Vendor|Product|1.0|100|Name|5|dhost=host1")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	ce, ok := cefEvent.Extensions.(*CentrifyExtensions)
+	if !ok {
+		t.Fatalf("expected *CentrifyExtensions, got %T", cefEvent.Extensions)
+	}
+	if ce.DHost != "host1" {
+		t.Errorf("expected DHost to be 'host1', got '%s'", ce.DHost)
+	}
+}
+
+// TestWithClock tests that the configured clock is used by the Parser.
+func TestWithClock(t *testing.T) {
+	fixed := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	p := NewParser(WithClock(func() time.Time { return fixed }))
+
+	if !p.now().Equal(fixed) {
+		t.Errorf("expected clock to return %v, got %v", fixed, p.now())
+	}
+}
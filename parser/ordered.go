@@ -0,0 +1,268 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DuplicateKeyPolicy determines how an extension key that appears more than
+// once in a record is handled.
+type DuplicateKeyPolicy int
+
+const (
+	// DuplicateLast keeps the last value of a repeated key. This is the default.
+	DuplicateLast DuplicateKeyPolicy = iota
+	// DuplicateFirst keeps the first value of a repeated key.
+	DuplicateFirst
+	// DuplicateCollect keeps every value. OrderedExtensions stores each pair;
+	// map-based extension types join the values with ", ".
+	DuplicateCollect
+	// DuplicateError fails the parse with ReasonDuplicateKey.
+	DuplicateError
+)
+
+// KeyValue is a single extension key/value pair.
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// OrderedExtensions is an Extensions implementation that keeps the extension
+// fields in the order they appear in the record. Depending on the parser's
+// DuplicateKeyPolicy a key may occur more than once; the single-value accessors
+// Get, GetField and AsMap then return its last value.
+//
+// It encodes to JSON as an object with the keys in their original order, where
+// a repeated key is written once, at its first position, with an array of its
+// values. Format writes every pair in order.
+type OrderedExtensions struct {
+	Fields []KeyValue
+}
+
+// ParseExtensions parses the extension string, keeping every key/value pair.
+func (oe *OrderedExtensions) ParseExtensions(extension string) map[string]string {
+	fields, _ := tokenizeExtensions(extension, false)
+	oe.loadOrdered(fields)
+	return oe.AsMap()
+}
+
+// loadOrdered stores the tokenized extension fields in order.
+func (oe *OrderedExtensions) loadOrdered(fields []extensionField) {
+	oe.Fields = make([]KeyValue, len(fields))
+	for i, field := range fields {
+		oe.Fields[i] = KeyValue{Key: field.Key, Value: field.Value}
+	}
+}
+
+// Get returns the last value of key. Keys are matched exactly first and then
+// case-insensitively.
+func (oe *OrderedExtensions) Get(key string) (string, bool) {
+	for i := len(oe.Fields) - 1; i >= 0; i-- {
+		if oe.Fields[i].Key == key {
+			return oe.Fields[i].Value, true
+		}
+	}
+	for i := len(oe.Fields) - 1; i >= 0; i-- {
+		if strings.EqualFold(oe.Fields[i].Key, key) {
+			return oe.Fields[i].Value, true
+		}
+	}
+	return "", false
+}
+
+// Values returns every value of key in order.
+func (oe *OrderedExtensions) Values(key string) []string {
+	var values []string
+	for _, field := range oe.Fields {
+		if field.Key == key {
+			values = append(values, field.Value)
+		}
+	}
+	return values
+}
+
+// GetField dynamically retrieves a field value by name.
+func (oe *OrderedExtensions) GetField(fieldName string) (interface{}, error) {
+	for i := len(oe.Fields) - 1; i >= 0; i-- {
+		if oe.Fields[i].Key == fieldName {
+			return oe.Fields[i].Value, nil
+		}
+	}
+	return nil, fmt.Errorf("field %s not found", fieldName)
+}
+
+// AsJSON returns the extension fields as a pretty JSON string in their
+// original order.
+func (oe *OrderedExtensions) AsJSON() string {
+	data, _ := json.MarshalIndent(oe, "", "  ")
+	return string(data)
+}
+
+// MarshalJSON encodes the extension fields as a JSON object in their original
+// order, with the values of a repeated key collected into an array.
+func (oe *OrderedExtensions) MarshalJSON() ([]byte, error) {
+	return marshalFields(formatFields(oe))
+}
+
+// AsMap returns the extension fields as a map, keeping the last value of a
+// repeated key.
+func (oe *OrderedExtensions) AsMap() map[string]string {
+	fields := make(map[string]string, len(oe.Fields))
+	for _, field := range oe.Fields {
+		fields[field.Key] = field.Value
+	}
+	return fields
+}
+
+// GetFieldNames returns the distinct field names in order of first appearance.
+func (oe *OrderedExtensions) GetFieldNames() []string {
+	seen := make(map[string]bool, len(oe.Fields))
+	fieldNames := make([]string, 0, len(oe.Fields))
+	for _, field := range oe.Fields {
+		if !seen[field.Key] {
+			seen[field.Key] = true
+			fieldNames = append(fieldNames, field.Key)
+		}
+	}
+	return fieldNames
+}
+
+// CustomFields returns the custom fields keyed by their labels. Fields sharing a
+// label are resolved with LabelCollisionSuffix.
+func (oe *OrderedExtensions) CustomFields() map[string]string {
+	folded, _ := FoldCustomFields(oe, LabelCollisionSuffix)
+	return folded
+}
+
+// SetField sets the value of key at the position of its first occurrence,
+// dropping any later ones, or appends the field if key is absent.
+func (oe *OrderedExtensions) SetField(key, value string) error {
+	if err := checkSetKey(key); err != nil {
+		return err
+	}
+	set := false
+	fields := oe.Fields[:0]
+	for _, field := range oe.Fields {
+		if field.Key == key {
+			if set {
+				continue
+			}
+			field.Value, set = value, true
+		}
+		fields = append(fields, field)
+	}
+	if !set {
+		fields = append(fields, KeyValue{Key: key, Value: value})
+	}
+	oe.Fields = fields
+	return nil
+}
+
+// DeleteField removes every occurrence of key and reports whether there was
+// any.
+func (oe *OrderedExtensions) DeleteField(key string) bool {
+	n := len(oe.Fields)
+	oe.Fields = slices.DeleteFunc(oe.Fields, func(field KeyValue) bool {
+		return field.Key == key
+	})
+	return len(oe.Fields) != n
+}
+
+// RenameField renames every occurrence of oldKey to newKey in place, dropping
+// the fields newKey already has.
+func (oe *OrderedExtensions) RenameField(oldKey, newKey string) error {
+	if !slices.ContainsFunc(oe.Fields, func(field KeyValue) bool { return field.Key == oldKey }) {
+		return fmt.Errorf("field %s not found", oldKey)
+	}
+	if err := checkSetKey(newKey); err != nil {
+		return err
+	}
+	if newKey != oldKey {
+		oe.DeleteField(newKey)
+	}
+	for i := range oe.Fields {
+		if oe.Fields[i].Key == oldKey {
+			oe.Fields[i].Key = newKey
+		}
+	}
+	return nil
+}
+
+// Clone returns a deep copy of the extensions.
+func (oe *OrderedExtensions) Clone() Extensions {
+	return &OrderedExtensions{Fields: slices.Clone(oe.Fields)}
+}
+
+// orderedLoader is implemented by extension types that keep the tokenized
+// fields in order, including repeated keys.
+type orderedLoader interface {
+	loadOrdered(fields []extensionField)
+}
+
+// dedupeFields drops the repeated keys that policy discards, keeping the
+// remaining fields in order.
+func dedupeFields(fields []extensionField, policy DuplicateKeyPolicy) []extensionField {
+	if policy == DuplicateCollect {
+		return fields
+	}
+	keep := make(map[string]int, len(fields))
+	for i, field := range fields {
+		if _, seen := keep[field.Key]; seen && policy == DuplicateFirst {
+			continue
+		}
+		keep[field.Key] = i
+	}
+	if len(keep) == len(fields) {
+		return fields
+	}
+
+	kept := make([]extensionField, 0, len(keep))
+	for i, field := range fields {
+		if keep[field.Key] == i {
+			kept = append(kept, field)
+		}
+	}
+	return kept
+}
+
+// mergeFields converts tokenized extension fields into a map, resolving
+// repeated keys with policy.
+func mergeFields(fields []extensionField, policy DuplicateKeyPolicy) map[string]string {
+	merged := make(map[string]string, len(fields))
+	for _, field := range fields {
+		prev, seen := merged[field.Key]
+		switch {
+		case !seen:
+			merged[field.Key] = field.Value
+		case policy == DuplicateFirst:
+		case policy == DuplicateCollect:
+			merged[field.Key] = prev + ", " + field.Value
+		default:
+			merged[field.Key] = field.Value
+		}
+	}
+	return merged
+}
+
+// firstDuplicate returns the error for the first repeated key in fields, or nil.
+func firstDuplicate(fields []extensionField) error {
+	seen := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		if seen[field.Key] {
+			return duplicateKeyError(field)
+		}
+		seen[field.Key] = true
+	}
+	return nil
+}
+
+// duplicateKeyError returns the error for a repeated key.
+func duplicateKeyError(field extensionField) *ParseError {
+	return &ParseError{
+		Err: ErrInvalidExtension, Reason: ReasonDuplicateKey, Field: -1, Key: field.Key, Offset: field.Offset,
+		Detail: fmt.Sprintf("duplicate key %q", field.Key),
+	}
+}
@@ -0,0 +1,145 @@
+// Tests for ordered extensions and duplicate key policies.
+package parser
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestOrderedExtensions tests that fields keep their original order.
+func TestOrderedExtensions(t *testing.T) {
+	p := NewParser(WithOrderedExtensions(), WithDuplicateKeys(DuplicateCollect))
+	cefEvent, err := p.Parse(ImpervaCEFCombined)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	oe, ok := cefEvent.Extensions.(*OrderedExtensions)
+	if !ok {
+		t.Fatalf("expected *OrderedExtensions, got %T", cefEvent.Extensions)
+	}
+
+	names := oe.GetFieldNames()
+	if names[0] != "fileid" || names[1] != "sourceServiceName" || names[len(names)-1] != "cs11Label" {
+		t.Errorf("unexpected field order: %v", names)
+	}
+	if values := oe.Values("ccode"); !reflect.DeepEqual(values, []string{"IL", "IL"}) {
+		t.Errorf("Values(ccode) = %v", values)
+	}
+	if value, ok := oe.Get("REQUESTMETHOD"); !ok || value != "GET" {
+		t.Errorf("Get(REQUESTMETHOD) = %q, %v", value, ok)
+	}
+	if value, err := oe.GetField("qstr"); err != nil || value != "p=%2fetc%2fpasswd" {
+		t.Errorf("GetField(qstr) = %v, %v", value, err)
+	}
+	if _, err := oe.GetField("missing"); err == nil {
+		t.Errorf("expected error for missing field")
+	}
+	if len(oe.AsMap()) != len(names) {
+		t.Errorf("AsMap() has %d keys, want %d", len(oe.AsMap()), len(names))
+	}
+	if custom := oe.CustomFields(); custom["latitude"] != "31.8969" {
+		t.Errorf("CustomFields() = %v", custom)
+	}
+	if value, ok := extensionValue(oe, "cicode"); !ok || value != "Rehovot" {
+		t.Errorf("extensionValue(cicode) = %q, %v", value, ok)
+	}
+}
+
+// TestOrderedExtensionsJSON tests the JSON encoding of ordered extensions.
+func TestOrderedExtensionsJSON(t *testing.T) {
+	oe := &OrderedExtensions{}
+	oe.ParseExtensions("dst=2 src=1 act=a act=b")
+	data, err := oe.MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON() error = %v", err)
+	}
+	if string(data) != `{"dst":"2","src":"1","act":["a","b"]}` {
+		t.Errorf("MarshalJSON() = %s", data)
+	}
+	if json := oe.AsJSON(); !strings.HasPrefix(json, "{\n  \"dst\"") {
+		t.Errorf("AsJSON() = %s", json)
+	}
+
+	cefEvent := &CEF{Version: "0", Extensions: oe}
+	if json := cefEvent.AsJSON(); !strings.Contains(json, `"extensions": {`+"\n"+`    "dst": "2",`) {
+		t.Errorf("CEF.AsJSON() = %s", json)
+	}
+}
+
+// TestFormatOrderedExtensions tests that Format writes every pair in order.
+func TestFormatOrderedExtensions(t *testing.T) {
+	line := "CEF:0|V|P|1|2|N|3|dst=2 src=1 act=a act=b"
+	cefEvent, err := NewParser(WithOrderedExtensions(), WithDuplicateKeys(DuplicateCollect)).Parse(line)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if formatted, err := Format(cefEvent); err != nil || formatted != line {
+		t.Errorf("Format() = %q, %v; want %q", formatted, err, line)
+	}
+}
+
+// TestDuplicateKeyPolicies tests each policy on the map and ordered types.
+func TestDuplicateKeyPolicies(t *testing.T) {
+	line := "CEF:0|V|P|1|2|N|3|act=a src=1 act=b"
+	tests := []struct {
+		name    string
+		policy  DuplicateKeyPolicy
+		fields  map[string]string
+		ordered []KeyValue
+	}{
+		{"Last", DuplicateLast, map[string]string{"act": "b", "src": "1"}, []KeyValue{{"src", "1"}, {"act", "b"}}},
+		{"First", DuplicateFirst, map[string]string{"act": "a", "src": "1"}, []KeyValue{{"act", "a"}, {"src", "1"}}},
+		{"Collect", DuplicateCollect, map[string]string{"act": "a, b", "src": "1"}, []KeyValue{{"act", "a"}, {"src", "1"}, {"act", "b"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cefEvent, err := NewParser(WithDuplicateKeys(test.policy)).Parse(line)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if fields := cefEvent.Extensions.AsMap(); !reflect.DeepEqual(fields, test.fields) {
+				t.Errorf("AsMap() = %v, want %v", fields, test.fields)
+			}
+
+			var reused CEF
+			if err := NewParser(WithDuplicateKeys(test.policy)).ParseBytes([]byte(line), &reused); err != nil {
+				t.Fatalf("ParseBytes() error = %v", err)
+			}
+			if fields := reused.Extensions.AsMap(); !reflect.DeepEqual(fields, test.fields) {
+				t.Errorf("ParseBytes() fields = %v, want %v", fields, test.fields)
+			}
+
+			cefEvent, err = NewParser(WithOrderedExtensions(), WithDuplicateKeys(test.policy)).Parse(line)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if fields := cefEvent.Extensions.(*OrderedExtensions).Fields; !reflect.DeepEqual(fields, test.ordered) {
+				t.Errorf("Fields = %v, want %v", fields, test.ordered)
+			}
+		})
+	}
+}
+
+// TestDuplicateKeyError tests the DuplicateError policy.
+func TestDuplicateKeyError(t *testing.T) {
+	p := NewParser(WithDuplicateKeys(DuplicateError))
+	line := "CEF:0|V|P|1|2|N|3|act=a src=1 act=b"
+
+	_, err := p.Parse(line)
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Reason != ReasonDuplicateKey || perr.Key != "act" || perr.Offset != 30 {
+		t.Errorf("Parse() error = %#v", err)
+	}
+
+	var cefEvent CEF
+	if err := p.ParseBytes([]byte(line), &cefEvent); !errors.Is(err, ErrInvalidExtension) {
+		t.Errorf("ParseBytes() error = %v", err)
+	}
+
+	if _, err := p.Parse("CEF:0|V|P|1|2|N|3|act=a src=1"); err != nil {
+		t.Errorf("Parse() error = %v", err)
+	}
+}
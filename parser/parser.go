@@ -3,142 +3,263 @@ package parser
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"regexp"
 	"strings"
 )
 
-// NewExtensions returns an Extensions struct based on the vendor, product, and version.
+// NewExtensions returns an Extensions struct based on the vendor, product, and version,
+// as registered in the package-level registry.
 func NewExtensions(vendor, product, version string) Extensions {
-	switch {
-	case vendor == "Incapsula" && product == "SIEMintegration":
-		return &ImpervaExtensions{}
-	case vendor == "Centrify" && product == "Centrify_Cloud":
-		return &CentrifyExtensions{}
-	default:
-		return &DefaultExtensions{}
-	}
+	return defaultRegistry.NewExtensions(vendor, product, version)
 }
 
+// defaultParser is the Parser used by the package-level parsing functions.
+var defaultParser = NewParser()
+
 // ParseCEF parses a CEF event string into a CEF struct.
 func ParseCEF(cef string) (*CEF, error) {
-	return ParseCEFWithContext(context.Background(), cef)
+	return defaultParser.Parse(cef)
 }
 
 // ParseCEFWithContext parses a CEF event string into a CEF struct, supporting context for cancellations and timeouts.
 func ParseCEFWithContext(ctx context.Context, cef string) (*CEF, error) {
-	// Basic input validation before parsing
-	if len(cef) == 0 || len(cef) > 10000 {
-		return nil, fmt.Errorf("invalid CEF string length")
-	}
+	return defaultParser.ParseContext(ctx, cef)
+}
+
+// ParseCEFWithValidation parses a CEF event string into a CEF struct, validating the header at the given level.
+func ParseCEFWithValidation(ctx context.Context, cef string, level ValidationLevel) (*CEF, error) {
+	return NewParser(WithValidationLevel(level)).ParseContext(ctx, cef)
+}
 
-	regex := regexp.MustCompile(`^CEF:([^\|]*)\|([^\|]*)\|([^\|]*)\|([^\|]*)\|([^\|]*)\|([^\|]*)\|([^\|]*)\|(.*)$`)
-	matches := regex.FindStringSubmatch(cef)
+// Parse parses a CEF event string into a CEF struct.
+func (p *Parser) Parse(cef string) (*CEF, error) {
+	return p.ParseContext(context.Background(), cef)
+}
 
-	if len(matches) == 0 {
-		return nil, fmt.Errorf("invalid CEF format")
+// ParseContext parses a CEF event string into a CEF struct, supporting context for cancellations and timeouts.
+func (p *Parser) ParseContext(ctx context.Context, cef string) (*CEF, error) {
+	if p.lenient {
+		return p.parseLenientContext(ctx, cef)
 	}
 
-	// Further validation on parsed fields
-	if !isValidCEFComponent(matches[1]) || !isValidCEFComponent(matches[2]) ||
-		!isValidCEFComponent(matches[3]) || !isValidCEFComponent(matches[4]) ||
-		!isValidCEFComponent(matches[5]) || !isValidCEFComponent(matches[6]) ||
-		!isValidCEFComponent(matches[7]) {
-		return nil, fmt.Errorf("one or more CEF components are invalid")
+	header, extension, envelope, err := p.parseHeader(cef)
+	if err != nil {
+		return nil, err
 	}
 
 	cefEvent := &CEF{
-		Version:       matches[1],
-		DeviceVendor:  matches[2],
-		DeviceProduct: matches[3],
-		DeviceVersion: matches[4],
-		SignatureID:   matches[5],
-		Name:          matches[6],
-		Severity:      matches[7],
-		Extensions:    NewExtensions(matches[2], matches[3], matches[4]),
+		Version:       header[0],
+		DeviceVendor:  header[1],
+		DeviceProduct: header[2],
+		DeviceVersion: header[3],
+		SignatureID:   header[4],
+		Name:          header[5],
+		Severity:      header[6],
+		Extensions:    p.newExtensions(header[1], header[2], header[3]),
+		Syslog:        envelope,
+		times:         p.timeSettings(),
 	}
 
 	select {
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	default:
-		extension := matches[8]
-		cefEvent.Extensions.ParseExtensions(extension)
+		fields, err := tokenizeExtensions(extension, p.quotedValues(cefEvent.Extensions))
+		if err != nil {
+			return nil, extensionError(err, cef, extension)
+		}
+		if err := p.checkExtensionLimits(fields); err != nil {
+			return nil, extensionError(err, cef, extension)
+		}
+		if p.duplicates == DuplicateError {
+			if err := firstDuplicate(fields); err != nil {
+				return nil, extensionError(err, cef, extension)
+			}
+		}
+		loadExtensions(cefEvent.Extensions, extension, fields, p.duplicates)
+		if p.foldLabels {
+			if cefEvent.CustomFields, err = FoldCustomFields(cefEvent.Extensions, p.labelCollision); err != nil {
+				perr := newParseError(ErrInvalidExtension, ReasonLabelCollision, cef, len(cef)-len(extension))
+				perr.Detail = err.Error()
+				return nil, perr
+			}
+		}
 	}
 
 	return cefEvent, nil
 }
 
-// parseExtensions parses a CEF extension string into a map.
-func parseExtensions(extension string) map[string]string {
-	var keyValPairs = make(map[string]string)
-	var currentKey string
-	var currentVal string
-	var isValueComplex bool
-	var complexValBuilder strings.Builder
-
-	parts := strings.Split(extension, " ")
-
-	for _, part := range parts {
-		if strings.Contains(part, "=") && !isValueComplex {
-			if currentKey != "" && currentVal != "" {
-				keyValPairs[currentKey] = strings.Trim(currentVal, `"`)
-			}
-			parts := strings.SplitN(part, "=", 2)
-			currentKey = parts[0]
-			currentVal = parts[1]
-
-			// Handle JSON-like and complex structures
-			if strings.HasPrefix(currentVal, "\"") && !strings.HasSuffix(currentVal, "\"") {
-				isValueComplex = true
-				complexValBuilder.WriteString(currentVal)
-			} else if strings.HasPrefix(currentVal, "[{") && !strings.HasSuffix(currentVal, "}]") {
-				isValueComplex = true
-				complexValBuilder.WriteString(currentVal)
-			}
-		} else {
-			if isValueComplex {
-				complexValBuilder.WriteString(" ")
-				complexValBuilder.WriteString(part)
-				if (strings.HasPrefix(complexValBuilder.String(), "\"") && strings.HasSuffix(part, "\"")) ||
-					(strings.HasPrefix(complexValBuilder.String(), "[{") && strings.HasSuffix(part, "}]")) {
-					isValueComplex = false
-					currentVal = complexValBuilder.String()
-					complexValBuilder.Reset()
-				}
-			} else {
-				currentVal += " " + part
+// parseHeader strips any syslog envelope from a CEF record and splits it into
+// its validated header fields and the raw extension string.
+func (p *Parser) parseHeader(line string) ([cefHeaderFields]string, string, *Syslog, error) {
+	var header [cefHeaderFields]string
+
+	// Basic input validation before parsing
+	if len(line) == 0 {
+		return header, "", nil, newParseError(ErrInvalidLength, ReasonEmpty, line, 0)
+	}
+	if p.maxLineLength > 0 && len(line) > p.maxLineLength {
+		return header, "", nil, newParseError(ErrInvalidLength, ReasonTooLong, line, p.maxLineLength)
+	}
+
+	cef := line
+	var envelope *Syslog
+	if p.syslog && !strings.HasPrefix(cef, cefPrefix) {
+		sl, record, err := splitSyslogAt(cef, p.location, p.now())
+		if err != nil {
+			reason := ReasonSyslog
+			if !strings.HasPrefix(line, "<") && !strings.Contains(line, cefPrefix) {
+				reason = ReasonMissingPrefix
 			}
+			return header, "", nil, newParseError(ErrInvalidFormat, reason, line, 0)
 		}
+		envelope, cef = sl, record
 	}
+	base := len(line) - len(cef)
 
-	if currentKey != "" && currentVal != "" {
-		keyValPairs[currentKey] = strings.Trim(currentVal, `"`)
+	header, extension, ok := scanHeader(cef)
+	if !ok {
+		if !strings.HasPrefix(cef, cefPrefix) {
+			return header, "", nil, newParseError(ErrInvalidFormat, ReasonMissingPrefix, line, base)
+		}
+		return header, "", nil, newParseError(ErrInvalidFormat, ReasonMissingFields, line, len(line))
 	}
 
-	return keyValPairs
+	// Further validation on parsed fields
+	if err := validateHeader(header, p.validation, p.maxHeaderLength); err != nil {
+		var perr *ParseError
+		if errors.As(err, &perr) {
+			perr.Offset = base + headerFieldOffset(cef, perr.Field)
+			perr.Snippet = snippet(line, perr.Offset)
+			perr.Detail = ""
+		}
+		return header, "", nil, err
+	}
+
+	return header, extension, envelope, nil
+}
+
+// extensionError places an extension error, whose offset is relative to the
+// extension string, within line.
+func extensionError(err error, line, extension string) error {
+	var perr *ParseError
+	if errors.As(err, &perr) {
+		perr.Offset += len(line) - len(extension)
+		perr.Snippet = snippet(line, perr.Offset)
+	}
+	return err
 }
 
-// isValidCEFComponent ensures that each CEF component is valid.
-func isValidCEFComponent(component string) bool {
-	// Validate length and ensure no forbidden characters
-	return len(component) > 0 && len(component) <= 100 && regexp.MustCompile(`^[a-zA-Z0-9_ .-]+$`).MatchString(component)
+// checkExtensionLimits enforces the configured extension count and key/value lengths.
+func (p *Parser) checkExtensionLimits(fields []extensionField) error {
+	if p.maxExtensions > 0 && len(fields) > p.maxExtensions {
+		return p.tooManyExtensions(fields[p.maxExtensions])
+	}
+	for _, field := range fields {
+		if err := p.checkExtensionField(field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkExtensionField enforces the configured key and value lengths.
+func (p *Parser) checkExtensionField(field extensionField) error {
+	if !isValidCEFKey(field.Key, p.maxKeyLength) {
+		return &ParseError{
+			Err: ErrInvalidExtension, Reason: ReasonKeyTooLong, Field: -1, Key: field.Key, Offset: field.Offset,
+			Detail: fmt.Sprintf("key %q is longer than %d characters", field.Key, p.maxKeyLength),
+		}
+	}
+	if field.Value != "" && !isValidCEFValue(field.Value, p.maxValueLength) {
+		return &ParseError{
+			Err: ErrInvalidExtension, Reason: ReasonValueTooLong, Field: -1, Key: field.Key, Offset: field.Offset + len(field.Key) + 1,
+			Detail: fmt.Sprintf("value of %q is longer than %d characters", field.Key, p.maxValueLength),
+		}
+	}
+	return nil
+}
+
+// tooManyExtensions returns the error for the first field beyond the limit.
+func (p *Parser) tooManyExtensions(field extensionField) error {
+	return &ParseError{
+		Err: ErrInvalidExtension, Reason: ReasonTooManyExtensions, Field: -1, Key: field.Key, Offset: field.Offset,
+		Detail: fmt.Sprintf("more than %d fields", p.maxExtensions),
+	}
+}
+
+// quotedExtensions is implemented by extension types whose vendors send
+// unescaped quoted and JSON values. Their extensions are always read as with
+// WithQuotedValues.
+type quotedExtensions interface {
+	quotedValues() bool
+}
+
+// quotedValues reports whether the extensions of an event decoded into ext
+// are read with quoted and JSON values kept whole.
+func (p *Parser) quotedValues(ext Extensions) bool {
+	if q, ok := ext.(quotedExtensions); ok && q.quotedValues() {
+		return true
+	}
+	return p.quoted
+}
+
+// fieldsLoader is implemented by the built-in extension types so that the
+// parser can hand them already tokenized fields.
+type fieldsLoader interface {
+	loadFields(fields map[string]string)
+}
+
+// loadExtensions populates ext from the tokenized extension fields, resolving
+// repeated keys with policy, and falls back to ParseExtensions for types that
+// only accept the raw extension string.
+func loadExtensions(ext Extensions, extension string, fields []extensionField, policy DuplicateKeyPolicy) {
+	switch loader := ext.(type) {
+	case orderedLoader:
+		loader.loadOrdered(dedupeFields(fields, policy))
+	case fieldsLoader:
+		loader.loadFields(mergeFields(fields, policy))
+	default:
+		ext.ParseExtensions(extension)
+	}
+}
+
+// parseExtensions parses a CEF extension string into a map.
+// Malformed escape sequences are kept verbatim; see tokenizeExtensions.
+func parseExtensions(extension string) map[string]string {
+	fields, _ := tokenizeExtensions(extension, false)
+	return fieldsToMap(fields)
+}
+
+// fieldsToMap converts tokenized extension fields into a map.
+func fieldsToMap(fields []extensionField) map[string]string {
+	keyValPairs := make(map[string]string, len(fields))
+	for _, field := range fields {
+		keyValPairs[field.Key] = field.Value
+	}
+	return keyValPairs
 }
 
 // isValidCEFKey validates if the CEF key conforms to expected patterns.
-func isValidCEFKey(key string) bool {
-	// Implement more complex validation if necessary
-	if len(key) == 0 || len(key) > 50 {
+// A maxLength of zero or less disables the length check.
+func isValidCEFKey(key string, maxLength int) bool {
+	if len(key) == 0 || (maxLength > 0 && len(key) > maxLength) {
 		return false
 	}
 	// Ensure the key contains only allowed characters
-	return regexp.MustCompile(`^[a-zA-Z0-9_]+$`).MatchString(key)
+	for i := 0; i < len(key); i++ {
+		if !isCEFKeyChar(key[i]) {
+			return false
+		}
+	}
+	return true
 }
 
 // isValidCEFValue validates the CEF value for length and content.
-func isValidCEFValue(value string) bool {
-	// Implement more complex validation if necessary
-	if len(value) == 0 || len(value) > 1000 {
+// A maxLength of zero or less disables the length check.
+func isValidCEFValue(value string, maxLength int) bool {
+	if len(value) == 0 || (maxLength > 0 && len(value) > maxLength) {
 		return false
 	}
 	// Basic check to ensure there are no unexpected characters
@@ -442,6 +442,17 @@ func TestParseCentrifyCEF(t *testing.T) {
 			CS5Label:           "internalSessionId",
 			CS6:                "0d10a24f4c57434198fb3ad4559cc48b",
 			CS6Label:           "azDeploymentId",
+			extra: map[string]string{
+				"authMethod":                    "UserPassword",
+				"azRoleId":                      "WebRole_IN_0",
+				"directoryServiceNameLocalized": "Centrify Directory",
+				"directoryServiceUuid":          "09B9A9B0-6CE8-465F-AB03-65766D33B05E",
+				"internalTrackingID":            "d3a0713b610146ca916155efca2be690",
+				"level":                         "Info",
+				"requestDeviceOS":               "Windows",
+				"requestIsMobileDevice":         "False",
+				"threadType":                    "RestCall",
+			},
 		},
 	}
 
@@ -523,7 +534,7 @@ func TestParseCEFWithContext(t *testing.T) {
 		},
 		{
 			name:       "Invalid CEF Components",
-			cef:        "CEF:0|@InvalidVendor|SIEMintegration|1|1|Normal|0| key1=value1 key2=value2", // Invalid vendor component
+			cef:        "CEF:0|Incapsula|SIEMintegration|1|1|Normal|11| key1=value1 key2=value2", // Invalid severity component
 			expectErr:  true,
 			errMessage: "one or more CEF components are invalid",
 		},
@@ -589,7 +600,7 @@ func TestIsValidCEFKey(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		result := isValidCEFKey(test.key)
+		result := isValidCEFKey(test.key, 50)
 		if result != test.expected {
 			t.Errorf("isValidCEFKey(%q) = %v; want %v", test.key, result, test.expected)
 		}
@@ -609,7 +620,7 @@ func TestIsValidCEFValue(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		result := isValidCEFValue(test.value)
+		result := isValidCEFValue(test.value, 1000)
 		if result != test.expected {
 			t.Errorf("isValidCEFValue(%q) = %v; want %v", test.value, result, test.expected)
 		}
@@ -0,0 +1,269 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"context"
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// Result is a record processed by a pipeline.
+type Result struct {
+	// Line is the line on which the record started for ParseReader, or the
+	// 1-based position of the record in the input channel for ParseStream.
+	Line   int
+	Record string
+	Event  *CEF
+	Err    error
+}
+
+// PipelineOption configures ParseStream and ParseReader.
+type PipelineOption func(*pipelineConfig)
+
+// pipelineConfig holds the settings of a pipeline.
+type pipelineConfig struct {
+	workers     int
+	bufferSize  int
+	ordered     bool
+	onError     func(Result)
+	scannerOpts []ScannerOption
+}
+
+// WithWorkers sets the number of parsing goroutines. The default is GOMAXPROCS.
+func WithWorkers(n int) PipelineOption {
+	return func(c *pipelineConfig) {
+		if n > 0 {
+			c.workers = n
+		}
+	}
+}
+
+// WithBufferSize sets how many records may be in flight between the input and
+// the consumer of the results. When the consumer falls behind, the pipeline
+// stops reading its input. The default is twice the number of workers.
+func WithBufferSize(n int) PipelineOption {
+	return func(c *pipelineConfig) {
+		if n > 0 {
+			c.bufferSize = n
+		}
+	}
+}
+
+// WithOrdered makes the pipeline deliver results in input order. By default
+// results are delivered as soon as they are parsed.
+func WithOrdered(ordered bool) PipelineOption {
+	return func(c *pipelineConfig) {
+		c.ordered = ordered
+	}
+}
+
+// WithErrorHandler sets a function called with every record that fails to be
+// read or parsed. Such records are then left out of the results. Records parsed
+// in lenient mode are delivered with their Warnings as Err instead. The
+// function is called from a single goroutine.
+func WithErrorHandler(fn func(Result)) PipelineOption {
+	return func(c *pipelineConfig) {
+		c.onError = fn
+	}
+}
+
+// WithScannerOptions sets the options of the Scanner used by ParseReader.
+func WithScannerOptions(opts ...ScannerOption) PipelineOption {
+	return func(c *pipelineConfig) {
+		c.scannerOpts = append(c.scannerOpts, opts...)
+	}
+}
+
+// ParseStream parses the lines received from lines with the default parser.
+// See Parser.ParseStream.
+func ParseStream(ctx context.Context, lines <-chan string, opts ...PipelineOption) <-chan Result {
+	return defaultParser.ParseStream(ctx, lines, opts...)
+}
+
+// ParseReader parses the records read from r with the default parser. See
+// Parser.ParseReader.
+func ParseReader(ctx context.Context, r io.Reader, opts ...PipelineOption) <-chan Result {
+	return defaultParser.ParseReader(ctx, r, opts...)
+}
+
+// ParseStream parses the lines received from lines on a pool of workers and
+// delivers the results on the returned channel, which is closed once lines is
+// closed and every record has been delivered, or once ctx is cancelled. The
+// caller must either drain the results or cancel ctx.
+func (p *Parser) ParseStream(ctx context.Context, lines <-chan string, opts ...PipelineOption) <-chan Result {
+	return p.runPipeline(ctx, newPipelineConfig(opts), func(send func(Result) bool) {
+		seq := 0
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case line, ok := <-lines:
+				if !ok {
+					return
+				}
+				seq++
+				if !send(Result{Line: seq, Record: line}) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// ParseReader reads records from r with a Scanner and parses them on a pool of
+// workers, delivering the results on the returned channel. A read error that
+// stops the Scanner is delivered as a final result. The channel is closed at
+// the end of the input or once ctx is cancelled. The caller must either drain
+// the results or cancel ctx.
+func (p *Parser) ParseReader(ctx context.Context, r io.Reader, opts ...PipelineOption) <-chan Result {
+	cfg := newPipelineConfig(opts)
+	scanner := NewScanner(r, append([]ScannerOption{WithParser(p)}, cfg.scannerOpts...)...)
+	scanner.raw = true
+
+	return p.runPipeline(ctx, cfg, func(send func(Result) bool) {
+		for scanner.Scan() {
+			_, err := scanner.Event()
+			if !send(Result{Line: scanner.Line(), Record: scanner.Text(), Err: err}) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			send(Result{Line: scanner.Line(), Err: err})
+		}
+	})
+}
+
+// newPipelineConfig applies opts to the default pipeline settings.
+func newPipelineConfig(opts []PipelineOption) pipelineConfig {
+	cfg := pipelineConfig{workers: runtime.GOMAXPROCS(0)}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.bufferSize == 0 {
+		cfg.bufferSize = 2 * cfg.workers
+	}
+	return cfg
+}
+
+// pipelineJob is a record on its way through a pipeline.
+type pipelineJob struct {
+	seq    int
+	result Result
+}
+
+// runPipeline runs source, which feeds records through send until send returns
+// false, and parses the records on cfg.workers goroutines.
+func (p *Parser) runPipeline(ctx context.Context, cfg pipelineConfig, source func(send func(Result) bool)) <-chan Result {
+	jobs := make(chan pipelineJob)
+	parsed := make(chan pipelineJob, cfg.workers)
+	out := make(chan Result)
+	// Each record holds a slot from the time it is read until it is delivered,
+	// bounding the records in flight, including those waiting to be reordered.
+	slots := make(chan struct{}, cfg.bufferSize)
+
+	go func() {
+		defer close(jobs)
+		seq := 0
+		source(func(r Result) bool {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return false
+			}
+			select {
+			case jobs <- pipelineJob{seq: seq, result: r}:
+				seq++
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				var job pipelineJob
+				var ok bool
+				select {
+				case job, ok = <-jobs:
+					if !ok {
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+
+				if job.result.Err == nil {
+					job.result.Event, job.result.Err = p.ParseContext(ctx, job.result.Record)
+					if job.result.Err != nil && ctx.Err() != nil {
+						return
+					}
+				}
+				select {
+				case parsed <- job:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(parsed)
+	}()
+
+	go func() {
+		defer close(out)
+		deliver := func(r Result) bool {
+			defer func() { <-slots }()
+			if r.Err != nil && cfg.onError != nil && !isWarnings(r.Err) {
+				cfg.onError(r)
+				return true
+			}
+			select {
+			case out <- r:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		pending := make(map[int]Result)
+		next := 0
+		for job := range parsed {
+			if !cfg.ordered {
+				if !deliver(job.result) {
+					return
+				}
+				continue
+			}
+			pending[job.seq] = job.result
+			for {
+				r, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				next++
+				if !deliver(r) {
+					return
+				}
+			}
+		}
+	}()
+
+	return out
+}
+
+// isWarnings reports whether err holds the Warnings of a lenient parse, which
+// come with a usable event.
+func isWarnings(err error) bool {
+	var warnings Warnings
+	return errors.As(err, &warnings)
+}
@@ -0,0 +1,191 @@
+// Tests for the concurrent parsing pipeline.
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// pipelineLines returns n CEF lines named after their index, with every
+// badEvery-th line invalid.
+func pipelineLines(n, badEvery int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("CEF:0|Vendor|Product|1.0|100|%d|5|src=10.0.0.1", i)
+		if badEvery > 0 && i%badEvery == 0 {
+			lines[i] = "not cef"
+		}
+	}
+	return lines
+}
+
+// feed sends lines on a new channel and closes it.
+func feed(lines []string) <-chan string {
+	ch := make(chan string)
+	go func() {
+		defer close(ch)
+		for _, line := range lines {
+			ch <- line
+		}
+	}()
+	return ch
+}
+
+// TestParseStreamOrdered tests that ordered results follow the input order.
+func TestParseStreamOrdered(t *testing.T) {
+	lines := pipelineLines(500, 7)
+	results := ParseStream(context.Background(), feed(lines), WithWorkers(8), WithOrdered(true))
+
+	i := 0
+	for r := range results {
+		if r.Line != i+1 || r.Record != lines[i] {
+			t.Fatalf("result %d = line %d %q, want line %d", i, r.Line, r.Record, i+1)
+		}
+		if (r.Err != nil) != (i%7 == 0) {
+			t.Errorf("result %d error = %v", i, r.Err)
+		}
+		if r.Err == nil && r.Event.Name != fmt.Sprint(i) {
+			t.Errorf("result %d name = %s", i, r.Event.Name)
+		}
+		i++
+	}
+	if i != len(lines) {
+		t.Errorf("got %d results, want %d", i, len(lines))
+	}
+}
+
+// TestParseStreamErrorHandler tests that failed records go to the error handler.
+func TestParseStreamErrorHandler(t *testing.T) {
+	lines := pipelineLines(200, 10)
+	var failed []int
+	results := ParseStream(context.Background(), feed(lines), WithWorkers(4), WithErrorHandler(func(r Result) {
+		failed = append(failed, r.Line)
+	}))
+
+	seen := make(map[int]bool)
+	for r := range results {
+		if r.Err != nil {
+			t.Errorf("unexpected error result: %v", r.Err)
+		}
+		seen[r.Line] = true
+	}
+	if len(seen) != 180 || len(failed) != 20 {
+		t.Errorf("got %d results and %d errors, want 180 and 20", len(seen), len(failed))
+	}
+}
+
+// TestParseStreamLenient tests that events parsed with warnings are delivered
+// rather than sent to the error handler.
+func TestParseStreamLenient(t *testing.T) {
+	lines := []string{
+		"CEF:0|V|P|1|2|ok|5|src=10.0.0.1",
+		"CEF:0|V|P|1|2|severity|11|src=10.0.0.1",
+		"CEF:0|V|P|1|2|escape|5|msg=a\\qb",
+	}
+	var failed []Result
+	p := NewParser(WithLenient(true))
+	results := p.ParseStream(context.Background(), feed(lines), WithWorkers(2), WithOrdered(true), WithErrorHandler(func(r Result) {
+		failed = append(failed, r)
+	}))
+
+	i := 0
+	for r := range results {
+		if r.Event == nil || r.Event.Name != strings.Split(lines[i], "|")[5] {
+			t.Errorf("result %d = %+v", i, r)
+		}
+		var warnings Warnings
+		if (i == 0) != (r.Err == nil) || (r.Err != nil && !errors.As(r.Err, &warnings)) {
+			t.Errorf("result %d error = %v", i, r.Err)
+		}
+		i++
+	}
+	if i != len(lines) || len(failed) != 0 {
+		t.Errorf("got %d results and %d errors, want %d and 0", i, len(failed), len(lines))
+	}
+
+	results = p.ParseReader(context.Background(), strings.NewReader(strings.Repeat("a", 100)+"\n"),
+		WithScannerOptions(WithMaxRecordSize(10)), WithErrorHandler(func(r Result) {
+			failed = append(failed, r)
+		}))
+	for r := range results {
+		t.Errorf("unexpected result: %+v", r)
+	}
+	if len(failed) != 1 || !errors.Is(failed[0].Err, ErrRecordTooLong) {
+		t.Errorf("errors = %v, want ErrRecordTooLong", failed)
+	}
+}
+
+// TestParseReader tests parsing records from a reader.
+func TestParseReader(t *testing.T) {
+	input := strings.Join(pipelineLines(100, 0), "\r\n") + "\n\n" + "not cef\n"
+	results := NewParser().ParseReader(context.Background(), strings.NewReader(input), WithOrdered(true), WithWorkers(3))
+
+	count := 0
+	var last Result
+	for r := range results {
+		count++
+		last = r
+	}
+	if count != 101 || last.Line != 102 || last.Err == nil {
+		t.Errorf("got %d results, last = %+v", count, last)
+	}
+}
+
+// TestParseStreamBackpressure tests that the input is not read ahead of a slow consumer.
+func TestParseStreamBackpressure(t *testing.T) {
+	var sent int32
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		for _, line := range pipelineLines(100, 0) {
+			lines <- line
+			atomic.AddInt32(&sent, 1)
+		}
+	}()
+
+	results := ParseStream(context.Background(), lines, WithWorkers(2), WithBufferSize(4))
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&sent); n > 5 {
+		t.Errorf("read %d lines ahead of the consumer, want at most 5", n)
+	}
+	for range results {
+	}
+}
+
+// TestParseStreamCancel tests that cancelling the context stops the pipeline.
+func TestParseStreamCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	lines := make(chan string)
+	go func() {
+		for {
+			select {
+			case lines <- "CEF:0|Vendor|Product|1.0|100|Name|5|src=10.0.0.1":
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	results := ParseStream(ctx, lines, WithWorkers(4))
+	for i := 0; i < 10; i++ {
+		<-results
+	}
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		for range results {
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("results were not closed after cancellation")
+	}
+}
@@ -0,0 +1,159 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Registry maps device vendors, products and version constraints to the
+// Extensions implementation used for their events. A Registry is safe for
+// concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	entries []registryEntry
+}
+
+// registryEntry is a single registered Extensions factory.
+type registryEntry struct {
+	vendor     string
+	product    string
+	constraint versionConstraint
+	factory    func() Extensions
+}
+
+// defaultRegistry is the Registry used by NewExtensions and the package-level
+// registration functions.
+var defaultRegistry = NewDefaultRegistry()
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// NewDefaultRegistry returns a Registry containing the built-in vendor extensions.
+func NewDefaultRegistry() *Registry {
+	r := NewRegistry()
+	_ = r.Register("Incapsula", "SIEMintegration", "*", func() Extensions { return &ImpervaExtensions{} })
+	_ = r.Register("Centrify", "Centrify_Cloud", "*", func() Extensions { return &CentrifyExtensions{} })
+	return r
+}
+
+// Register adds a factory for events from the given vendor and product whose
+// DeviceVersion satisfies versionConstraint. The constraint may be empty or "*"
+// to match any version, a wildcard pattern such as "1.*", a semver range such
+// as ">=1.2 <2" or "^3", or an exact version. Registering the same vendor,
+// product and constraint again replaces the previous factory.
+func (r *Registry) Register(vendor, product, versionConstraint string, factory func() Extensions) error {
+	if vendor == "" || product == "" {
+		return fmt.Errorf("vendor and product must not be empty")
+	}
+	if factory == nil {
+		return fmt.Errorf("factory must not be nil")
+	}
+	constraint, err := parseVersionConstraint(versionConstraint)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry := registryEntry{vendor: vendor, product: product, constraint: constraint, factory: factory}
+	if i := r.indexOf(vendor, product, constraint.raw); i >= 0 {
+		r.entries = append(r.entries[:i], r.entries[i+1:]...)
+	}
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+// Unregister removes the factory registered for the vendor, product and
+// constraint. It reports whether a factory was removed.
+func (r *Registry) Unregister(vendor, product, versionConstraint string) bool {
+	constraint, err := parseVersionConstraint(versionConstraint)
+	if err != nil {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := r.indexOf(vendor, product, constraint.raw)
+	if i < 0 {
+		return false
+	}
+	r.entries = append(r.entries[:i], r.entries[i+1:]...)
+	return true
+}
+
+// Lookup returns the factory for events from the given vendor, product and
+// version. When several constraints match, exact versions take precedence over
+// ranges, ranges over wildcard patterns and those over match-any constraints;
+// among equally specific constraints the most recently registered wins.
+func (r *Registry) Lookup(vendor, product, version string) (func() Extensions, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var best *registryEntry
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		entry := &r.entries[i]
+		if entry.vendor != vendor || entry.product != product || !entry.constraint.match(version) {
+			continue
+		}
+		if best == nil || entry.constraint.kind > best.constraint.kind {
+			best = entry
+		}
+	}
+	if best == nil {
+		return nil, false
+	}
+	return best.factory, true
+}
+
+// NewExtensions returns a new Extensions value for the vendor, product and
+// version, or a DefaultExtensions if no factory matches.
+func (r *Registry) NewExtensions(vendor, product, version string) Extensions {
+	if factory, ok := r.Lookup(vendor, product, version); ok {
+		if ext := factory(); ext != nil {
+			return ext
+		}
+	}
+	return &DefaultExtensions{}
+}
+
+// Clone returns a copy of the registry that can be modified independently.
+func (r *Registry) Clone() *Registry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return &Registry{entries: append([]registryEntry(nil), r.entries...)}
+}
+
+// indexOf returns the index of the entry with the given key, or -1. The caller
+// must hold r.mu.
+func (r *Registry) indexOf(vendor, product, constraint string) int {
+	for i, entry := range r.entries {
+		if entry.vendor == vendor && entry.product == product && entry.constraint.raw == constraint {
+			return i
+		}
+	}
+	return -1
+}
+
+// RegisterExtensions adds a factory to the package-level registry used by
+// NewExtensions, ParseCEF and any Parser without its own registry.
+// See Registry.Register for the supported version constraints.
+func RegisterExtensions(vendor, product, versionConstraint string, factory func() Extensions) error {
+	return defaultRegistry.Register(vendor, product, versionConstraint, factory)
+}
+
+// UnregisterExtensions removes a factory from the package-level registry.
+func UnregisterExtensions(vendor, product, versionConstraint string) bool {
+	return defaultRegistry.Unregister(vendor, product, versionConstraint)
+}
+
+// LookupExtensions returns the factory the package-level registry would use
+// for the vendor, product and version.
+func LookupExtensions(vendor, product, version string) (func() Extensions, bool) {
+	return defaultRegistry.Lookup(vendor, product, version)
+}
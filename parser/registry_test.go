@@ -0,0 +1,188 @@
+// Tests for the vendor extension registry.
+package parser
+
+import (
+	"testing"
+)
+
+// customExtensions is a DefaultExtensions used to tell registered factories apart.
+type customExtensions struct {
+	DefaultExtensions
+	name string
+}
+
+// newCustom returns a factory producing customExtensions with the given name.
+func newCustom(name string) func() Extensions {
+	return func() Extensions { return &customExtensions{name: name} }
+}
+
+// lookupName returns the name of the customExtensions selected by the registry.
+func lookupName(r *Registry, vendor, product, version string) string {
+	if ext, ok := r.NewExtensions(vendor, product, version).(*customExtensions); ok {
+		return ext.name
+	}
+	return ""
+}
+
+// TestVersionConstraint tests exact, wildcard and range matching of versions.
+func TestVersionConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		expected   bool
+	}{
+		{"", "anything", true},
+		{"*", "1.0", true},
+		{"1.0", "1.0", true},
+		{"1.0", "1.0.1", false},
+		{"1.*", "1.5", true},
+		{"1.*", "2.0", false},
+		{">=1.2 <2", "1.2", true},
+		{">=1.2 <2", "1.10.3", true},
+		{">=1.2 <2", "2.0", false},
+		{">=1.2, <2", "1.1", false},
+		{"^1.2", "1.9", true},
+		{"^1.2", "2.0", false},
+		{"^0.2", "0.3", false},
+		{"~1.2", "1.2.9", true},
+		{"~1.2", "1.3", false},
+		{"<1 || >=3", "0.5", true},
+		{"<1 || >=3", "2", false},
+		{"<1 || >=3", "v3.1", true},
+		{">=1", "not-a-version", false},
+		{"=2.0.0", "2", true},
+	}
+
+	for _, test := range tests {
+		c, err := parseVersionConstraint(test.constraint)
+		if err != nil {
+			t.Fatalf("parseVersionConstraint(%q) error = %v", test.constraint, err)
+		}
+		if result := c.match(test.version); result != test.expected {
+			t.Errorf("constraint %q match %q = %v; want %v", test.constraint, test.version, result, test.expected)
+		}
+	}
+}
+
+// TestVersionConstraintInvalid tests that malformed constraints are rejected.
+func TestVersionConstraintInvalid(t *testing.T) {
+	for _, constraint := range []string{">=abc", "<>1", "1.[", ">=1 ||"} {
+		if _, err := parseVersionConstraint(constraint); err == nil {
+			t.Errorf("parseVersionConstraint(%q) expected error, got nil", constraint)
+		}
+	}
+}
+
+// TestRegistryPrecedence tests that the most specific constraint wins.
+func TestRegistryPrecedence(t *testing.T) {
+	r := NewRegistry()
+	for constraint, name := range map[string]string{
+		"*":        "any",
+		"2.*":      "wildcard",
+		">=2 <3":   "range",
+		"2.5":      "exact",
+		">=10 <11": "other",
+	} {
+		if err := r.Register("Acme", "Firewall", constraint, newCustom(name)); err != nil {
+			t.Fatalf("Register(%q) error = %v", constraint, err)
+		}
+	}
+
+	tests := map[string]string{
+		"2.5":  "exact",
+		"2.4":  "range",
+		"2.x":  "wildcard",
+		"1.0":  "any",
+		"10.1": "other",
+	}
+	for version, expected := range tests {
+		if name := lookupName(r, "Acme", "Firewall", version); name != expected {
+			t.Errorf("lookup version %q = %q, want %q", version, name, expected)
+		}
+	}
+
+	if _, ok := r.Lookup("Acme", "Router", "1.0"); ok {
+		t.Errorf("expected no factory for an unregistered product")
+	}
+	if _, ok := r.NewExtensions("Acme", "Router", "1.0").(*DefaultExtensions); !ok {
+		t.Errorf("expected DefaultExtensions for an unregistered product")
+	}
+}
+
+// TestRegistryReplaceAndUnregister tests replacing and removing factories.
+func TestRegistryReplaceAndUnregister(t *testing.T) {
+	r := NewRegistry()
+	_ = r.Register("Acme", "Firewall", "*", newCustom("first"))
+	_ = r.Register("Acme", "Firewall", "*", newCustom("second"))
+
+	if name := lookupName(r, "Acme", "Firewall", "1"); name != "second" {
+		t.Errorf("expected replaced factory 'second', got %q", name)
+	}
+	if !r.Unregister("Acme", "Firewall", "*") {
+		t.Errorf("expected Unregister to remove the factory")
+	}
+	if r.Unregister("Acme", "Firewall", "*") {
+		t.Errorf("expected second Unregister to report false")
+	}
+	if _, ok := r.Lookup("Acme", "Firewall", "1"); ok {
+		t.Errorf("expected no factory after Unregister")
+	}
+}
+
+// TestRegistryRegisterErrors tests argument validation in Register.
+func TestRegistryRegisterErrors(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register("", "Firewall", "*", newCustom("x")); err == nil {
+		t.Errorf("expected error for empty vendor")
+	}
+	if err := r.Register("Acme", "Firewall", "*", nil); err == nil {
+		t.Errorf("expected error for nil factory")
+	}
+	if err := r.Register("Acme", "Firewall", ">=x", newCustom("x")); err == nil {
+		t.Errorf("expected error for invalid constraint")
+	}
+}
+
+// TestDefaultRegistry tests the built-in vendor registrations and package-level functions.
+func TestDefaultRegistry(t *testing.T) {
+	if _, ok := NewExtensions("Incapsula", "SIEMintegration", "1").(*ImpervaExtensions); !ok {
+		t.Errorf("expected ImpervaExtensions for Incapsula")
+	}
+	if _, ok := NewExtensions("Centrify", "Centrify_Cloud", "1.0").(*CentrifyExtensions); !ok {
+		t.Errorf("expected CentrifyExtensions for Centrify")
+	}
+
+	if err := RegisterExtensions("Acme", "Firewall", "^1", newCustom("acme")); err != nil {
+		t.Fatalf("RegisterExtensions() error = %v", err)
+	}
+	defer UnregisterExtensions("Acme", "Firewall", "^1")
+
+	if _, ok := LookupExtensions("Acme", "Firewall", "1.4"); !ok {
+		t.Errorf("expected LookupExtensions to find the registered factory")
+	}
+	cefEvent, err := ParseCEF("CEF:0|Acme|Firewall|1.4|100|Name|5|src=10.0.0.1")
+	if err != nil {
+		t.Fatalf("ParseCEF() error = %v", err)
+	}
+	if ext, ok := cefEvent.Extensions.(*customExtensions); !ok || ext.Fields["src"] != "10.0.0.1" {
+		t.Errorf("expected registered extensions with src populated, got %#v", cefEvent.Extensions)
+	}
+}
+
+// TestWithRegistry tests that a per-Parser registry is independent of the package-level one.
+func TestWithRegistry(t *testing.T) {
+	r := NewDefaultRegistry().Clone()
+	_ = r.Register("Acme", "Firewall", "*", newCustom("parser"))
+
+	cefEvent, err := NewParser(WithRegistry(r)).Parse("CEF:0|Acme|Firewall|2.0|100|Name|5|src=10.0.0.1")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if _, ok := cefEvent.Extensions.(*customExtensions); !ok {
+		t.Errorf("expected per-parser registry extensions, got %T", cefEvent.Extensions)
+	}
+
+	if _, ok := LookupExtensions("Acme", "Firewall", "2.0"); ok {
+		t.Errorf("expected package-level registry to be unaffected")
+	}
+}
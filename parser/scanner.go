@@ -0,0 +1,339 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+)
+
+// DefaultMaxRecordSize is the default maximum size of a record read by a Scanner.
+const DefaultMaxRecordSize = 1 << 20
+
+// maxOctetCountDigits bounds the length prefix of an octet-counted frame.
+const maxOctetCountDigits = 10
+
+// octetChunkSize is the amount by which the buffer of an octet-counted frame
+// grows as the frame is read, since the count itself is untrusted input.
+const octetChunkSize = 64 << 10
+
+// ErrRecordTooLong is reported for records larger than the Scanner's maximum
+// record size. The record is skipped and scanning continues.
+var ErrRecordTooLong = errors.New("record exceeds the maximum size")
+
+// Framing selects how a Scanner splits its input into records.
+type Framing int
+
+const (
+	// FramingAuto detects RFC 6587 octet counting per record and otherwise
+	// reads newline-terminated records.
+	FramingAuto Framing = iota
+	// FramingNonTransparent reads records terminated by LF or CRLF, as in log
+	// files and RFC 6587 non-transparent framing.
+	FramingNonTransparent
+	// FramingOctetCounting reads RFC 6587 octet-counted records of the form
+	// "MSG-LEN SP MSG".
+	FramingOctetCounting
+)
+
+// LineError records the line on which a record started alongside the error
+// encountered while reading or parsing it.
+type LineError struct {
+	Line int
+	Err  error
+}
+
+// Error implements the error interface.
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// ScannerOption configures a Scanner.
+type ScannerOption func(*Scanner)
+
+// WithParser sets the Parser used for records. The default parser is used
+// otherwise.
+func WithParser(p *Parser) ScannerOption {
+	return func(s *Scanner) {
+		if p != nil {
+			s.parser = p
+		}
+	}
+}
+
+// WithMaxRecordSize sets the maximum size of a record in bytes, excluding its
+// framing. Larger records are skipped with ErrRecordTooLong. Zero or a negative
+// value disables the limit. The default is DefaultMaxRecordSize.
+func WithMaxRecordSize(n int) ScannerOption {
+	return func(s *Scanner) {
+		s.maxRecordSize = n
+	}
+}
+
+// WithFraming sets the record framing. The default is FramingAuto.
+func WithFraming(f Framing) ScannerOption {
+	return func(s *Scanner) {
+		s.framing = f
+	}
+}
+
+// Scanner reads CEF records from an io.Reader one at a time. Records are read
+// incrementally, so the input is never buffered as a whole. Empty lines are
+// skipped.
+//
+//	scanner := parser.NewScanner(file)
+//	for scanner.Scan() {
+//		cef, err := scanner.Event()
+//		...
+//	}
+//	if err := scanner.Err(); err != nil {
+//		...
+//	}
+type Scanner struct {
+	r             *bufio.Reader
+	parser        *Parser
+	maxRecordSize int
+	framing       Framing
+
+	raw      bool // read records without parsing them
+	buf      []byte
+	record   []byte
+	nextLine int // line number of the next unread byte
+	line     int // line number on which the current record started
+	event    *CEF
+	eventErr error
+	err      error
+	done     bool
+}
+
+// NewScanner returns a Scanner reading CEF records from r.
+func NewScanner(r io.Reader, opts ...ScannerOption) *Scanner {
+	s := &Scanner{
+		r:             bufio.NewReader(r),
+		parser:        defaultParser,
+		maxRecordSize: DefaultMaxRecordSize,
+		nextLine:      1,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Scan advances to the next record, which is then available through Event. It
+// returns false at the end of the input or when reading fails, after which Err
+// reports the failure. Records that fail to parse do not stop the scan.
+func (s *Scanner) Scan() bool {
+	s.event, s.eventErr, s.record = nil, nil, nil
+	if s.done {
+		return false
+	}
+
+	record, err := s.readRecord()
+	switch {
+	case err == io.EOF:
+		s.done = true
+		return false
+	case errors.Is(err, ErrRecordTooLong):
+		s.eventErr = &LineError{Line: s.line, Err: err}
+		return true
+	case err != nil:
+		s.done = true
+		s.err = &LineError{Line: s.line, Err: err}
+		return false
+	}
+
+	s.record = record
+	if s.raw {
+		return true
+	}
+	s.event, err = s.parser.Parse(string(record))
+	if err != nil {
+		s.eventErr = &LineError{Line: s.line, Err: err}
+	}
+	return true
+}
+
+// Text returns the current record without its framing.
+func (s *Scanner) Text() string {
+	return string(s.record)
+}
+
+// Event returns the event parsed from the current record, or a *LineError if
+// the record could not be read or parsed.
+func (s *Scanner) Event() (*CEF, error) {
+	return s.event, s.eventErr
+}
+
+// Line returns the line number on which the current record started.
+func (s *Scanner) Line() int {
+	return s.line
+}
+
+// Err returns the first error that stopped the Scanner, or nil if it reached
+// the end of the input.
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// readRecord reads the next non-empty record.
+func (s *Scanner) readRecord() ([]byte, error) {
+	for {
+		if s.framing != FramingNonTransparent {
+			if err := s.skipLineBreaks(); err != nil {
+				return nil, err
+			}
+		}
+		s.line = s.nextLine
+
+		var record []byte
+		var err error
+		switch {
+		case s.framing == FramingOctetCounting:
+			record, err = s.readOctetCounted()
+		case s.framing == FramingAuto && s.octetCounted():
+			record, err = s.readOctetCounted()
+		default:
+			record, err = s.readLine()
+		}
+		if err != nil || len(record) > 0 {
+			return record, err
+		}
+	}
+}
+
+// skipLineBreaks consumes line breaks between octet-counted frames.
+func (s *Scanner) skipLineBreaks() error {
+	for {
+		c, err := s.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch c {
+		case '\n':
+			s.nextLine++
+		case '\r':
+		default:
+			return s.r.UnreadByte()
+		}
+	}
+}
+
+// octetCounted reports whether the next record starts with an RFC 6587 length
+// prefix.
+func (s *Scanner) octetCounted() bool {
+	peek, _ := s.r.Peek(maxOctetCountDigits + 1)
+	n := 0
+	for n < len(peek) && '0' <= peek[n] && peek[n] <= '9' {
+		n++
+	}
+	return n > 0 && peek[0] != '0' && n < len(peek) && peek[n] == ' '
+}
+
+// readOctetCounted reads an RFC 6587 octet-counted frame.
+func (s *Scanner) readOctetCounted() ([]byte, error) {
+	prefix, err := s.r.ReadSlice(' ')
+	if err != nil && err != bufio.ErrBufferFull {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	digits := prefix[:len(prefix)-1]
+	if len(digits) == 0 || len(digits) > maxOctetCountDigits || !isDigits(string(digits)) {
+		return nil, fmt.Errorf("invalid octet count %q", prefix)
+	}
+	n, err := strconv.Atoi(string(digits))
+	if err != nil {
+		return nil, fmt.Errorf("invalid octet count %q", prefix)
+	}
+
+	if s.maxRecordSize > 0 && n > s.maxRecordSize {
+		counter := &lineCounter{}
+		_, err := io.CopyN(counter, s.r, int64(n))
+		s.nextLine += counter.lines
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		return nil, ErrRecordTooLong
+	}
+
+	// The buffer only grows as data arrives, so that a bogus count cannot
+	// allocate more than the input holds, even without a record size limit.
+	s.buf = s.buf[:0]
+	for remaining := n; remaining > 0; {
+		chunk := min(remaining, octetChunkSize)
+		start := len(s.buf)
+		s.buf = slices.Grow(s.buf, chunk)[:start+chunk]
+		if _, err := io.ReadFull(s.r, s.buf[start:]); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		remaining -= chunk
+	}
+	s.nextLine += bytes.Count(s.buf, []byte{'\n'})
+	return bytes.TrimRight(s.buf, "\r\n"), nil
+}
+
+// readLine reads a record terminated by LF or CRLF, or by the end of the input.
+func (s *Scanner) readLine() ([]byte, error) {
+	s.buf = s.buf[:0]
+	tooLong := false
+	for {
+		chunk, err := s.r.ReadSlice('\n')
+		if !tooLong && s.maxRecordSize > 0 && len(s.buf)+len(chunk) > s.maxRecordSize+2 {
+			tooLong = true
+		}
+		if !tooLong {
+			s.buf = append(s.buf, chunk...)
+		}
+
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == io.EOF {
+			if len(s.buf) == 0 && !tooLong {
+				return nil, io.EOF
+			}
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.nextLine++
+		break
+	}
+
+	record := bytes.TrimSuffix(bytes.TrimSuffix(s.buf, []byte{'\n'}), []byte{'\r'})
+	if tooLong || (s.maxRecordSize > 0 && len(record) > s.maxRecordSize) {
+		return nil, ErrRecordTooLong
+	}
+	return record, nil
+}
+
+// unexpectedEOF converts io.EOF inside a frame to io.ErrUnexpectedEOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// lineCounter is an io.Writer that counts the line feeds written to it.
+type lineCounter struct {
+	lines int
+}
+
+// Write implements io.Writer.
+func (c *lineCounter) Write(p []byte) (int, error) {
+	c.lines += bytes.Count(p, []byte{'\n'})
+	return len(p), nil
+}
@@ -0,0 +1,174 @@
+// Tests for the streaming Scanner.
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// scanResult is a record seen by a Scanner in tests.
+type scanResult struct {
+	line int
+	name string
+	err  bool
+}
+
+// scanAll collects the records of a Scanner.
+func scanAll(s *Scanner) []scanResult {
+	var results []scanResult
+	for s.Scan() {
+		cef, err := s.Event()
+		result := scanResult{line: s.Line(), err: err != nil}
+		if cef != nil {
+			result.name = cef.Name
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// TestScanner tests line and octet-counted framing.
+func TestScanner(t *testing.T) {
+	record := func(name string) string {
+		return "CEF:0|Vendor|Product|1.0|100|" + name + "|5|src=10.0.0.1"
+	}
+	frame := func(msg string) string {
+		return fmt.Sprintf("%d %s", len(msg), msg)
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		opts     []ScannerOption
+		expected []scanResult
+	}{
+		{
+			name:     "LF",
+			input:    record("a") + "\n" + record("b") + "\n",
+			expected: []scanResult{{line: 1, name: "a"}, {line: 2, name: "b"}},
+		},
+		{
+			name:     "CRLF without final line break",
+			input:    record("a") + "\r\n\r\n" + record("b"),
+			expected: []scanResult{{line: 1, name: "a"}, {line: 3, name: "b"}},
+		},
+		{
+			name:     "Parse error",
+			input:    record("a") + "\nnot cef\n" + record("c") + "\n",
+			expected: []scanResult{{line: 1, name: "a"}, {line: 2, err: true}, {line: 3, name: "c"}},
+		},
+		{
+			name:     "Octet counting",
+			input:    frame("<134>Oct 16 12:00:00 host1 "+record("a")) + frame(record("b")) + "\n" + frame(record("c")),
+			expected: []scanResult{{line: 1, name: "a"}, {line: 1, name: "b"}, {line: 2, name: "c"}},
+		},
+		{
+			name:     "Forced octet counting",
+			input:    frame(record("a")) + frame(record("b")),
+			opts:     []ScannerOption{WithFraming(FramingOctetCounting)},
+			expected: []scanResult{{line: 1, name: "a"}, {line: 1, name: "b"}},
+		},
+		{
+			name:     "Forced non-transparent framing",
+			input:    "12 " + record("a") + "\n",
+			opts:     []ScannerOption{WithFraming(FramingNonTransparent)},
+			expected: []scanResult{{line: 1, err: true}},
+		},
+		{
+			name:     "Record too long",
+			input:    record("a") + "\n" + record(strings.Repeat("x", 100)) + "\n" + record("c") + "\n",
+			opts:     []ScannerOption{WithMaxRecordSize(60)},
+			expected: []scanResult{{line: 1, name: "a"}, {line: 2, err: true}, {line: 3, name: "c"}},
+		},
+		{
+			name:     "Octet-counted record too long",
+			input:    frame(record(strings.Repeat("x", 100))) + frame(record("b")),
+			opts:     []ScannerOption{WithMaxRecordSize(60)},
+			expected: []scanResult{{line: 1, err: true}, {line: 1, name: "b"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := NewScanner(strings.NewReader(test.input), test.opts...)
+			results := scanAll(s)
+			if err := s.Err(); err != nil {
+				t.Fatalf("Err() = %v", err)
+			}
+			if fmt.Sprint(results) != fmt.Sprint(test.expected) {
+				t.Errorf("records = %v, want %v", results, test.expected)
+			}
+		})
+	}
+}
+
+// TestScannerErrors tests errors that stop the Scanner.
+func TestScannerErrors(t *testing.T) {
+	s := NewScanner(strings.NewReader("CEF:0|V|P|1|1|a|5|\n100 CEF:0|V|P|1|1|b|5|"))
+	results := scanAll(s)
+	if len(results) != 1 {
+		t.Errorf("expected one record before the error, got %v", results)
+	}
+	var lineErr *LineError
+	if err := s.Err(); !errors.As(err, &lineErr) || lineErr.Line != 2 || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Err() = %v, want unexpected EOF on line 2", err)
+	}
+
+	s = NewScanner(strings.NewReader("CEF:0|V|P|1|1|a|5|"), WithFraming(FramingOctetCounting))
+	if s.Scan() || s.Err() == nil {
+		t.Errorf("expected invalid octet count error, got %v", s.Err())
+	}
+
+	s = NewScanner(strings.NewReader(strings.Repeat("a", 100)+"\n"), WithMaxRecordSize(10))
+	if !s.Scan() {
+		t.Fatalf("Scan() = false, err = %v", s.Err())
+	}
+	if _, err := s.Event(); !errors.Is(err, ErrRecordTooLong) {
+		t.Errorf("Event() error = %v, want ErrRecordTooLong", err)
+	}
+}
+
+// TestScannerOctetCountUnlimited tests that a huge octet count does not
+// allocate the frame up front when the record size limit is disabled.
+func TestScannerOctetCountUnlimited(t *testing.T) {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	s := NewScanner(strings.NewReader("9999999999 CEF:0|V|P|1|1|a|5|"), WithFraming(FramingOctetCounting), WithMaxRecordSize(0))
+	if s.Scan() || !errors.Is(s.Err(), io.ErrUnexpectedEOF) {
+		t.Errorf("Err() = %v, want unexpected EOF", s.Err())
+	}
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+		t.Errorf("allocated %d bytes for a 19-byte frame", allocated)
+	}
+
+	frame := "CEF:0|V|P|1|1|" + strings.Repeat("n", 3*octetChunkSize) + "|5|"
+	s = NewScanner(strings.NewReader(fmt.Sprintf("%d %s", len(frame), frame)), WithFraming(FramingOctetCounting), WithMaxRecordSize(0))
+	if !s.Scan() {
+		t.Fatalf("Scan() = false, err = %v", s.Err())
+	}
+	if s.Text() != frame {
+		t.Errorf("Text() has %d bytes, want %d", len(s.Text()), len(frame))
+	}
+}
+
+// TestScannerLargeInput tests scanning input larger than the read buffer.
+func TestScannerLargeInput(t *testing.T) {
+	line := "CEF:0|Vendor|Product|1.0|100|Name|5|msg=" + strings.Repeat("m", 5000) + "\n"
+	s := NewScanner(strings.NewReader(strings.Repeat(line, 100)))
+
+	count := 0
+	for s.Scan() {
+		if _, err := s.Event(); err != nil {
+			t.Fatalf("Event() error = %v", err)
+		}
+		count++
+	}
+	if count != 100 || s.Err() != nil {
+		t.Errorf("scanned %d records, err = %v", count, s.Err())
+	}
+}
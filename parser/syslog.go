@@ -0,0 +1,246 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Syslog holds the syslog envelope a CEF record was received in.
+type Syslog struct {
+	// Priority is the PRI value, or -1 when the envelope has none. Facility and
+	// Severity are derived from it and are also -1 when it is absent.
+	Priority int
+	Facility int
+	Severity int
+	// Version is the RFC 5424 protocol version, or 0 for RFC 3164 messages.
+	Version   int
+	Timestamp time.Time
+	Hostname  string
+	AppName   string
+	ProcID    string
+	MsgID     string
+	// StructuredData maps SD-IDs to their parameters (RFC 5424 only).
+	StructuredData map[string]map[string]string `json:",omitempty"`
+}
+
+// utf8BOM may precede an RFC 5424 message.
+const utf8BOM = "\xef\xbb\xbf"
+
+// SplitSyslog detects an RFC 5424 or RFC 3164 syslog envelope around a CEF or
+// LEEF record and returns the parsed envelope and the record it contains. RFC
+// 3164 timestamps without a zone are interpreted in UTC.
+func SplitSyslog(line string) (*Syslog, string, error) {
+	return splitSyslogAt(line, nil, time.Now())
+}
+
+// splitSyslogAt is SplitSyslog with the location and current time used for RFC
+// 3164 timestamps.
+func splitSyslogAt(line string, loc *time.Location, now time.Time) (*Syslog, string, error) {
+	sl := &Syslog{Priority: -1, Facility: -1, Severity: -1}
+	rest := line
+
+	if strings.HasPrefix(rest, "<") {
+		end := strings.IndexByte(rest, '>')
+		if end < 2 || end > 4 || !isDigits(rest[1:end]) {
+			return nil, "", fmt.Errorf("invalid syslog priority")
+		}
+		pri, _ := strconv.Atoi(rest[1:end])
+		if pri > 191 {
+			return nil, "", fmt.Errorf("invalid syslog priority %d", pri)
+		}
+		sl.Priority, sl.Facility, sl.Severity = pri, pri/8, pri%8
+		rest = rest[end+1:]
+
+		if version, after, ok := strings.Cut(rest, " "); ok && len(version) <= 2 && isDigits(version) && version != "0" {
+			sl.Version, _ = strconv.Atoi(version)
+			msg, err := parseRFC5424(sl, after)
+			if err != nil {
+				return nil, "", err
+			}
+			return sl, msg, nil
+		}
+	}
+
+	msg, err := parseRFC3164(sl, rest, loc, now)
+	if err != nil {
+		return nil, "", err
+	}
+	return sl, msg, nil
+}
+
+// parseRFC5424 parses the RFC 5424 header following the version into sl and
+// returns the message.
+func parseRFC5424(sl *Syslog, s string) (string, error) {
+	var header [5]string
+	for i := range header {
+		var ok bool
+		header[i], s, ok = strings.Cut(s, " ")
+		if !ok || header[i] == "" {
+			return "", fmt.Errorf("invalid RFC 5424 header")
+		}
+		if header[i] == "-" {
+			header[i] = ""
+		}
+	}
+
+	if header[0] != "" {
+		ts, err := time.Parse(time.RFC3339Nano, header[0])
+		if err != nil {
+			return "", fmt.Errorf("invalid RFC 5424 timestamp %q", header[0])
+		}
+		sl.Timestamp = ts
+	}
+	sl.Hostname, sl.AppName, sl.ProcID, sl.MsgID = header[1], header[2], header[3], header[4]
+
+	if strings.HasPrefix(s, "-") {
+		s = s[1:]
+	} else {
+		sd, n, err := parseStructuredData(s)
+		if err != nil {
+			return "", err
+		}
+		sl.StructuredData = sd
+		s = s[n:]
+	}
+
+	if s != "" && s[0] != ' ' {
+		return "", fmt.Errorf("invalid RFC 5424 structured data")
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(s, " "), utf8BOM), nil
+}
+
+// parseStructuredData parses RFC 5424 structured data elements at the start of s
+// and returns them with the number of bytes consumed.
+func parseStructuredData(s string) (map[string]map[string]string, int, error) {
+	sd := make(map[string]map[string]string)
+	i := 0
+	for i < len(s) && s[i] == '[' {
+		i++
+		start := i
+		for i < len(s) && s[i] != ' ' && s[i] != ']' {
+			i++
+		}
+		id := s[start:i]
+		if id == "" || i == len(s) {
+			return nil, 0, fmt.Errorf("invalid RFC 5424 structured data")
+		}
+		params := make(map[string]string)
+
+		for s[i] == ' ' {
+			i++
+			start = i
+			for i < len(s) && s[i] != '=' {
+				i++
+			}
+			if i+1 >= len(s) || s[i+1] != '"' {
+				return nil, 0, fmt.Errorf("invalid RFC 5424 structured data in %q", id)
+			}
+			name := s[start:i]
+			i += 2
+
+			var b strings.Builder
+			for i < len(s) && s[i] != '"' {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(`"\]`, s[i+1]) >= 0 {
+					i++
+				}
+				b.WriteByte(s[i])
+				i++
+			}
+			if i+1 >= len(s) {
+				return nil, 0, fmt.Errorf("invalid RFC 5424 structured data in %q", id)
+			}
+			params[name] = b.String()
+			i++
+		}
+
+		if s[i] != ']' {
+			return nil, 0, fmt.Errorf("invalid RFC 5424 structured data in %q", id)
+		}
+		sd[id] = params
+		i++
+	}
+	if len(sd) == 0 {
+		return nil, 0, fmt.Errorf("invalid RFC 5424 structured data")
+	}
+	return sd, i, nil
+}
+
+// parseRFC3164 parses an RFC 3164 header ("Mmm dd hh:mm:ss host tag:") into sl
+// and returns the message, which must start with the CEF prefix. Many senders
+// omit the priority, the tag or the hostname, or use an RFC 3339 timestamp;
+// these variants are accepted as well.
+func parseRFC3164(sl *Syslog, s string, loc *time.Location, now time.Time) (string, error) {
+	idx := cefMessageStart(s)
+	if idx < 0 {
+		return "", fmt.Errorf("no CEF or LEEF record in syslog message")
+	}
+	tokens := strings.Fields(s[:idx])
+
+	switch {
+	case len(tokens) > 0 && strings.Contains(tokens[0], "T") && strings.Contains(tokens[0], ":"):
+		ts, err := time.Parse(time.RFC3339Nano, tokens[0])
+		if err != nil {
+			return "", fmt.Errorf("invalid syslog timestamp %q", tokens[0])
+		}
+		sl.Timestamp = ts
+		tokens = tokens[1:]
+	case len(tokens) >= 3 && isMonth(tokens[0]):
+		n := 3
+		if len(tokens) >= 4 && !strings.Contains(tokens[2], ":") {
+			n = 4
+		}
+		ts, err := parseTimestampAt(strings.Join(tokens[:n], " "), loc, now)
+		if err != nil {
+			return "", fmt.Errorf("invalid syslog timestamp: %w", err)
+		}
+		sl.Timestamp = ts
+		tokens = tokens[n:]
+	case sl.Priority < 0:
+		return "", fmt.Errorf("no syslog header")
+	}
+
+	if len(tokens) > 0 && !isSyslogTag(tokens[0]) {
+		sl.Hostname = tokens[0]
+		tokens = tokens[1:]
+	}
+	if len(tokens) > 0 {
+		tag := strings.TrimSuffix(tokens[0], ":")
+		if open := strings.IndexByte(tag, '['); open >= 0 && strings.HasSuffix(tag, "]") {
+			sl.ProcID = tag[open+1 : len(tag)-1]
+			tag = tag[:open]
+		}
+		sl.AppName = tag
+		tokens = tokens[1:]
+	}
+	if len(tokens) > 0 {
+		return "", fmt.Errorf("invalid RFC 3164 header")
+	}
+
+	return s[idx:], nil
+}
+
+// isMonth reports whether token is an abbreviated month name such as "Oct".
+func isMonth(token string) bool {
+	_, err := time.Parse("Jan", token)
+	return err == nil
+}
+
+// isSyslogTag reports whether token looks like an RFC 3164 tag such as
+// "app:" or "app[123]:" rather than a hostname.
+func isSyslogTag(token string) bool {
+	return strings.HasSuffix(token, ":") || strings.HasSuffix(token, "]")
+}
+
+// cefMessageStart returns the offset of the first CEF or LEEF prefix at the
+// start of a space-separated token in s, or -1.
+func cefMessageStart(s string) int {
+	for i := 0; i < len(s); i++ {
+		if (i == 0 || s[i-1] == ' ') && (strings.HasPrefix(s[i:], cefPrefix) || strings.HasPrefix(s[i:], leefPrefix)) {
+			return i
+		}
+	}
+	return -1
+}
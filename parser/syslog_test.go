@@ -0,0 +1,137 @@
+// Tests for syslog envelope detection.
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestSplitSyslog tests RFC 3164 and RFC 5424 envelopes and their variants.
+func TestSplitSyslog(t *testing.T) {
+	now := time.Date(2024, 10, 20, 0, 0, 0, 0, time.UTC)
+	record := "CEF:0|Vendor|Product|1.0|100|Name|5|src=10.0.0.1"
+
+	tests := []struct {
+		name      string
+		line      string
+		expected  *Syslog
+		expectErr bool
+	}{
+		{
+			name: "RFC 3164",
+			line: "<134>Oct 16 12:00:00 host1 " + record,
+			expected: &Syslog{
+				Priority: 134, Facility: 16, Severity: 6,
+				Timestamp: time.Date(2024, 10, 16, 12, 0, 0, 0, time.UTC),
+				Hostname:  "host1",
+			},
+		},
+		{
+			name: "RFC 3164 with tag",
+			line: "<13>Oct  6 12:00:00 host1 agent[42]: " + record,
+			expected: &Syslog{
+				Priority: 13, Facility: 1, Severity: 5,
+				Timestamp: time.Date(2024, 10, 6, 12, 0, 0, 0, time.UTC),
+				Hostname:  "host1", AppName: "agent", ProcID: "42",
+			},
+		},
+		{
+			name: "RFC 3164 without priority",
+			line: "Oct 16 2023 12:00:00 host1 " + record,
+			expected: &Syslog{
+				Priority: -1, Facility: -1, Severity: -1,
+				Timestamp: time.Date(2023, 10, 16, 12, 0, 0, 0, time.UTC),
+				Hostname:  "host1",
+			},
+		},
+		{
+			name: "RFC 3339 timestamp",
+			line: "<134>2024-10-16T12:00:00+02:00 host1 " + record,
+			expected: &Syslog{
+				Priority: 134, Facility: 16, Severity: 6,
+				Timestamp: time.Date(2024, 10, 16, 10, 0, 0, 0, time.UTC),
+				Hostname:  "host1",
+			},
+		},
+		{
+			name:     "Priority only",
+			line:     "<134>" + record,
+			expected: &Syslog{Priority: 134, Facility: 16, Severity: 6},
+		},
+		{
+			name: "RFC 5424",
+			line: `<165>1 2024-10-16T12:00:00.5Z host1 app 1234 ID47 [exampleSDID@32473 iut="3" eventSource="App\]lication"][meta seq="1"] ` + "\xef\xbb\xbf" + record,
+			expected: &Syslog{
+				Priority: 165, Facility: 20, Severity: 5, Version: 1,
+				Timestamp: time.Date(2024, 10, 16, 12, 0, 0, 500000000, time.UTC),
+				Hostname:  "host1", AppName: "app", ProcID: "1234", MsgID: "ID47",
+				StructuredData: map[string]map[string]string{
+					"exampleSDID@32473": {"iut": "3", "eventSource": "App]lication"},
+					"meta":              {"seq": "1"},
+				},
+			},
+		},
+		{
+			name: "RFC 5424 with nil values",
+			line: "<165>1 - - - - - - " + record,
+			expected: &Syslog{
+				Priority: 165, Facility: 20, Severity: 5, Version: 1,
+			},
+		},
+		{name: "No envelope", line: "garbage " + record, expectErr: true},
+		{name: "Invalid priority", line: "<999>Oct 16 12:00:00 host1 " + record, expectErr: true},
+		{name: "No CEF record", line: "<134>Oct 16 12:00:00 host1 hello", expectErr: true},
+		{name: "Unterminated structured data", line: `<165>1 - - - - - [id a="b" ` + record, expectErr: true},
+		{name: "Invalid timestamp", line: "<165>1 yesterday - - - - - " + record, expectErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sl, msg, err := splitSyslogAt(test.line, nil, now)
+			if test.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got %+v", sl)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("did not expect error, got '%v'", err)
+			}
+			if msg != record {
+				t.Errorf("message = %q, want %q", msg, record)
+			}
+			if !sl.Timestamp.Equal(test.expected.Timestamp) {
+				t.Errorf("Timestamp = %v, want %v", sl.Timestamp, test.expected.Timestamp)
+			}
+			sl.Timestamp, test.expected.Timestamp = time.Time{}, time.Time{}
+			if !reflect.DeepEqual(sl, test.expected) {
+				t.Errorf("Syslog = %+v, want %+v", sl, test.expected)
+			}
+		})
+	}
+}
+
+// TestParseSyslogCEF tests that parsing strips syslog envelopes.
+func TestParseSyslogCEF(t *testing.T) {
+	expected, err := ParseCEF(CentrifyCEF)
+	if err != nil {
+		t.Fatalf("ParseCEF() error = %v", err)
+	}
+
+	cefEvent, err := ParseCEF("<134>Oct 16 12:00:00 host1 " + CentrifyCEF)
+	if err != nil {
+		t.Fatalf("ParseCEF() error = %v", err)
+	}
+	if cefEvent.Syslog == nil || cefEvent.Syslog.Hostname != "host1" {
+		t.Errorf("Syslog = %+v", cefEvent.Syslog)
+	}
+	cefEvent.Syslog = nil
+	if !reflect.DeepEqual(cefEvent, expected) {
+		t.Errorf("ParseCEF() = %v, want %v", cefEvent, expected)
+	}
+
+	if _, err := NewParser(WithSyslog(false)).Parse("<134>Oct 16 12:00:00 host1 " + CentrifyCEF); err == nil {
+		t.Errorf("expected error with syslog detection disabled, got nil")
+	}
+}
@@ -0,0 +1,241 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeSettings holds the settings used to resolve event timestamps. A nil
+// *timeSettings resolves zone-less values in UTC and uses time.Now.
+type timeSettings struct {
+	location *time.Location
+	clock    func() time.Time
+}
+
+// zoneAbbreviations maps common time zone abbreviations to their UTC offsets,
+// since time.Parse does not resolve abbreviations outside the local zone.
+var zoneAbbreviations = map[string]int{
+	"UTC": 0, "UT": 0, "GMT": 0, "Z": 0, "WET": 0,
+	"BST": 1 * 3600, "CET": 1 * 3600, "WEST": 1 * 3600,
+	"CEST": 2 * 3600, "EET": 2 * 3600,
+	"EEST": 3 * 3600, "MSK": 3 * 3600,
+	"IST": 5*3600 + 1800,
+	"SGT": 8 * 3600, "HKT": 8 * 3600, "AWST": 8 * 3600,
+	"JST": 9 * 3600, "KST": 9 * 3600,
+	"AEST": 10 * 3600, "AEDT": 11 * 3600,
+	"NZST": 12 * 3600, "NZDT": 13 * 3600,
+	"AST": -4 * 3600, "ADT": -3 * 3600,
+	"EST": -5 * 3600, "EDT": -4 * 3600,
+	"CST": -6 * 3600, "CDT": -5 * 3600,
+	"MST": -7 * 3600, "MDT": -6 * 3600,
+	"PST": -8 * 3600, "PDT": -7 * 3600,
+	"AKST": -9 * 3600, "AKDT": -8 * 3600,
+	"HST": -10 * 3600,
+}
+
+// ParseTimestamp parses a CEF timestamp. It accepts epoch seconds or
+// milliseconds (with an optional fraction), RFC 3339, and the date formats
+// sanctioned by the CEF specification:
+//
+//	MMM dd HH:mm:ss[.SSS] [zzz]
+//	MMM dd yyyy HH:mm:ss[.SSS] [zzz]
+//
+// Values without a zone are interpreted in loc, or UTC if loc is nil. Values
+// without a year are placed in the current year, or the previous one if that
+// would put them more than a day in the future.
+func ParseTimestamp(value string, loc *time.Location) (time.Time, error) {
+	return parseTimestampAt(value, loc, time.Now())
+}
+
+// parseTimestampAt parses a CEF timestamp relative to the time now.
+func parseTimestampAt(value string, loc *time.Location, now time.Time) (time.Time, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, fmt.Errorf("empty timestamp")
+	}
+
+	if ts, ok := parseEpoch(value); ok {
+		return ts, nil
+	}
+	if ts, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return ts, nil
+	}
+
+	fields := strings.Fields(value)
+	if len(fields) < 3 {
+		return time.Time{}, fmt.Errorf("unrecognized timestamp %q", value)
+	}
+
+	hasYear := !strings.Contains(fields[2], ":")
+	layout := "Jan 2 15:04:05"
+	n := 3
+	if hasYear {
+		layout = "Jan 2 2006 15:04:05"
+		n = 4
+	}
+	if len(fields) < n || len(fields) > n+1 {
+		return time.Time{}, fmt.Errorf("unrecognized timestamp %q", value)
+	}
+	if len(fields) == n+1 {
+		zone, err := resolveZone(fields[n])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unrecognized timestamp %q: %w", value, err)
+		}
+		loc = zone
+	}
+
+	ts, err := time.ParseInLocation(layout, strings.Join(fields[:n], " "), loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unrecognized timestamp %q: %w", value, err)
+	}
+
+	if !hasYear {
+		year := now.In(loc).Year()
+		ts = time.Date(year, ts.Month(), ts.Day(), ts.Hour(), ts.Minute(), ts.Second(), ts.Nanosecond(), loc)
+		if ts.Sub(now) > 24*time.Hour {
+			ts = ts.AddDate(-1, 0, 0)
+		}
+	}
+	return ts, nil
+}
+
+// parseEpoch parses seconds or milliseconds since the epoch. Values of eleven
+// or more integer digits are taken as milliseconds.
+func parseEpoch(value string) (time.Time, bool) {
+	whole, frac, _ := strings.Cut(value, ".")
+	if !isDigits(whole) || (frac != "" && !isDigits(frac)) {
+		return time.Time{}, false
+	}
+
+	n, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var nanos int64
+	if frac != "" {
+		if len(frac) > 9 {
+			frac = frac[:9]
+		}
+		nanos, _ = strconv.ParseInt(frac+strings.Repeat("0", 9-len(frac)), 10, 64)
+	}
+
+	if len(whole) >= 11 {
+		return time.Unix(0, n*int64(time.Millisecond)+nanos/1000).UTC(), true
+	}
+	return time.Unix(n, nanos).UTC(), true
+}
+
+// resolveZone resolves a zone name, abbreviation or numeric offset such as
+// "America/New_York", "PST", "+0100", "-08:00" or "GMT+1".
+func resolveZone(name string) (*time.Location, error) {
+	if offset, ok := zoneAbbreviations[strings.ToUpper(name)]; ok {
+		return time.FixedZone(strings.ToUpper(name), offset), nil
+	}
+
+	offset := name
+	for _, prefix := range []string{"GMT", "UTC"} {
+		if strings.HasPrefix(strings.ToUpper(offset), prefix) {
+			offset = offset[len(prefix):]
+			break
+		}
+	}
+	if len(offset) > 1 && (offset[0] == '+' || offset[0] == '-') {
+		if seconds, ok := parseOffset(offset); ok {
+			return time.FixedZone(name, seconds), nil
+		}
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "" || strings.EqualFold(name, "local") {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// parseOffset parses a signed offset of the form +h, +hh, +hhmm or +hh:mm.
+func parseOffset(s string) (int, bool) {
+	sign := 1
+	if s[0] == '-' {
+		sign = -1
+	}
+	digits := strings.Replace(s[1:], ":", "", 1)
+	if !isDigits(digits) || len(digits) > 4 {
+		return 0, false
+	}
+
+	var hours, minutes int
+	switch len(digits) {
+	case 1, 2:
+		hours, _ = strconv.Atoi(digits)
+	case 3, 4:
+		hours, _ = strconv.Atoi(digits[:len(digits)-2])
+		minutes, _ = strconv.Atoi(digits[len(digits)-2:])
+	}
+	if hours > 14 || minutes > 59 {
+		return 0, false
+	}
+	return sign * (hours*3600 + minutes*60), true
+}
+
+// eventTime parses the timestamp extension key of the CEF event, applying the
+// event's dtz field to zone-less values.
+func (cef *CEF) eventTime(key string) (time.Time, error) {
+	value, ok := extensionValue(cef.Extensions, key)
+	if !ok {
+		return time.Time{}, fmt.Errorf("field %s not found", key)
+	}
+	return cef.parseTime(key, value)
+}
+
+// parseTime parses value as the timestamp extension key of the CEF event. See
+// eventTime.
+func (cef *CEF) parseTime(key, value string) (time.Time, error) {
+	var loc *time.Location
+	now := time.Now()
+	if cef.times != nil {
+		loc = cef.times.location
+		if cef.times.clock != nil {
+			now = cef.times.clock()
+		}
+	}
+	if dtz, ok := extensionValue(cef.Extensions, "dtz"); ok {
+		if zone, err := resolveZone(strings.TrimSpace(dtz)); err == nil {
+			loc = zone
+		}
+	}
+
+	ts, err := parseTimestampAt(value, loc, now)
+	if err != nil {
+		return time.Time{}, &ConversionError{Key: key, Value: value, Type: TypeTimestamp, Err: err}
+	}
+	return ts, nil
+}
+
+// Time returns the parsed value of a timestamp extension field such as "rt",
+// "start", "end" or "deviceCustomDate1". Zone-less values use the event's dtz
+// field when present, otherwise the Parser's default location.
+func (cef *CEF) Time(key string) (time.Time, error) {
+	return cef.eventTime(key)
+}
+
+// ReceiptTime returns the parsed deviceReceiptTime (rt) of the event.
+func (cef *CEF) ReceiptTime() (time.Time, error) {
+	return cef.eventTime("rt")
+}
+
+// StartTime returns the parsed startTime (start) of the event.
+func (cef *CEF) StartTime() (time.Time, error) {
+	return cef.eventTime("start")
+}
+
+// EndTime returns the parsed endTime (end) of the event.
+func (cef *CEF) EndTime() (time.Time, error) {
+	return cef.eventTime("end")
+}
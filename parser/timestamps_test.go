@@ -0,0 +1,104 @@
+// Tests for CEF timestamp parsing.
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseTimestamp tests the supported timestamp formats.
+func TestParseTimestamp(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		value     string
+		loc       *time.Location
+		expected  time.Time
+		expectErr bool
+	}{
+		{"Epoch millis", "1453290121336", nil, time.UnixMilli(1453290121336).UTC(), false},
+		{"Epoch seconds", "1453290121", nil, time.Unix(1453290121, 0).UTC(), false},
+		{"Epoch seconds with fraction", "1453290121.5", nil, time.Unix(1453290121, 500000000).UTC(), false},
+		{"RFC 3339", "2024-03-01T10:00:00Z", nil, time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), false},
+		{"Without year", "Mar 1 10:00:00", nil, time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), false},
+		{"Without year with millis", "Mar 01 10:00:00.250", nil, time.Date(2024, 3, 1, 10, 0, 0, 250000000, time.UTC), false},
+		{"Without year previous year", "Dec 31 23:00:00", nil, time.Date(2023, 12, 31, 23, 0, 0, 0, time.UTC), false},
+		{"With year", "Jan 21 2016 12:22:01", nil, time.Date(2016, 1, 21, 12, 22, 1, 0, time.UTC), false},
+		{"With year millis and zone", "Jan 21 2016 12:22:01.336 PST", nil, time.Date(2016, 1, 21, 20, 22, 1, 336000000, time.UTC), false},
+		{"With IANA zone", "Jan 21 2016 12:22:01 America/New_York", nil, time.Date(2016, 1, 21, 17, 22, 1, 0, time.UTC), false},
+		{"With offset zone", "Jan 21 2016 12:22:01 GMT+01:00", nil, time.Date(2016, 1, 21, 11, 22, 1, 0, time.UTC), false},
+		{"Default location", "Jan 21 2016 12:22:01", newYork, time.Date(2016, 1, 21, 17, 22, 1, 0, time.UTC), false},
+		{"Empty", "", nil, time.Time{}, true},
+		{"Unknown zone", "Jan 21 2016 12:22:01 Nowhere/Special", nil, time.Time{}, true},
+		{"Garbage", "yesterday", nil, time.Time{}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ts, err := parseTimestampAt(test.value, test.loc, now)
+			if test.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", ts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("did not expect error, got '%v'", err)
+			}
+			if !ts.Equal(test.expected) {
+				t.Errorf("parseTimestampAt(%q) = %v, want %v", test.value, ts, test.expected)
+			}
+		})
+	}
+}
+
+// TestEventTimes tests the timestamp accessors on parsed events.
+func TestEventTimes(t *testing.T) {
+	cefEvent, err := ParseCEF(ImpervaCEF1)
+	if err != nil {
+		t.Fatalf("ParseCEF() error = %v", err)
+	}
+	if ts, err := cefEvent.StartTime(); err != nil || ts.UnixMilli() != 1720396716929 {
+		t.Errorf("StartTime() = %v, %v", ts, err)
+	}
+	if ts, err := cefEvent.EndTime(); err != nil || ts.UnixMilli() != 1720396717135 {
+		t.Errorf("EndTime() = %v, %v", ts, err)
+	}
+	if _, err := cefEvent.ReceiptTime(); err == nil {
+		t.Errorf("expected error for missing rt, got nil")
+	}
+
+	cefEvent, err = ParseCEF("CEF:0|Vendor|Product|1.0|100|Name|5|rt=Jan 21 2016 12:22:01 dtz=America/New_York deviceCustomDate1=Jan 21 2016 12:22:01 UTC")
+	if err != nil {
+		t.Fatalf("ParseCEF() error = %v", err)
+	}
+	if ts, err := cefEvent.ReceiptTime(); err != nil || !ts.Equal(time.Date(2016, 1, 21, 17, 22, 1, 0, time.UTC)) {
+		t.Errorf("ReceiptTime() = %v, %v", ts, err)
+	}
+	if ts, err := cefEvent.Time("deviceCustomDate1"); err != nil || !ts.Equal(time.Date(2016, 1, 21, 12, 22, 1, 0, time.UTC)) {
+		t.Errorf("Time(deviceCustomDate1) = %v, %v", ts, err)
+	}
+}
+
+// TestParserTimeOptions tests WithLocation and WithClock on parsed events.
+func TestParserTimeOptions(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*3600)
+	clock := func() time.Time { return time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC) }
+	p := NewParser(WithLocation(loc), WithClock(clock))
+
+	cefEvent, err := p.Parse("CEF:0|Vendor|Product|1.0|100|Name|5|rt=Dec 30 10:00:00 end=Jan 1 2024 10:00:00")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if ts, err := cefEvent.ReceiptTime(); err != nil || !ts.Equal(time.Date(2023, 12, 30, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("ReceiptTime() = %v, %v", ts, err)
+	}
+	if ts, err := cefEvent.EndTime(); err != nil || !ts.Equal(time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("EndTime() = %v, %v", ts, err)
+	}
+}
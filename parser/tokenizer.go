@@ -0,0 +1,255 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// extensionField is a single key/value pair located in a CEF extension string.
+type extensionField struct {
+	Key    string
+	Value  string
+	Offset int // byte offset of the key within the extension string
+}
+
+// tokenizeExtensions splits a CEF extension string into key/value pairs.
+//
+// Keys are located using the rule from the CEF specification: a value runs until
+// the last unescaped space before the next unescaped `key=`. The escape sequences
+// `\=`, `\\`, `\n` and `\r` are unescaped in values. Any other escape is kept
+// verbatim and the first one found is returned as an error alongside the fields.
+//
+// When quoted is set, values that open with a double quote, '[' or '{' are read
+// as a single quoted or JSON unit when it is properly closed, so that embedded
+// ` key=` sequences in legacy vendor payloads (such as Imperva's cs10) do not
+// start a new pair, and enclosing double quotes are removed from values. This
+// is not part of the specification; see WithQuotedValues.
+func tokenizeExtensions(extension string, quoted bool) ([]extensionField, error) {
+	var fields []extensionField
+	err := scanExtensions(extension, quoted, func(field extensionField, _ *ParseError) {
+		fields = append(fields, field)
+	})
+	return fields, err
+}
+
+// scanExtensions calls fn for each key/value pair of a CEF extension string, as
+// described for tokenizeExtensions, along with the malformed escape in the
+// value, if any. Keys and values that need no unescaping are substrings of
+// extension. It returns the first malformed escape.
+func scanExtensions(extension string, quoted bool, fn func(field extensionField, escapeErr *ParseError)) error {
+	var firstErr error
+
+	pos := firstKeyOffset(extension)
+	for pos >= 0 {
+		key, _ := scanKey(extension, pos)
+		valStart := pos + len(key) + 1
+
+		valEnd, next := -1, -1
+		if quoted {
+			valEnd, next = groupedValueEnd(extension, valStart)
+		}
+		if valEnd < 0 {
+			valEnd, next = plainValueEnd(extension, valStart)
+		}
+
+		raw := extension[valStart:valEnd]
+		if quoted {
+			raw = trimQuotes(raw)
+		}
+		value, off := unescapeExtensionValue(raw)
+		var escapeErr *ParseError
+		if off >= 0 {
+			// Locate the escape relative to the extension, accounting for a stripped quote.
+			base := valStart
+			if len(raw) != valEnd-valStart {
+				base++
+			}
+			escapeErr = &ParseError{
+				Err: ErrInvalidExtension, Reason: ReasonMalformedEscape, Field: -1, Key: key, Offset: base + off,
+				Detail: fmt.Sprintf("malformed escape sequence %q", escapeAt(raw, off)),
+			}
+			if firstErr == nil {
+				firstErr = escapeErr
+			}
+		}
+
+		fn(extensionField{Key: key, Value: value, Offset: pos}, escapeErr)
+		pos = next
+	}
+
+	return firstErr
+}
+
+// firstKeyOffset returns the offset of the first key in the extension, or -1 if
+// there is none. Leading text that does not form a key is skipped.
+func firstKeyOffset(s string) int {
+	i := 0
+	for i < len(s) && s[i] == ' ' {
+		i++
+	}
+	if _, ok := scanKey(s, i); ok {
+		return i
+	}
+	_, next := plainValueEnd(s, i)
+	return next
+}
+
+// plainValueEnd returns the end of a value starting at start and the offset of
+// the following key (-1 if the value runs to the end of the string).
+func plainValueEnd(s string, start int) (int, int) {
+	escaped := false
+	for i := start; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case s[i] == '\\':
+			escaped = true
+		case s[i] == ' ':
+			if _, ok := scanKey(s, i+1); ok {
+				return i, i + 1
+			}
+		}
+	}
+	return len(s), -1
+}
+
+// groupedValueEnd returns the end of a quoted or JSON value starting at start and
+// the offset of the following key. It returns -1 for the end when the value is
+// not a properly closed group followed by another key or the end of the string.
+func groupedValueEnd(s string, start int) (int, int) {
+	if start >= len(s) {
+		return -1, -1
+	}
+
+	switch s[start] {
+	case '"':
+		for i := start + 1; i < len(s); i++ {
+			if s[i] != '"' {
+				continue
+			}
+			if next, ok := groupFollower(s, i+1); ok {
+				return i + 1, next
+			}
+		}
+	case '[', '{':
+		depth := 0
+		inString := false
+		for i := start; i < len(s); i++ {
+			c := s[i]
+			switch {
+			case inString && c == '\\':
+				i++
+			case c == '"':
+				inString = !inString
+			case inString:
+			case c == '[' || c == '{':
+				depth++
+			case c == ']' || c == '}':
+				depth--
+				if depth == 0 {
+					if next, ok := groupFollower(s, i+1); ok {
+						return i + 1, next
+					}
+					return -1, -1
+				}
+			}
+		}
+	}
+
+	return -1, -1
+}
+
+// groupFollower reports whether the text at i is the end of the string or a space
+// followed by a key, returning the offset of that key (-1 at the end).
+func groupFollower(s string, i int) (int, bool) {
+	if i == len(s) {
+		return -1, true
+	}
+	if s[i] != ' ' {
+		return 0, false
+	}
+	j := i
+	for j < len(s) && s[j] == ' ' {
+		j++
+	}
+	if j == len(s) {
+		return -1, true
+	}
+	if _, ok := scanKey(s, j); ok {
+		return j, true
+	}
+	return 0, false
+}
+
+// scanKey reads a `key=` token at offset i and returns the key.
+func scanKey(s string, i int) (string, bool) {
+	j := i
+	for j < len(s) && isCEFKeyChar(s[j]) {
+		j++
+	}
+	if j == i || j >= len(s) || s[j] != '=' {
+		return "", false
+	}
+	return s[i:j], true
+}
+
+// isCEFKeyChar reports whether c may appear in an extension key.
+func isCEFKeyChar(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// trimQuotes removes a single pair of enclosing double quotes from a value.
+func trimQuotes(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// unescapeExtensionValue resolves the CEF escape sequences in an extension value.
+// It returns the offset of the first malformed escape, or -1 if there is none.
+func unescapeExtensionValue(s string) (string, int) {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s, -1
+	}
+
+	bad := -1
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) {
+			switch s[i+1] {
+			case '=', '\\':
+				b.WriteByte(s[i+1])
+				i++
+				continue
+			case 'n':
+				b.WriteByte('\n')
+				i++
+				continue
+			case 'r':
+				b.WriteByte('\r')
+				i++
+				continue
+			}
+		}
+		if bad < 0 {
+			bad = i
+		}
+		b.WriteByte('\\')
+	}
+	return b.String(), bad
+}
+
+// escapeAt returns the escape sequence starting at offset i for error messages.
+func escapeAt(s string, i int) string {
+	if i+1 < len(s) {
+		return s[i : i+2]
+	}
+	return s[i:]
+}
@@ -0,0 +1,131 @@
+// Tests for the extension tokenizer.
+package parser
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// TestTokenizeExtensions tests key location and unescaping in extension strings.
+func TestTokenizeExtensions(t *testing.T) {
+	tests := []struct {
+		name      string
+		extension string
+		quoted    bool
+		expected  map[string]string
+		expectErr bool
+	}{
+		{
+			name:      "Escaped equals in value",
+			extension: `msg=a\=b c=d`,
+			expected:  map[string]string{"msg": "a=b", "c": "d"},
+		},
+		{
+			name:      "Escaped backslash and newlines",
+			extension: `filePath=C:\\Windows msg=line1\nline2\rend`,
+			expected:  map[string]string{"filePath": `C:\Windows`, "msg": "line1\nline2\rend"},
+		},
+		{
+			name:      "Spaces and mid-word equals",
+			extension: `request=http://example.com/?a=b&c=d act=blocked request method`,
+			expected:  map[string]string{"request": "http://example.com/?a=b&c=d", "act": "blocked request method"},
+		},
+		{
+			name:      "Escaped key separator is not a key",
+			extension: `msg=x y\=z src=1.1.1.1`,
+			expected:  map[string]string{"msg": "x y=z", "src": "1.1.1.1"},
+		},
+		{
+			name:      "Leading spaces and empty value",
+			extension: `  cs1= cs1Label=Rule`,
+			expected:  map[string]string{"cs1": "", "cs1Label": "Rule"},
+		},
+		{
+			name:      "Quotes are part of the value",
+			extension: `msg="User logged in from 10.0.0.1" src=10.0.0.1`,
+			expected:  map[string]string{"msg": `"User logged in from 10.0.0.1"`, "src": "10.0.0.1"},
+		},
+		{
+			name:      "Lone double quote",
+			extension: `msg=" src=1`,
+			expected:  map[string]string{"msg": `"`, "src": "1"},
+		},
+		{
+			name:      "Unbalanced brackets",
+			extension: `a={] k="}`,
+			expected:  map[string]string{"a": "{]", "k": `"}`},
+		},
+		{
+			name:      "JSON value is split at an embedded key",
+			extension: `cs10=[{"rule":"1; mode=block"}] cs10Label=Rule Info`,
+			expected:  map[string]string{"cs10": `[{"rule":"1;`, "mode": `block"}]`, "cs10Label": "Rule Info"},
+		},
+		{
+			name:      "Legacy quoted value",
+			extension: `msg="User logged in from 10.0.0.1" src=10.0.0.1`,
+			quoted:    true,
+			expected:  map[string]string{"msg": "User logged in from 10.0.0.1", "src": "10.0.0.1"},
+		},
+		{
+			name:      "Legacy JSON value with embedded key pattern",
+			extension: `cs10=[{"rule":"1; mode=block"}] cs10Label=Rule Info`,
+			quoted:    true,
+			expected:  map[string]string{"cs10": `[{"rule":"1; mode=block"}]`, "cs10Label": "Rule Info"},
+		},
+		{
+			name:      "Malformed escape",
+			extension: `msg=a\qb c=d`,
+			expected:  map[string]string{"msg": `a\qb`, "c": "d"},
+			expectErr: true,
+		},
+		{
+			name:      "Trailing backslash",
+			extension: `msg=abc\`,
+			expected:  map[string]string{"msg": `abc\`},
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fields, err := tokenizeExtensions(test.extension, test.quoted)
+			if test.expectErr && err == nil {
+				t.Errorf("expected error, got nil")
+			}
+			if !test.expectErr && err != nil {
+				t.Errorf("did not expect error, got '%v'", err)
+			}
+
+			actual := make(map[string]string, len(fields))
+			for _, field := range fields {
+				actual[field.Key] = field.Value
+			}
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("tokenizeExtensions(%q) = %v, want %v", test.extension, actual, test.expected)
+			}
+		})
+	}
+}
+
+// TestTokenizeExtensionsOffsets tests that key offsets point into the extension string.
+func TestTokenizeExtensionsOffsets(t *testing.T) {
+	extension := ` a=1 bb=two words c=3`
+	fields, err := tokenizeExtensions(extension, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, field := range fields {
+		if extension[field.Offset:field.Offset+len(field.Key)] != field.Key {
+			t.Errorf("offset %d does not point at key %q", field.Offset, field.Key)
+		}
+	}
+}
+
+// TestParseCEFMalformedEscape tests that malformed extension escapes are reported.
+func TestParseCEFMalformedEscape(t *testing.T) {
+	_, err := ParseCEFWithContext(context.Background(), `CEF:0|Vendor|Product|1.0|100|Name|5|msg=bad\escape`)
+	if err == nil {
+		t.Fatalf("expected error for malformed escape, got nil")
+	}
+}
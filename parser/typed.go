@@ -0,0 +1,184 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConversionError reports a failure to convert an extension value to a type.
+type ConversionError struct {
+	Key   string   // CEF extension key
+	Value string   // raw value that failed to convert
+	Type  DataType // requested data type
+	Err   error    // underlying error
+	// GoType is the requested Go type when converting with Field, and takes
+	// the place of Type in the message.
+	GoType reflect.Type
+}
+
+// Error implements the error interface.
+func (e *ConversionError) Error() string {
+	if e.GoType != nil {
+		return fmt.Sprintf("cannot convert %s=%q to %s: %v", e.Key, e.Value, e.GoType, e.Err)
+	}
+	return fmt.Sprintf("cannot convert %s=%q to %s: %v", e.Key, e.Value, e.Type, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ConversionError) Unwrap() error {
+	return e.Err
+}
+
+// TypedExtensions wraps an Extensions value with accessors that convert values
+// according to the ArcSight extension dictionary. Keys not in the dictionary,
+// such as vendor-specific ones, are converted to the requested type directly.
+type TypedExtensions struct {
+	Extensions
+}
+
+// Typed returns a typed accessor for ext.
+func Typed(ext Extensions) TypedExtensions {
+	return TypedExtensions{Extensions: ext}
+}
+
+// Typed returns a typed accessor for the extensions of the CEF event.
+func (cef *CEF) Typed() TypedExtensions {
+	return Typed(cef.Extensions)
+}
+
+// Get returns the value of key converted according to its dictionary type:
+// int64 for integers, float64 for floating point, netip.Addr for addresses,
+// net.HardwareAddr for MAC addresses, time.Time for timestamps and string
+// otherwise.
+func (t TypedExtensions) Get(key string) (interface{}, error) {
+	def, _ := LookupField(key)
+	switch def.Type {
+	case TypeInteger, TypeLong:
+		return t.GetInt(key)
+	case TypeFloat, TypeDouble:
+		return t.GetFloat(key)
+	case TypeIPv4, TypeIPv6:
+		return t.GetIP(key)
+	case TypeMAC:
+		return t.GetMAC(key)
+	case TypeTimestamp:
+		return t.GetTime(key)
+	default:
+		return t.GetString(key)
+	}
+}
+
+// GetString returns the raw string value of key.
+func (t TypedExtensions) GetString(key string) (string, error) {
+	value, ok := extensionValue(t.Extensions, key)
+	if !ok {
+		return "", fmt.Errorf("field %s not found", key)
+	}
+	return value, nil
+}
+
+// GetInt returns the value of key as an integer. Integer fields are limited to
+// 32 bits as defined by the dictionary.
+func (t TypedExtensions) GetInt(key string) (int64, error) {
+	value, err := t.typedValue(key, TypeLong, TypeInteger, TypeLong)
+	if err != nil {
+		return 0, err
+	}
+	bits := 64
+	if def, ok := LookupField(key); ok && def.Type == TypeInteger {
+		bits = 32
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, bits)
+	if err != nil {
+		return 0, &ConversionError{Key: key, Value: value, Type: TypeLong, Err: err}
+	}
+	return n, nil
+}
+
+// GetFloat returns the value of key as a floating point number.
+func (t TypedExtensions) GetFloat(key string) (float64, error) {
+	value, err := t.typedValue(key, TypeDouble, TypeFloat, TypeDouble, TypeInteger, TypeLong)
+	if err != nil {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0, &ConversionError{Key: key, Value: value, Type: TypeDouble, Err: err}
+	}
+	return f, nil
+}
+
+// GetIP returns the value of key as an IP address. IPv6 addresses are accepted
+// in IPv4 fields, as many devices send them there.
+func (t TypedExtensions) GetIP(key string) (netip.Addr, error) {
+	value, err := t.typedValue(key, TypeIPv6, TypeIPv4, TypeIPv6)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	addr, err := netip.ParseAddr(strings.TrimSpace(value))
+	if err != nil {
+		return netip.Addr{}, &ConversionError{Key: key, Value: value, Type: TypeIPv6, Err: err}
+	}
+	return addr, nil
+}
+
+// GetMAC returns the value of key as a MAC address.
+func (t TypedExtensions) GetMAC(key string) (net.HardwareAddr, error) {
+	value, err := t.typedValue(key, TypeMAC, TypeMAC)
+	if err != nil {
+		return nil, err
+	}
+	mac, err := net.ParseMAC(strings.TrimSpace(value))
+	if err != nil {
+		return nil, &ConversionError{Key: key, Value: value, Type: TypeMAC, Err: err}
+	}
+	return mac, nil
+}
+
+// GetTime returns the value of key as a time. Zone-less values are interpreted
+// in the zone given by the dtz field, or UTC; see ParseTimestamp.
+func (t TypedExtensions) GetTime(key string) (time.Time, error) {
+	value, err := t.typedValue(key, TypeTimestamp, TypeTimestamp)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var loc *time.Location
+	if dtz, ok := extensionValue(t.Extensions, "dtz"); ok {
+		loc, _ = resolveZone(strings.TrimSpace(dtz))
+	}
+	ts, err := ParseTimestamp(value, loc)
+	if err != nil {
+		return time.Time{}, &ConversionError{Key: key, Value: value, Type: TypeTimestamp, Err: err}
+	}
+	return ts, nil
+}
+
+// typedValue returns the raw value of key after checking that its dictionary
+// type, if any, is one of allowed. The requested type is used in errors.
+func (t TypedExtensions) typedValue(key string, requested DataType, allowed ...DataType) (string, error) {
+	value, err := t.GetString(key)
+	if err != nil {
+		return "", err
+	}
+	def, ok := LookupField(key)
+	if !ok {
+		return value, nil
+	}
+	for _, dt := range allowed {
+		if def.Type == dt {
+			return value, nil
+		}
+	}
+	return "", &ConversionError{
+		Key:   key,
+		Value: value,
+		Type:  requested,
+		Err:   fmt.Errorf("dictionary type of %s is %s", def.FullName, def.Type),
+	}
+}
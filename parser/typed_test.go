@@ -0,0 +1,126 @@
+// Tests for the typed extension accessors and dictionary.
+package parser
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+// TestLookupField tests dictionary lookup by key and full name.
+func TestLookupField(t *testing.T) {
+	def, ok := LookupField("src")
+	if !ok || def.FullName != "sourceAddress" || def.Type != TypeIPv4 {
+		t.Errorf("LookupField(src) = %+v, %v", def, ok)
+	}
+	def, ok = LookupField("deviceCustomNumber1")
+	if !ok || def.Key != "cn1" || def.Type != TypeLong {
+		t.Errorf("LookupField(deviceCustomNumber1) = %+v, %v", def, ok)
+	}
+	def, ok = LookupField("MSG")
+	if !ok || def.MaxLength != 1023 {
+		t.Errorf("LookupField(MSG) = %+v, %v", def, ok)
+	}
+	if _, ok := LookupField("siteid"); ok {
+		t.Errorf("expected vendor key siteid to be absent from the dictionary")
+	}
+	if TypeMAC.String() != "MAC Address" {
+		t.Errorf("TypeMAC.String() = %q", TypeMAC.String())
+	}
+}
+
+// TestTypedExtensions tests typed conversion of DefaultExtensions values.
+func TestTypedExtensions(t *testing.T) {
+	cefEvent, err := ParseCEF("CEF:0|Vendor|Product|1.0|100|Name|5|cn1=200 cnt=3 src=10.0.0.1 c6a1=2001:db8::1 smac=00:0d:60:af:1b:61 rt=1453290121336 cfp1=1.5 msg=hello siteid=77")
+	if err != nil {
+		t.Fatalf("ParseCEF() error = %v", err)
+	}
+	typed := cefEvent.Typed()
+
+	if n, err := typed.GetInt("cn1"); err != nil || n != 200 {
+		t.Errorf("GetInt(cn1) = %v, %v", n, err)
+	}
+	if n, err := typed.GetInt("siteid"); err != nil || n != 77 {
+		t.Errorf("GetInt(siteid) = %v, %v", n, err)
+	}
+	if f, err := typed.GetFloat("cfp1"); err != nil || f != 1.5 {
+		t.Errorf("GetFloat(cfp1) = %v, %v", f, err)
+	}
+	if ip, err := typed.GetIP("src"); err != nil || ip != netip.MustParseAddr("10.0.0.1") {
+		t.Errorf("GetIP(src) = %v, %v", ip, err)
+	}
+	if ip, err := typed.GetIP("c6a1"); err != nil || ip != netip.MustParseAddr("2001:db8::1") {
+		t.Errorf("GetIP(c6a1) = %v, %v", ip, err)
+	}
+	if mac, err := typed.GetMAC("smac"); err != nil || mac.String() != "00:0d:60:af:1b:61" {
+		t.Errorf("GetMAC(smac) = %v, %v", mac, err)
+	}
+	expectedTime := time.UnixMilli(1453290121336).UTC()
+	if ts, err := typed.GetTime("rt"); err != nil || !ts.Equal(expectedTime) {
+		t.Errorf("GetTime(rt) = %v, %v", ts, err)
+	}
+
+	value, err := typed.Get("cnt")
+	if err != nil || value != int64(3) {
+		t.Errorf("Get(cnt) = %#v, %v", value, err)
+	}
+	value, err = typed.Get("smac")
+	if _, ok := value.(net.HardwareAddr); err != nil || !ok {
+		t.Errorf("Get(smac) = %#v, %v", value, err)
+	}
+	value, err = typed.Get("msg")
+	if err != nil || value != "hello" {
+		t.Errorf("Get(msg) = %#v, %v", value, err)
+	}
+}
+
+// TestTypedExtensionsErrors tests conversion errors and dictionary type mismatches.
+func TestTypedExtensionsErrors(t *testing.T) {
+	typed := Typed(&DefaultExtensions{Fields: map[string]string{
+		"cn1":  "many",
+		"src":  "10.0.0.1",
+		"smac": "not-a-mac",
+		"cnt":  "9999999999",
+	}})
+
+	var convErr *ConversionError
+	if _, err := typed.GetInt("cn1"); !errors.As(err, &convErr) || convErr.Key != "cn1" {
+		t.Errorf("expected ConversionError for cn1, got %v", err)
+	}
+	if _, err := typed.GetInt("src"); !errors.As(err, &convErr) || convErr.Type != TypeLong {
+		t.Errorf("expected dictionary type mismatch for src, got %v", err)
+	}
+	if _, err := typed.GetMAC("smac"); !errors.As(err, &convErr) {
+		t.Errorf("expected ConversionError for smac, got %v", err)
+	}
+	if _, err := typed.GetInt("cnt"); !errors.As(err, &convErr) {
+		t.Errorf("expected 32-bit overflow error for cnt, got %v", err)
+	}
+	if _, err := typed.GetIP("dst"); err == nil {
+		t.Errorf("expected error for missing field, got nil")
+	}
+}
+
+// TestTypedVendorExtensions tests typed access through vendor struct tags.
+func TestTypedVendorExtensions(t *testing.T) {
+	cefEvent, err := ParseCEF(ImpervaCEF1)
+	if err != nil {
+		t.Fatalf("ParseCEF() error = %v", err)
+	}
+	typed := cefEvent.Typed()
+
+	if n, err := typed.GetInt("cn1"); err != nil || n != 200 {
+		t.Errorf("GetInt(cn1) = %v, %v", n, err)
+	}
+	if ip, err := typed.GetIP("src"); err != nil || ip != netip.MustParseAddr("123.123.123.123") {
+		t.Errorf("GetIP(src) = %v, %v", ip, err)
+	}
+	if ts, err := typed.GetTime("start"); err != nil || ts.UnixMilli() != 1720396716929 {
+		t.Errorf("GetTime(start) = %v, %v", ts, err)
+	}
+	if _, err := typed.GetString("dst"); err == nil {
+		t.Errorf("expected error for missing field, got nil")
+	}
+}
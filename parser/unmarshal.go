@@ -0,0 +1,70 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Unmarshal parses a CEF record with the default parser and stores it in the
+// struct pointed to by v. See Parser.Unmarshal.
+func Unmarshal(line string, v interface{}) error {
+	return defaultParser.Unmarshal(line, v)
+}
+
+// Unmarshal parses a CEF record and stores its header and extension values in
+// the struct pointed to by v, bypassing the vendor extension types. Fields are
+// bound with `cef` struct tags as described for BindFields; header fields use
+// the header option with their name:
+//
+//	type Event struct {
+//		Vendor   string     `cef:"DeviceVendor,header"`
+//		Severity int        `cef:"Severity,header"`
+//		Source   netip.Addr `cef:"src"`
+//		Received time.Time  `cef:"rt"`
+//		Hosts    []string   `cef:"cs1,sep=;"`
+//		Rules    []Rule     `cef:"cs2,json"`
+//		Device   struct {
+//			Host string `cef:"dvchost"`
+//		}
+//	}
+//
+// Timestamps without a zone are interpreted in the zone given by the dtz field,
+// the parser's location or UTC. Parse errors are returned before anything is
+// stored; in lenient mode the event is stored and its warnings are returned
+// unless a field fails to convert.
+func (p *Parser) Unmarshal(line string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Unmarshal requires a non-nil pointer to a struct, got %T", v)
+	}
+
+	raw := *p
+	raw.newExtensions = func(string, string, string) Extensions {
+		return &DefaultExtensions{}
+	}
+	raw.foldLabels = false
+	cefEvent, err := raw.Parse(line)
+	if cefEvent == nil {
+		return err
+	}
+
+	header := [cefHeaderFields]string{
+		cefEvent.Version, cefEvent.DeviceVendor, cefEvent.DeviceProduct, cefEvent.DeviceVersion,
+		cefEvent.SignatureID, cefEvent.Name, cefEvent.Severity,
+	}
+	fields := cefEvent.Extensions.(*DefaultExtensions).Fields
+	loc := p.location
+	if dtz, ok := lookupKey(fields, "dtz"); ok {
+		if zone, zoneErr := resolveZone(strings.TrimSpace(dtz)); zoneErr == nil {
+			loc = zone
+		}
+	}
+
+	b := binder{fields: fields, header: &header, loc: loc}
+	if _, bindErr := b.bind(rv.Elem()); bindErr != nil {
+		return bindErr
+	}
+	return err
+}
@@ -0,0 +1,162 @@
+// Tests for unmarshaling CEF records into user-defined structs.
+package parser
+
+import (
+	"errors"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// unmarshalRule is decoded from a JSON extension value.
+type unmarshalRule struct {
+	ID   string `json:"rule_id"`
+	Type string `json:"type"`
+}
+
+// unmarshalDevice is bound as a nested struct.
+type unmarshalDevice struct {
+	Vendor  string `cef:"DeviceVendor,header"`
+	Product string `cef:"DeviceProduct,header"`
+	Host    string `cef:"dvchost"`
+}
+
+// unmarshalEvent exercises every kind of binding supported by Unmarshal.
+type unmarshalEvent struct {
+	Version   int    `cef:"Version,header"`
+	Signature string `cef:"SignatureID,header"`
+	Name      string `cef:"name,header"`
+	Severity  int    `cef:"Severity,header"`
+	Device    unmarshalDevice
+	Network   *struct {
+		Source netip.Addr `cef:"src"`
+		Port   uint16     `cef:"spt"`
+	}
+	Missing *struct {
+		Value string `cef:"nothere"`
+	}
+	Received  time.Time       `cef:"rt"`
+	Start     *time.Time      `cef:"start"`
+	Hosts     []string        `cef:"cs1,sep=;"`
+	Ports     []int           `cef:"cs2,list"`
+	Rules     []unmarshalRule `cef:"cs3,json"`
+	Message   string          `cef:"msg"`
+	Protocol  string          `cef:"PROTO"`
+	Untouched string          `cef:"-"`
+}
+
+// TestUnmarshal tests header, extension, nested and converted fields.
+func TestUnmarshal(t *testing.T) {
+	line := `CEF:0|Security|threatmanager|1.0|100|worm stopped|7|src=10.0.0.1 spt=443 dvchost=fw1 dtz=Europe/Paris rt=Oct 16 2024 12:00:00 start=1729080000000 cs1=a.example;b.example cs2=80, 443 cs3=[{"rule_id":"7","type":"block"}] msg=line\=1 proto=TCP`
+
+	v := unmarshalEvent{Untouched: "kept"}
+	if err := Unmarshal(line, &v); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	paris, _ := time.LoadLocation("Europe/Paris")
+	start := time.UnixMilli(1729080000000).UTC()
+	expected := unmarshalEvent{
+		Version:   0,
+		Signature: "100",
+		Name:      "worm stopped",
+		Severity:  7,
+		Device:    unmarshalDevice{Vendor: "Security", Product: "threatmanager", Host: "fw1"},
+		Network: &struct {
+			Source netip.Addr `cef:"src"`
+			Port   uint16     `cef:"spt"`
+		}{Source: netip.MustParseAddr("10.0.0.1"), Port: 443},
+		Received:  time.Date(2024, 10, 16, 12, 0, 0, 0, paris),
+		Start:     &start,
+		Hosts:     []string{"a.example", "b.example"},
+		Ports:     []int{80, 443},
+		Rules:     []unmarshalRule{{ID: "7", Type: "block"}},
+		Message:   "line=1",
+		Protocol:  "TCP",
+		Untouched: "kept",
+	}
+	if !v.Received.Equal(expected.Received) {
+		t.Errorf("Received = %v, want %v", v.Received, expected.Received)
+	}
+	v.Received = expected.Received
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("Unmarshal() = %+v, want %+v", v, expected)
+	}
+}
+
+// TestUnmarshalErrors tests invalid targets, parse errors and conversion errors.
+func TestUnmarshalErrors(t *testing.T) {
+	var v unmarshalEvent
+	if err := Unmarshal("CEF:0|V|P|1|2|N|3|", v); err == nil {
+		t.Errorf("expected error for a non-pointer target")
+	}
+	if err := Unmarshal("not cef", &v); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("expected ErrInvalidFormat, got %v", err)
+	}
+
+	err := Unmarshal("CEF:0|V|P|1|2|N|High|spt=http", &v)
+	if err == nil || !strings.HasPrefix(err.Error(), "field Severity: ") {
+		t.Errorf("expected Severity conversion error, got %v", err)
+	}
+
+	var lenient struct {
+		Severity string `cef:"Severity,header"`
+		Message  string `cef:"msg"`
+	}
+	err = NewParser(WithLenient(true)).Unmarshal(`CEF:0|V|P|1|2|N|11|msg=a\qb`, &lenient)
+	var warnings Warnings
+	if !errors.As(err, &warnings) || lenient.Severity != "11" || lenient.Message != `a\qb` {
+		t.Errorf("Unmarshal() = %+v, %v", lenient, err)
+	}
+}
+
+// TestUnmarshalVendorKeys tests that vendor records bind their raw keys.
+func TestUnmarshalVendorKeys(t *testing.T) {
+	var v struct {
+		Country []string `cef:"ccode"`
+		City    string   `cef:"cicode"`
+		Query   string   `cef:"qstr"`
+	}
+	if err := NewParser(WithDuplicateKeys(DuplicateCollect)).Unmarshal(ImpervaCEFCombined, &v); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(v.Country, []string{"IL, IL"}) || v.City != "Rehovot" || v.Query != "p=%2fetc%2fpasswd" {
+		t.Errorf("Unmarshal() = %+v", v)
+	}
+}
+
+// unmarshalNode refers to its own type.
+type unmarshalNode struct {
+	Name   string `cef:"name"`
+	Next   *unmarshalNode
+	Parent *unmarshalTree
+}
+
+// unmarshalTree and unmarshalNode refer to each other.
+type unmarshalTree struct {
+	Host string `cef:"dvchost"`
+	Root *unmarshalNode
+}
+
+// TestUnmarshalRecursiveTypes tests that self-referential types are bound once
+// per level instead of recursing forever.
+func TestUnmarshalRecursiveTypes(t *testing.T) {
+	var node unmarshalNode
+	if err := Unmarshal("CEF:0|V|P|1|2|N|3|name=a dvchost=h", &node); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	expected := unmarshalNode{Name: "a", Parent: &unmarshalTree{Host: "h"}}
+	if !reflect.DeepEqual(node, expected) {
+		t.Errorf("Unmarshal() = %+v, want %+v", node, expected)
+	}
+
+	var tree unmarshalTree
+	if err := BindFields(&tree, map[string]string{"name": "a", "dvchost": "h"}); err != nil {
+		t.Fatalf("BindFields() error = %v", err)
+	}
+	if tree.Host != "h" || tree.Root == nil || tree.Root.Name != "a" || tree.Root.Next != nil || tree.Root.Parent != nil {
+		t.Errorf("BindFields() = %+v, Root = %+v", tree, tree.Root)
+	}
+}
@@ -0,0 +1,161 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidationLevel controls how strictly the CEF header components are validated.
+type ValidationLevel int
+
+const (
+	// ValidationDefault accepts any UTF-8 header text and applies the per-field
+	// rules: Version is numeric, Severity is 0-10 or one of Unknown, Low, Medium,
+	// High and Very-High, and SignatureID is non-empty.
+	ValidationDefault ValidationLevel = iota
+	// ValidationStrict applies the default rules, requires DeviceVendor,
+	// DeviceProduct and Name to be non-empty, rejects control characters and
+	// enforces the field lengths from the CEF specification.
+	ValidationStrict
+	// ValidationNone performs no validation of the header components.
+	ValidationNone
+)
+
+// headerFieldNames names the header components in the order they appear.
+var headerFieldNames = [cefHeaderFields]string{
+	"Version", "DeviceVendor", "DeviceProduct", "DeviceVersion", "SignatureID", "Name", "Severity",
+}
+
+// strictHeaderLengths holds the maximum length of each header component under ValidationStrict.
+var strictHeaderLengths = [cefHeaderFields]int{31, 63, 63, 31, 1023, 512, 31}
+
+// severityNames holds the textual severities permitted by the CEF specification.
+var severityNames = []string{"Unknown", "Low", "Medium", "High", "Very-High"}
+
+// Validate checks the header components of the CEF event at the given level.
+// Failures are reported as a *ParseError naming the offending field.
+func (cef *CEF) Validate(level ValidationLevel) error {
+	return validateHeader([cefHeaderFields]string{
+		cef.Version, cef.DeviceVendor, cef.DeviceProduct, cef.DeviceVersion,
+		cef.SignatureID, cef.Name, cef.Severity,
+	}, level, DefaultMaxHeaderLength)
+}
+
+// validateHeader checks unescaped header components at the given level and
+// returns the first problem found. See checkHeader.
+func validateHeader(header [cefHeaderFields]string, level ValidationLevel, maxLength int) error {
+	var first *ParseError
+	checkHeader(header, level, maxLength, func(err *ParseError) bool {
+		first = err
+		return false
+	})
+	if first == nil {
+		return nil
+	}
+	return first
+}
+
+// checkHeader checks unescaped header components at the given level, passing
+// each problem to report until it returns false. Under ValidationDefault
+// components may be at most maxLength characters, where zero or less means no
+// limit; ValidationStrict uses the lengths from the spec.
+func checkHeader(header [cefHeaderFields]string, level ValidationLevel, maxLength int, report func(*ParseError) bool) {
+	if level == ValidationNone {
+		return
+	}
+
+	for i, component := range header {
+		if !utf8.ValidString(component) {
+			if !report(headerError(i, ReasonInvalidUTF8, fmt.Sprintf("invalid %s: not valid UTF-8", headerFieldNames[i]))) {
+				return
+			}
+			continue
+		}
+		limit := maxLength
+		if level == ValidationStrict {
+			limit = strictHeaderLengths[i]
+			if strings.ContainsFunc(component, isControlRune) {
+				if !report(headerError(i, ReasonControlCharacter, fmt.Sprintf("invalid %s: contains control characters", headerFieldNames[i]))) {
+					return
+				}
+			}
+		}
+		if limit > 0 && utf8.RuneCountInString(component) > limit {
+			if !report(headerError(i, ReasonFieldTooLong, fmt.Sprintf("invalid %s: longer than %d characters", headerFieldNames[i], limit))) {
+				return
+			}
+		}
+	}
+
+	if !isValidVersion(header[0]) {
+		if !report(headerError(0, ReasonInvalidVersion, fmt.Sprintf("invalid Version %q: must be numeric", header[0]))) {
+			return
+		}
+	}
+	if header[4] == "" {
+		if !report(headerError(4, ReasonEmptyField, "invalid SignatureID: must not be empty")) {
+			return
+		}
+	}
+	if !isValidSeverity(header[6]) {
+		if !report(headerError(6, ReasonInvalidSeverity, fmt.Sprintf("invalid Severity %q: must be 0-10 or one of %s", header[6], strings.Join(severityNames, ", ")))) {
+			return
+		}
+	}
+
+	if level == ValidationStrict {
+		for _, i := range []int{1, 2, 5} {
+			if header[i] == "" {
+				if !report(headerError(i, ReasonEmptyField, fmt.Sprintf("invalid %s: must not be empty", headerFieldNames[i]))) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// headerError returns a ParseError for header field i. Its offset and snippet
+// are filled in by the caller, which knows the position of the field.
+func headerError(i int, reason Reason, detail string) *ParseError {
+	return &ParseError{Err: ErrInvalidHeader, Reason: reason, Field: i, Offset: -1, Detail: detail}
+}
+
+// isValidVersion reports whether the CEF version is numeric, such as "0" or "1.0".
+func isValidVersion(version string) bool {
+	major, minor, found := strings.Cut(version, ".")
+	return isDigits(major) && (!found || isDigits(minor))
+}
+
+// isValidSeverity reports whether the severity is 0-10 or a textual severity.
+func isValidSeverity(severity string) bool {
+	if n, err := strconv.Atoi(severity); err == nil && isDigits(severity) {
+		return n <= 10
+	}
+	for _, name := range severityNames {
+		if strings.EqualFold(severity, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDigits reports whether s is a non-empty string of ASCII digits.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isControlRune reports whether r is an ASCII or C1 control character.
+func isControlRune(r rune) bool {
+	return r < 0x20 || (r >= 0x7f && r < 0xa0)
+}
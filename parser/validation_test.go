@@ -0,0 +1,87 @@
+// Tests for the header validation policy.
+package parser
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestValidateHeader tests the per-field header rules at each validation level.
+func TestValidateHeader(t *testing.T) {
+	valid := [cefHeaderFields]string{"0", "Vendor", "Product", "1.0", "100", "Name", "5"}
+	with := func(i int, value string) [cefHeaderFields]string {
+		header := valid
+		header[i] = value
+		return header
+	}
+
+	tests := []struct {
+		name      string
+		header    [cefHeaderFields]string
+		level     ValidationLevel
+		expectErr bool
+	}{
+		{"Valid header", valid, ValidationDefault, false},
+		{"Name with punctuation", with(5, "Login failed: user (admin)"), ValidationDefault, false},
+		{"Name with slash", with(5, "SQL Injection/XSS"), ValidationDefault, false},
+		{"Non-ASCII product", with(2, "Sécurité Réseau"), ValidationDefault, false},
+		{"Empty device version", with(3, ""), ValidationDefault, false},
+		{"Long name", with(5, strings.Repeat("a", 200)), ValidationDefault, false},
+		{"Dotted version", with(0, "1.0"), ValidationDefault, false},
+		{"Non-numeric version", with(0, "x"), ValidationDefault, true},
+		{"Empty version", with(0, ""), ValidationDefault, true},
+		{"Severity 10", with(6, "10"), ValidationDefault, false},
+		{"Severity 11", with(6, "11"), ValidationDefault, true},
+		{"Negative severity", with(6, "-1"), ValidationDefault, true},
+		{"Textual severity", with(6, "Very-High"), ValidationDefault, false},
+		{"Textual severity case", with(6, "medium"), ValidationDefault, false},
+		{"Unknown textual severity", with(6, "Critical"), ValidationDefault, true},
+		{"Empty signature ID", with(4, ""), ValidationDefault, true},
+		{"Invalid UTF-8", with(1, "\xff"), ValidationDefault, true},
+		{"Too long component", with(5, strings.Repeat("a", DefaultMaxHeaderLength+1)), ValidationDefault, true},
+		{"Strict empty vendor", with(1, ""), ValidationStrict, true},
+		{"Strict control character", with(5, "a\tb"), ValidationStrict, true},
+		{"Strict long vendor", with(1, strings.Repeat("a", 64)), ValidationStrict, true},
+		{"Strict valid", valid, ValidationStrict, false},
+		{"None accepts anything", with(6, "Critical"), ValidationNone, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateHeader(test.header, test.level, DefaultMaxHeaderLength)
+			if test.expectErr && err == nil {
+				t.Errorf("expected error, got nil")
+			}
+			if !test.expectErr && err != nil {
+				t.Errorf("did not expect error, got '%v'", err)
+			}
+		})
+	}
+}
+
+// TestParseCEFWithValidation tests that the validation level is applied when parsing.
+func TestParseCEFWithValidation(t *testing.T) {
+	cef := "CEF:0||Product|1.0|100|Name|Critical|src=1.1.1.1"
+
+	if _, err := ParseCEFWithValidation(context.Background(), cef, ValidationDefault); err == nil {
+		t.Errorf("expected error at default level, got nil")
+	}
+	if _, err := ParseCEFWithValidation(context.Background(), cef, ValidationNone); err != nil {
+		t.Errorf("did not expect error at none level, got '%v'", err)
+	}
+}
+
+// TestCEFValidate tests the Validate method on a parsed event.
+func TestCEFValidate(t *testing.T) {
+	cefEvent, err := ParseCEF("CEF:0||Product||100|Name|Low|src=1.1.1.1")
+	if err != nil {
+		t.Fatalf("ParseCEF() error = %v", err)
+	}
+	if err := cefEvent.Validate(ValidationDefault); err != nil {
+		t.Errorf("Validate(ValidationDefault) error = %v", err)
+	}
+	if err := cefEvent.Validate(ValidationStrict); err == nil {
+		t.Errorf("expected Validate(ValidationStrict) error for empty vendor, got nil")
+	}
+}
@@ -0,0 +1,212 @@
+// Package parser provides functionality for parsing CEF events.
+package parser
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// versionMatchKind orders version constraints from least to most specific.
+type versionMatchKind int
+
+const (
+	versionMatchAny versionMatchKind = iota
+	versionMatchWildcard
+	versionMatchRange
+	versionMatchExact
+)
+
+// versionConstraint matches DeviceVersion values against a registered constraint.
+//
+// A constraint is one of:
+//   - "" or "*", matching any version
+//   - a wildcard pattern such as "1.*" or "2.?", matched with path.Match
+//   - a semver range of space or comma separated comparators (>, >=, <, <=, =,
+//     ^ and ~), with alternatives separated by "||", such as ">=1.2 <2" or "^3.1"
+//   - any other string, matching that exact version
+type versionConstraint struct {
+	raw    string
+	kind   versionMatchKind
+	ranges [][]versionComparator // alternatives of ANDed comparators
+}
+
+// versionComparator is a single comparison within a semver range.
+type versionComparator struct {
+	op      string
+	version []int
+}
+
+// parseVersionConstraint parses a version constraint for the registry.
+func parseVersionConstraint(raw string) (versionConstraint, error) {
+	c := versionConstraint{raw: strings.TrimSpace(raw)}
+
+	switch {
+	case c.raw == "" || c.raw == "*":
+		c.kind = versionMatchAny
+	case strings.ContainsAny(c.raw, "<>=^~|"):
+		c.kind = versionMatchRange
+		for _, alternative := range strings.Split(c.raw, "||") {
+			comparators, err := parseVersionComparators(alternative)
+			if err != nil {
+				return c, fmt.Errorf("invalid version constraint %q: %w", raw, err)
+			}
+			c.ranges = append(c.ranges, comparators)
+		}
+	case strings.ContainsAny(c.raw, "*?["):
+		if _, err := path.Match(c.raw, ""); err != nil {
+			return c, fmt.Errorf("invalid version constraint %q: %w", raw, err)
+		}
+		c.kind = versionMatchWildcard
+	default:
+		c.kind = versionMatchExact
+	}
+
+	return c, nil
+}
+
+// parseVersionComparators parses a space or comma separated list of comparators.
+func parseVersionComparators(s string) ([]versionComparator, error) {
+	var comparators []versionComparator
+	for _, term := range strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' }) {
+		rest := strings.TrimLeft(term, "<>=^~")
+		op := term[:len(term)-len(rest)]
+		version, ok := parseVersion(rest)
+		if !ok {
+			return nil, fmt.Errorf("invalid version %q", rest)
+		}
+		switch op {
+		case "", "=":
+			op = "="
+		case ">", ">=", "<", "<=", "^", "~":
+		default:
+			return nil, fmt.Errorf("invalid operator %q", op)
+		}
+		comparators = append(comparators, versionComparator{op: op, version: version})
+	}
+	if len(comparators) == 0 {
+		return nil, fmt.Errorf("empty range")
+	}
+	return comparators, nil
+}
+
+// match reports whether version satisfies the constraint.
+func (c versionConstraint) match(version string) bool {
+	switch c.kind {
+	case versionMatchAny:
+		return true
+	case versionMatchWildcard:
+		ok, _ := path.Match(c.raw, version)
+		return ok
+	case versionMatchExact:
+		return version == c.raw
+	}
+
+	v, ok := parseVersion(version)
+	if !ok {
+		return false
+	}
+	for _, comparators := range c.ranges {
+		if matchComparators(comparators, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchComparators reports whether v satisfies every comparator.
+func matchComparators(comparators []versionComparator, v []int) bool {
+	for _, comparator := range comparators {
+		if !comparator.match(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// match reports whether v satisfies the comparator.
+func (vc versionComparator) match(v []int) bool {
+	cmp := compareVersions(v, vc.version)
+	switch vc.op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case "^":
+		// Same left-most non-zero component, at or above the given version.
+		return cmp >= 0 && compareVersions(v, caretUpperBound(vc.version)) < 0
+	case "~":
+		// Same major and minor version, at or above the given version.
+		return cmp >= 0 && compareVersions(v, tildeUpperBound(vc.version)) < 0
+	default:
+		return cmp == 0
+	}
+}
+
+// caretUpperBound returns the exclusive upper bound of a ^ comparator.
+func caretUpperBound(v []int) []int {
+	for i, n := range v {
+		if n != 0 || i == len(v)-1 {
+			upper := append([]int(nil), v[:i+1]...)
+			upper[i]++
+			return upper
+		}
+	}
+	return []int{1}
+}
+
+// tildeUpperBound returns the exclusive upper bound of a ~ comparator.
+func tildeUpperBound(v []int) []int {
+	if len(v) < 2 {
+		return []int{v[0] + 1}
+	}
+	return []int{v[0], v[1] + 1}
+}
+
+// parseVersion parses a dotted numeric version such as "1", "2.0" or "v3.1.4".
+// Pre-release and build suffixes after '-' or '+' are ignored.
+func parseVersion(s string) ([]int, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+	if s == "" {
+		return nil, false
+	}
+
+	parts := strings.Split(s, ".")
+	version := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		version[i] = n
+	}
+	return version, true
+}
+
+// compareVersions compares two versions, treating missing components as zero.
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}